@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bingoohuang/ngg/gossh"
+	"github.com/bingoohuang/ngg/ver"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	var c gossh.Config
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "run commands in hosts, or keep the port forwardings",
+		RunE: func(*cobra.Command, []string) error {
+			gs := c.Parse()
+			defer gs.Close()
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return gs.Run(ctx, os.Stdout)
+		},
+	}
+	configFlags(runCmd.Flags(), &c)
+
+	var r gossh.ReplayConfig
+	replayCmd := &cobra.Command{
+		Use:   "replay",
+		Short: "play back or search the recorded sessions",
+		RunE: func(_ *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return r.Replay(ctx, os.Stdout, args...)
+		},
+	}
+	replayFlags(replayCmd.Flags(), &r)
+
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "show gossh version",
+		Run:   func(*cobra.Command, []string) { fmt.Printf("%s\n", ver.Version()) },
+	}
+
+	rootCmd := &cobra.Command{
+		Use:   "gossh",
+		Short: "ssh/upload/download in multiple hosts, with port forwardings",
+		// gossh -H ... is the same as gossh run -H ...
+		RunE: runCmd.RunE,
+	}
+	rootCmd.Flags().AddFlagSet(runCmd.Flags())
+	rootCmd.AddCommand(runCmd, replayCmd, versionCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err)
+		os.Exit(1)
+	}
+}
+
+func configFlags(f *pflag.FlagSet, c *gossh.Config) {
+	f.StringVarP(&c.ReplaceQuote, "replaceQuote", "q", "", "replace for quote")
+	f.StringVarP(&c.ReplaceBang, "replaceBang", "b", "", "replace for bang(!)")
+	f.StringVarP(&c.Separator, "separator", "s", "", "separator for hosts, cmds, default comma")
+	f.StringVar(&c.NetTimeout, "netTimeout", "", "timeout(eg. 15s, 3m), empty for no timeout")
+	f.StringVar(&c.CmdTimeout, "cmdTimeout", "", "timeout(eg. 15s, 3m), default 15m")
+	f.StringVar(&c.Group, "group", "", "group name")
+	f.StringVar(&c.CmdsFile, "cmdsFile", "", "cmds file")
+	f.StringVarP(&c.HostsFile, "hostsFile", "f", "", "hosts file")
+	f.StringVar(&c.Pass, "pass", "", "pass.")
+	f.StringVarP(&c.User, "user", "u", "", "user")
+	f.StringVarP(&c.Passphrase, "passphrase", "p", "", "passphrase for decrypt {PBE}Password")
+	f.StringArrayVarP(&c.Cmds, "cmds", "C", nil, "commands to be executedChan")
+	f.StringArrayVarP(&c.Hosts, "hosts", "H", nil, "hosts")
+	f.IntVarP(&c.ExecMode, "execMode", "e", gossh.ExecModeCmdByCmd, "exec mode(0: cmd by cmd, 1 host by host)")
+	f.BoolVar(&c.FirstConfirm, "firstConfirm", false, "confirm before the first command")
+	f.BoolVar(&c.Confirm, "confirm", false, "conform to continue.")
+	f.BoolVarP(&c.GlobalRemote, "globalRemote", "g", false, "run as global remote ssh command(no need %host)")
+	f.BoolVarP(&c.PrintConfig, "printConfig", "P", false, "print config before running")
+	f.BoolVarP(&c.SplitSSH, "splitSSH", "S", false, "split ssh commands by comma or not")
+	f.StringVar(&c.SSHConfig, "sshConfig", "", "ssh config file for Host/HostName/User/Port/IdentityFile/ProxyJump, default ~/.ssh/config, - to disable")
+	f.StringVar(&c.HostKeyCheck, "hostKeyCheck", "", "host key checking against known_hosts: no (default), yes, tofu (trust on first use)")
+	f.StringVar(&c.KnownHosts, "knownHosts", "", "known_hosts file, default ~/.ssh/known_hosts")
+	f.BoolVarP(&c.ForwardAgent, "forwardAgent", "A", false, "forward ssh-agent to the remote hosts")
+	f.StringArrayVarP(&c.LocalForward, "localForward", "L", nil, "local port forwarding [bind_address:]port:host:hostport via the first host")
+	f.StringArrayVarP(&c.RemoteForward, "remoteForward", "R", nil, "remote port forwarding [bind_address:]port:host:hostport via the first host")
+	f.StringArrayVarP(&c.DynamicForward, "dynamicForward", "D", nil, "dynamic SOCKS5 port forwarding [bind_address:]port via the first host")
+	f.StringVar(&c.Playbook, "playbook", "", "playbook file (yaml or toml) of structured tasks")
+	f.BoolVar(&c.DryRun, "dryRun", false, "print the resolved playbook plan for each host without executing")
+	f.StringVar(&c.RecordDir, "recordDir", "", "dir to record every host session as asciicast v2 file, eg. ~/.gossh/casts")
+}
+
+func replayFlags(f *pflag.FlagSet, r *gossh.ReplayConfig) {
+	f.StringVar(&r.Dir, "dir", "", "recordings dir, default ~/.gossh/casts")
+	f.StringVarP(&r.Search, "search", "s", "", "search the recordings for the command by regexp")
+	f.Float64Var(&r.Speed, "speed", 0, "playback speed, default 1")
+	f.StringVar(&r.MaxIdle, "maxIdle", "", "max idle time between events when playing back(eg. 2s), empty for no limit")
+}
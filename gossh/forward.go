@@ -0,0 +1,113 @@
+package gossh
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/bingoohuang/ngg/gossh/pkg/gossh"
+	"golang.org/x/sync/errgroup"
+)
+
+// forwardTypes are the types of port forwardings in the order they are applied.
+var forwardTypes = []gossh.ForwardType{gossh.ForwardLocal, gossh.ForwardRemote, gossh.ForwardDynamic}
+
+// Forwards collects the port forwardings of the host, defined by host properties L/R/D,
+// eg. `192.168.1.1:22 root/pass L=13306:127.0.0.1:3306 D=1080`.
+func (h *Host) Forwards() ([]gossh.Forward, error) {
+	var forwards []gossh.Forward
+	for _, typ := range forwardTypes {
+		for _, spec := range h.Properties[string(typ)] {
+			f, err := gossh.ParseForward(typ, spec)
+			if err != nil {
+				return nil, fmt.Errorf("host %s: %w", h.ID, err)
+			}
+			forwards = append(forwards, f)
+		}
+	}
+
+	return forwards, nil
+}
+
+// configForwards parses the LocalForward/RemoteForward/DynamicForward in the config.
+func (c *Config) configForwards() ([]gossh.Forward, error) {
+	var forwards []gossh.Forward
+	for i, specs := range [][]string{c.LocalForward, c.RemoteForward, c.DynamicForward} {
+		typ := forwardTypes[i]
+		for _, spec := range specs {
+			f, err := gossh.ParseForward(typ, spec)
+			if err != nil {
+				return nil, err
+			}
+			forwards = append(forwards, f)
+		}
+	}
+
+	return forwards, nil
+}
+
+// HasForwards tells whether there are any port forwardings defined in the config or hosts.
+func (g *GoSSH) HasForwards() bool {
+	if len(g.Config.LocalForward)+len(g.Config.RemoteForward)+len(g.Config.DynamicForward) > 0 {
+		return true
+	}
+
+	for _, h := range g.Hosts {
+		for _, typ := range forwardTypes {
+			if len(h.Properties[string(typ)]) > 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Forward starts the port forwarding tunnels of the hosts in the group, and blocks until ctx is done.
+// The forwards in the config are applied to the first host of the group.
+// Tunnels reconnect automatically, and jump hosts (proxy or ProxyJump) are supported.
+func (g *GoSSH) Forward(ctx context.Context, hostGroup string) error {
+	global, err := g.Config.configForwards()
+	if err != nil {
+		return err
+	}
+
+	var tunnels []*gossh.Tunnel
+	for _, h := range g.Hosts {
+		if h.groups[hostGroup] != 1 {
+			continue
+		}
+
+		forwards, err := h.Forwards()
+		if err != nil {
+			return err
+		}
+
+		if len(global) > 0 {
+			forwards = append(global, forwards...)
+			global = nil
+		}
+
+		if len(forwards) == 0 {
+			continue
+		}
+
+		tunnels = append(tunnels, &gossh.Tunnel{Dial: h.GetGosshConnect, Forwards: forwards})
+	}
+
+	if len(global) > 0 {
+		return fmt.Errorf("no hosts in group %s for the port forwardings", hostGroup)
+	}
+
+	if len(tunnels) == 0 {
+		log.Printf("W! no port forwardings defined")
+		return nil
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+	for _, t := range tunnels {
+		eg.Go(func() error { return t.Run(ctx) })
+	}
+
+	return eg.Wait()
+}
@@ -1,10 +1,13 @@
 package gossh
 
 import (
+	"fmt"
+	"log"
 	"net"
 	"strings"
 
 	"github.com/bingoohuang/ngg/gossh/pkg/hostparse"
+	"github.com/bingoohuang/ngg/gossh/pkg/sshconfig"
 	"github.com/bingoohuang/ngg/ss"
 )
 
@@ -20,9 +23,99 @@ func (c Config) parseHosts() Hosts {
 
 	hosts.FixHost()
 	hosts.FixProxy()
+	c.applySSHConfig(hosts)
+
+	if c.ForwardAgent {
+		for _, h := range hosts {
+			h.ForwardAgent = true
+		}
+	}
 	return hosts
 }
 
+// maxJumpDepth limits the nested ProxyJump of the jump hosts.
+const maxJumpDepth = 10
+
+// applySSHConfig applies the ssh config (like ~/.ssh/config) to the hosts,
+// so the host can be specified by the Host alias, and ProxyJump hops are chained as the proxies.
+func (c Config) applySSHConfig(hosts Hosts) {
+	file := c.SSHConfig
+	if file == "-" {
+		return
+	}
+	if file == "" {
+		file = sshconfig.DefaultFile()
+	}
+
+	sc, err := sshconfig.ParseFile(file)
+	if err != nil {
+		log.Printf("W! failed to parse ssh config %s: %v", file, err)
+		return
+	}
+
+	for _, h := range hosts {
+		e := resolveSSHConfig(sc, h)
+		if h.Proxy != nil || len(e.ProxyJump) == 0 {
+			continue
+		}
+
+		if h.Proxy, err = jumpHosts(sc, e.ProxyJump, 0); err != nil {
+			log.Fatalf("F! failed to resolve ProxyJump of %s: %v", e.Alias, err)
+		}
+	}
+}
+
+// resolveSSHConfig fills the host by the matched ssh config entry.
+func resolveSSHConfig(sc *sshconfig.Config, h *Host) sshconfig.Entry {
+	host, port, err := net.SplitHostPort(h.Addr)
+	if err != nil {
+		host, port = h.Addr, ""
+	}
+
+	e := sc.Resolve(host)
+	// like ssh, the ssh config Port only applies when the port is not given explicitly.
+	if !h.hasPort {
+		port = ss.Or(e.Port, port)
+	}
+
+	h.Addr = net.JoinHostPort(e.HostName, ss.Or(port, "22"))
+	h.User = ss.Or(h.User, e.User)
+	h.IdentityFiles = append(h.IdentityFiles, e.IdentityFiles...)
+	h.ForwardAgent = h.ForwardAgent || e.ForwardAgent
+	return e
+}
+
+// jumpHosts creates the hosts for the ProxyJump hops like `user@bastion1:22,bastion2`,
+// returns the last hop, which is the direct proxy of the target host.
+func jumpHosts(sc *sshconfig.Config, hops []string, depth int) (proxy *Host, err error) {
+	if depth >= maxJumpDepth {
+		return nil, fmt.Errorf("ProxyJump can not be nested more than %d", maxJumpDepth)
+	}
+
+	for i, hop := range hops {
+		user, host, port := sshconfig.ParseJump(hop)
+		h := &Host{
+			ID:         "jump:" + hop,
+			Addr:       net.JoinHostPort(host, ss.Or(port, "22")),
+			User:       user,
+			Properties: make(map[string][]string),
+			hasPort:    port != "",
+		}
+
+		// like ssh, only the first hop uses its own ProxyJump, the other hops are reached by the previous one.
+		if e := resolveSSHConfig(sc, h); i == 0 && len(e.ProxyJump) > 0 {
+			if proxy, err = jumpHosts(sc, e.ProxyJump, depth+1); err != nil {
+				return nil, err
+			}
+		}
+
+		h.Proxy = proxy
+		proxy = h
+	}
+
+	return proxy, nil
+}
+
 func (c Config) parseHost(host string) Hosts {
 	return convertHosts(hostparse.Parse(host))
 }
@@ -35,7 +128,7 @@ func convertHosts(parsed []hostparse.Host) Hosts {
 	hosts := make(Hosts, len(parsed))
 	for i, p := range parsed {
 		addr := net.JoinHostPort(p.Addr, ss.Or(p.Port, "22"))
-		hosts[i] = &Host{ID: p.ID, Addr: addr, User: p.User, Password: p.Password, Properties: p.Props}
+		hosts[i] = &Host{ID: p.ID, Addr: addr, User: p.User, Password: p.Password, Properties: p.Props, hasPort: p.Port != ""}
 
 		for k, v := range p.Props {
 			if strings.HasPrefix(k, "@") && IsCapitalized(k[1:]) {
@@ -12,6 +12,7 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/bingoohuang/ngg/gossh/pkg/asciicast"
 	"github.com/bingoohuang/ngg/gossh/pkg/cmdtype"
 	"github.com/bingoohuang/ngg/gossh/pkg/gossh"
 	"github.com/bingoohuang/ngg/ss"
@@ -53,6 +54,20 @@ type Config struct {
 	PrintConfig  bool `help:"print config before running" short:"P"`
 
 	SplitSSH bool `help:"split ssh commands by comma or not" short:"S"`
+
+	SSHConfig    string `help:"ssh config file for Host/HostName/User/Port/IdentityFile/ProxyJump, default ~/.ssh/config, - to disable"`
+	HostKeyCheck string `help:"host key checking against known_hosts: no (default), yes, tofu (trust on first use)"`
+	KnownHosts   string `help:"known_hosts file, default ~/.ssh/known_hosts"`
+	ForwardAgent bool   `help:"forward ssh-agent to the remote hosts" short:"A"`
+
+	LocalForward   []string `help:"local port forwarding [bind_address:]port:host:hostport via the first host" short:"L"`
+	RemoteForward  []string `help:"remote port forwarding [bind_address:]port:host:hostport via the first host" short:"R"`
+	DynamicForward []string `help:"dynamic SOCKS5 port forwarding [bind_address:]port via the first host" short:"D"`
+
+	Playbook string `help:"playbook file (yaml or toml) of structured tasks"`
+	DryRun   bool   `help:"print the resolved playbook plan for each host without executing"`
+
+	RecordDir string `help:"dir to record every host session as asciicast v2 file, eg. ~/.gossh/casts"`
 }
 
 const (
@@ -64,6 +79,9 @@ const (
 	ExecModeHostByHost
 )
 
+// defaultCmdTimeout is the timeout of a command when CmdTimeout is not set.
+const defaultCmdTimeout = 15 * time.Minute
+
 // GetSeparator get the separator.
 func (c Config) GetSeparator() string { return c.Separator }
 
@@ -96,6 +114,8 @@ type Host struct {
 
 	sftpSSHClient *ssh.Client
 
+	recorder *asciicast.Recorder
+
 	cmdChan      chan CmdWrap
 	executedChan chan any
 
@@ -104,7 +124,14 @@ type Host struct {
 	User     string
 	ID       string
 
+	// IdentityFiles are the private key files, like IdentityFile in ssh config.
+	IdentityFiles []string
+
 	localConnected bool
+	// hasPort tells whether the port is given explicitly, or else the default 22 is used.
+	hasPort bool
+	// ForwardAgent forwards the local ssh-agent to the remote host.
+	ForwardAgent bool
 }
 
 // globalVarsMap is the global map of result variable.
@@ -197,6 +224,12 @@ func (h *Host) Close() error {
 		g.Go(c.Close)
 	}
 
+	if r := h.recorder; r != nil {
+		h.recorder = nil
+
+		g.Go(r.Close)
+	}
+
 	return g.Wait()
 }
 
@@ -210,17 +243,62 @@ func (h *Host) GetGosshConnect() (*gossh.Connect, error) {
 			return nil, err
 		}
 
-		gc.ProxyDialer = pc.Client
+		// use the Connect as dialer, so that the whole jump chain is closed together.
+		gc.ProxyDialer = pc
 	}
 
-	key := gossh.PasswordKey(h.User, h.Password)
-	if err := gc.CreateClient(h.Addr, key); err != nil {
+	if err := gc.CreateClient(h.Addr, h.clientConfig()); err != nil {
 		return nil, fmt.Errorf("CreateClient(%s) failed: %w", h.Addr, err)
 	}
 
 	return gc, nil
 }
 
+// sshAgent is the local ssh-agent, nil when SSH_AUTH_SOCK is not available.
+var sshAgent = sync.OnceValue(gossh.SSHAgent)
+
+// hostKeyCallback is created by the global HostKeyCheck and KnownHosts settings.
+var hostKeyCallback = sync.OnceValue(func() ssh.HostKeyCallback {
+	check := gossh.HostKeyCheck(viper.GetString("HostKeyCheck"))
+	callback, err := gossh.HostKeyCallback(check, ss.ExpandHome(viper.GetString("KnownHosts")))
+	if err != nil {
+		log.Fatalf("F! failed to create host key callback: %v", err)
+	}
+	return callback
+})
+
+// clientConfig makes the ssh.ClientConfig with identity files, ssh-agent and password auth methods.
+func (h *Host) clientConfig() *ssh.ClientConfig {
+	var auth []ssh.AuthMethod
+
+	var signers []ssh.Signer
+	for _, f := range h.IdentityFiles {
+		signer, err := gossh.IdentityFileSigner(f, "")
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("W! failed to load identity file %s: %v", f, err)
+			}
+			continue
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+
+	if a := sshAgent(); a != nil {
+		auth = append(auth, gossh.AgentAuth(a))
+	}
+
+	if h.Password != "" || len(auth) == 0 {
+		auth = append(auth, ssh.Password(h.Password))
+	}
+
+	cc := gossh.MakeClientConfig(h.User, auth)
+	cc.HostKeyCallback = hostKeyCallback()
+	return cc
+}
+
 const ignoreWarning = "-q -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null"
 
 // PrintSSH prints sshpass ssh commands.
@@ -521,10 +599,13 @@ func (c *Config) parseVars() {
 
 	cmdTimeout, _ := time.ParseDuration(c.CmdTimeout)
 	if cmdTimeout == 0 {
-		cmdTimeout = 15 * time.Minute // nolint
+		cmdTimeout = defaultCmdTimeout
 	}
 
 	viper.Set("CmdTimeout", cmdTimeout)
+	viper.Set("HostKeyCheck", c.HostKeyCheck)
+	viper.Set("KnownHosts", c.KnownHosts)
+	viper.Set("RecordDir", c.RecordDir)
 
 	if c.ReplaceQuote != "" {
 		for i, cmd := range c.Cmds {
@@ -0,0 +1,385 @@
+// Package asciicast records and replays terminal sessions in asciicast v2 format,
+// see https://docs.asciinema.org/manual/asciicast/v2/.
+package asciicast
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Header is the first line of the asciicast v2 file.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// EventType is the type of event, o for output, i for input.
+type EventType string
+
+const (
+	// Output is the data printed to the terminal.
+	Output EventType = "o"
+	// Input is the data typed by the user.
+	Input EventType = "i"
+)
+
+// Event is an event line like [1.001, "o", "hello"].
+type Event struct {
+	Time float64
+	Type EventType
+	Data string
+}
+
+// MarshalJSON marshals the event as a json array.
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Time, e.Type, e.Data})
+}
+
+// UnmarshalJSON unmarshals the event from a json array.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var arr []json.RawMessage
+	if err := json.Unmarshal(data, &arr); err != nil {
+		return err
+	}
+	if len(arr) != 3 {
+		return fmt.Errorf("invalid event %s", data)
+	}
+
+	if err := json.Unmarshal(arr[0], &e.Time); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(arr[1], &e.Type); err != nil {
+		return err
+	}
+	return json.Unmarshal(arr[2], &e.Data)
+}
+
+// Recorder writes the events to the asciicast file.
+type Recorder struct {
+	w     io.WriteCloser
+	bw    *bufio.Writer
+	start time.Time
+	mu    sync.Mutex
+	err   error
+
+	output, input *eventWriter
+}
+
+// Create creates the asciicast file and writes the header.
+func Create(file string, h Header) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := NewRecorder(f, h)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// NewRecorder creates a Recorder writing to w.
+func NewRecorder(w io.WriteCloser, h Header) (*Recorder, error) {
+	start := time.Now()
+	h.Version = 2
+	if h.Timestamp == 0 {
+		h.Timestamp = start.Unix()
+	}
+
+	header, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{w: w, bw: bufio.NewWriter(w), start: start}
+	r.output, r.input = &eventWriter{r: r, typ: Output}, &eventWriter{r: r, typ: Input}
+	if _, err := r.bw.Write(append(header, '\n')); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Record records the data as an event with the elapsed time since the recording started.
+func (r *Recorder) Record(typ EventType, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	elapsed := time.Since(r.start).Seconds()
+	line, err := json.Marshal(Event{Time: math.Round(elapsed*1e6) / 1e6, Type: typ, Data: string(data)})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+	if _, r.err = r.bw.Write(append(line, '\n')); r.err == nil {
+		r.err = r.bw.Flush()
+	}
+
+	return r.err
+}
+
+// Output returns an io.Writer which records the written data as output events.
+func (r *Recorder) Output() io.Writer { return r.output }
+
+// Input returns an io.Writer which records the written data as input events.
+func (r *Recorder) Input() io.Writer { return r.input }
+
+// Close flushes and closes the file.
+func (r *Recorder) Close() error {
+	r.output.flush()
+	r.input.flush()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.bw.Flush()
+	if err1 := r.w.Close(); err == nil {
+		err = err1
+	}
+	if r.err == nil {
+		r.err = errors.New("recorder closed")
+	}
+
+	return err
+}
+
+type eventWriter struct {
+	r   *Recorder
+	typ EventType
+
+	mu sync.Mutex
+	// partial is the incomplete UTF-8 sequence at the end of the last write.
+	partial []byte
+}
+
+// Write records p, the recording error is ignored, so the session is never broken by the recorder.
+// An incomplete UTF-8 sequence at the end is kept until the next write, so that a multibyte rune is not split.
+func (w *eventWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data := append(w.partial, p...)
+	n := completeLen(data)
+	w.partial = append([]byte(nil), data[n:]...)
+	_ = w.r.Record(w.typ, data[:n])
+	return len(p), nil
+}
+
+// flush records the remaining incomplete sequence as it is.
+func (w *eventWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_ = w.r.Record(w.typ, w.partial)
+	w.partial = nil
+}
+
+// completeLen returns the length of p without the incomplete UTF-8 sequence at the end.
+func completeLen(p []byte) int {
+	// a rune is at most utf8.UTFMax bytes, so only the last utf8.UTFMax-1 bytes can be incomplete.
+	for i := len(p) - 1; i >= 0 && i >= len(p)-(utf8.UTFMax-1); i-- {
+		if utf8.RuneStart(p[i]) {
+			if !utf8.FullRune(p[i:]) {
+				return i
+			}
+			break
+		}
+	}
+
+	return len(p)
+}
+
+// Cast is a parsed asciicast file.
+type Cast struct {
+	Header Header
+	Events []Event
+}
+
+// ReadFile reads the asciicast file.
+func ReadFile(file string) (*Cast, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Read reads the asciicast v2 content.
+func Read(r io.Reader) (*Cast, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	c := &Cast{}
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("empty asciicast")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &c.Header); err != nil {
+		return nil, fmt.Errorf("parse header: %w", err)
+	}
+	if c.Header.Version != 2 {
+		return nil, fmt.Errorf("unsupported asciicast version %d", c.Header.Version)
+	}
+
+	for line := 2; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("parse line %d: %w", line, err)
+		}
+		c.Events = append(c.Events, e)
+	}
+
+	return c, scanner.Err()
+}
+
+// Play writes the output events to w in their timing.
+// speed speeds up the playing, and maxIdle limits the pause between events, 0 for no limit.
+func (c *Cast) Play(ctx context.Context, w io.Writer, speed float64, maxIdle time.Duration) error {
+	if speed <= 0 {
+		speed = 1
+	}
+
+	last := 0.0
+	for _, e := range c.Events {
+		if e.Type != Output {
+			continue
+		}
+
+		wait := time.Duration((e.Time - last) / speed * float64(time.Second))
+		if maxIdle > 0 && wait > maxIdle {
+			wait = maxIdle
+		}
+		last = e.Time
+
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		if _, err := io.WriteString(w, e.Data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Commands collects the input lines typed in the session, with the time when the line is finished.
+func (c *Cast) Commands() []Event {
+	var commands []Event
+	var line strings.Builder
+	var lineTime float64
+
+	for _, e := range c.Events {
+		if e.Type != Input {
+			continue
+		}
+
+		for _, r := range e.Data {
+			switch r {
+			case '\r', '\n':
+				if cmd := strings.TrimSpace(line.String()); cmd != "" {
+					commands = append(commands, Event{Time: e.Time, Type: Input, Data: cmd})
+				}
+				line.Reset()
+			default:
+				line.WriteRune(r)
+				lineTime = e.Time
+			}
+		}
+	}
+
+	// the last line without a newline, takes the time when it is typed last.
+	if cmd := strings.TrimSpace(line.String()); cmd != "" {
+		commands = append(commands, Event{Time: lineTime, Type: Input, Data: cmd})
+	}
+
+	return commands
+}
+
+// Match is a command matched in the recording file.
+type Match struct {
+	File   string
+	Title  string
+	Start  time.Time
+	Offset time.Duration
+	Cmd    string
+}
+
+func (m Match) String() string {
+	return fmt.Sprintf("%s %s [%s +%s] %s", m.File, m.Title,
+		m.Start.Format(time.RFC3339), m.Offset.Truncate(time.Millisecond), m.Cmd)
+}
+
+// Search searches the commands matching the regexp in all the *.cast files of the dir.
+func Search(dir string, re *regexp.Regexp) ([]Match, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.cast"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var matches []Match
+	for _, f := range files {
+		c, err := ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", f, err)
+		}
+
+		for _, cmd := range c.Commands() {
+			if re.MatchString(cmd.Data) {
+				matches = append(matches, Match{
+					File:   f,
+					Title:  c.Header.Title,
+					Start:  time.Unix(c.Header.Timestamp, 0),
+					Offset: time.Duration(cmd.Time * float64(time.Second)),
+					Cmd:    cmd.Data,
+				})
+			}
+		}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,63 @@
+package asciicast_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	. "github.com/bingoohuang/ngg/gossh/pkg/asciicast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndSearch(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Create(filepath.Join(dir, "h1.cast"), Header{Width: 80, Height: 24, Title: "h1"})
+	assert.Nil(t, err)
+
+	_, _ = r.Output().Write([]byte("[root@h1 ~]# "))
+	_, _ = r.Input().Write([]byte("rm -fr /tmp/x\n"))
+	_, _ = r.Output().Write([]byte("rm -fr /tmp/x\r\n[root@h1 ~]# "))
+	_, _ = r.Input().Write([]byte("date\n"))
+	assert.Nil(t, r.Close())
+
+	c, err := ReadFile(filepath.Join(dir, "h1.cast"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, c.Header.Version)
+	assert.Len(t, c.Events, 4)
+	assert.Equal(t, []string{"rm -fr /tmp/x", "date"}, []string{c.Commands()[0].Data, c.Commands()[1].Data})
+
+	var out bytes.Buffer
+	assert.Nil(t, c.Play(context.Background(), &out, 100, 0))
+	assert.Equal(t, "[root@h1 ~]# rm -fr /tmp/x\r\n[root@h1 ~]# ", out.String())
+
+	matches, err := Search(dir, regexp.MustCompile(`rm\s+-fr`))
+	assert.Nil(t, err)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, "h1", matches[0].Title)
+	assert.Equal(t, "rm -fr /tmp/x", matches[0].Cmd)
+}
+
+func TestRecordUTF8AndTrailingLine(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Create(filepath.Join(dir, "h2.cast"), Header{Width: 80, Height: 24})
+	assert.Nil(t, err)
+
+	out := []byte("你好\r\n")
+	_, _ = r.Output().Write(out[:4])
+	_, _ = r.Output().Write(out[4:])
+	_, _ = r.Input().Write([]byte("echo 世界"))
+	assert.Nil(t, r.Close())
+
+	c, err := ReadFile(filepath.Join(dir, "h2.cast"))
+	assert.Nil(t, err)
+	assert.Len(t, c.Events, 3)
+	assert.Equal(t, "你", c.Events[0].Data)
+	assert.Equal(t, "好\r\n", c.Events[1].Data)
+
+	cmds := c.Commands()
+	assert.Len(t, cmds, 1)
+	assert.Equal(t, "echo 世界", cmds[0].Data)
+	assert.Equal(t, c.Events[2].Time, cmds[0].Time)
+}
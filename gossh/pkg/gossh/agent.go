@@ -0,0 +1,58 @@
+package gossh
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// SSHAgent connects to the local ssh-agent by SSH_AUTH_SOCK, returns nil when no agent is available.
+func SSHAgent() agent.ExtendedAgent {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil
+	}
+
+	return agent.NewClient(conn)
+}
+
+// AgentAuth returns the ssh.AuthMethod which uses signers from the ssh-agent.
+func AgentAuth(a agent.Agent) ssh.AuthMethod {
+	return ssh.PublicKeysCallback(a.Signers)
+}
+
+// ForwardAgent forwards the local ssh-agent to the remote host on the session.
+func ForwardAgent(client *ssh.Client, session *ssh.Session, a agent.Agent) error {
+	if err := agent.ForwardToAgent(client, a); err != nil {
+		return fmt.Errorf("forward to agent: %w", err)
+	}
+
+	if err := agent.RequestAgentForwarding(session); err != nil {
+		return fmt.Errorf("request agent forwarding: %w", err)
+	}
+
+	return nil
+}
+
+// IdentityFileSigner loads the private key file as a ssh.Signer, passphrase is only used for encrypted keys.
+func IdentityFileSigner(path, passphrase string) (ssh.Signer, error) {
+	privateKey, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	if _, ok := err.(*ssh.PassphraseMissingError); ok && passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, []byte(passphrase))
+	}
+
+	return signer, err
+}
@@ -1,7 +1,9 @@
 package gossh
 
 import (
+	"errors"
 	"io"
+	"net"
 	"time"
 
 	"github.com/bingoohuang/ngg/gnet"
@@ -48,13 +50,23 @@ func (c *Connect) CreateClient(addr string, cc *ssh.ClientConfig) error {
 	return nil
 }
 
-// Close closes the ssh client.
+// Dial dials the address from the remote host, so that the Connect can be used as a jump host.
+func (c *Connect) Dial(network, addr string) (net.Conn, error) {
+	if c.Client == nil {
+		return nil, errors.New("ssh client is not connected")
+	}
+
+	return c.Client.Dial(network, addr)
+}
+
+// Close closes the ssh client, and the proxy dialer (jump hosts) in chain.
 func (c *Connect) Close() error {
 	client := c.Client
 	c.Client = nil
 
+	var err error
 	if client != nil {
-		return client.Close()
+		err = client.Close()
 	}
 
 	if c.ProxyDialer != nil {
@@ -63,5 +75,5 @@ func (c *Connect) Close() error {
 		}
 	}
 
-	return nil
+	return err
 }
@@ -0,0 +1,340 @@
+package gossh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ForwardType is the type of port forwarding.
+type ForwardType string
+
+const (
+	// ForwardLocal forwards the local port to the address reached from the remote host, like ssh -L.
+	ForwardLocal ForwardType = "L"
+	// ForwardRemote forwards the remote port to the address reached from the local host, like ssh -R.
+	ForwardRemote ForwardType = "R"
+	// ForwardDynamic starts a local SOCKS5 proxy which dials from the remote host, like ssh -D.
+	ForwardDynamic ForwardType = "D"
+)
+
+// Forward defines a port forwarding.
+type Forward struct {
+	Type ForwardType
+	// Listen is the address to listen, on local host for L/D, and on remote host for R.
+	Listen string
+	// Target is the address to dial, from remote host for L, and from local host for R, empty for D.
+	Target string
+}
+
+func (f Forward) String() string {
+	if f.Type == ForwardDynamic {
+		return fmt.Sprintf("-%s %s", f.Type, f.Listen)
+	}
+
+	return fmt.Sprintf("-%s %s => %s", f.Type, f.Listen, f.Target)
+}
+
+// ParseForward parses the forward spec like ssh does,
+// [bind_address:]port:host:hostport for L and R, [bind_address:]port for D.
+func ParseForward(typ ForwardType, spec string) (Forward, error) {
+	f := Forward{Type: typ}
+	parts := splitForwardSpec(spec)
+
+	switch typ {
+	case ForwardDynamic:
+		switch len(parts) {
+		case 1:
+			f.Listen = net.JoinHostPort("127.0.0.1", parts[0])
+		case 2:
+			f.Listen = net.JoinHostPort(parts[0], parts[1])
+		default:
+			return f, fmt.Errorf("invalid dynamic forward %q, should be [bind_address:]port", spec)
+		}
+	case ForwardLocal, ForwardRemote:
+		switch len(parts) {
+		case 3:
+			f.Listen = net.JoinHostPort("127.0.0.1", parts[0])
+			f.Target = net.JoinHostPort(parts[1], parts[2])
+		case 4:
+			f.Listen = net.JoinHostPort(parts[0], parts[1])
+			f.Target = net.JoinHostPort(parts[2], parts[3])
+		default:
+			return f, fmt.Errorf("invalid forward %q, should be [bind_address:]port:host:hostport", spec)
+		}
+	default:
+		return f, fmt.Errorf("unknown forward type %q", typ)
+	}
+
+	return f, nil
+}
+
+// splitForwardSpec splits the spec by colon, the IPv6 address can be quoted in brackets.
+func splitForwardSpec(spec string) (parts []string) {
+	for spec != "" {
+		if strings.HasPrefix(spec, "[") {
+			if p := strings.Index(spec, "]"); p > 0 {
+				parts = append(parts, spec[1:p])
+				spec = strings.TrimPrefix(spec[p+1:], ":")
+				continue
+			}
+		}
+
+		p := strings.Index(spec, ":")
+		if p < 0 {
+			parts = append(parts, spec)
+			break
+		}
+
+		parts = append(parts, spec[:p])
+		spec = spec[p+1:]
+	}
+
+	return parts
+}
+
+// Tunnel keeps the port forwardings over the ssh connection, and reconnects automatically when it is broken.
+type Tunnel struct {
+	// Dial creates a new ssh connection, jump hosts are handled by the Connect itself.
+	Dial     func() (*Connect, error)
+	Forwards []Forward
+
+	// RetryInterval is the interval to reconnect, default 5s.
+	RetryInterval time.Duration
+	// KeepAlive is the interval to send keepalive requests, default 30s.
+	KeepAlive time.Duration
+
+	conn    *Connect
+	connMu  sync.Mutex
+	connErr error
+}
+
+// Run starts the tunnel and blocks until the ctx is done.
+func (t *Tunnel) Run(ctx context.Context) error {
+	retry := t.RetryInterval
+	if retry <= 0 {
+		retry = 5 * time.Second
+	}
+
+	var listeners []net.Listener
+	defer func() {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+	}()
+
+	for _, f := range t.Forwards {
+		if f.Type == ForwardRemote {
+			continue
+		}
+
+		l, err := net.Listen("tcp", f.Listen)
+		if err != nil {
+			return fmt.Errorf("listen %s: %w", f, err)
+		}
+		listeners = append(listeners, l)
+		log.Printf("I! forward %s started", f)
+
+		go t.serveLocal(ctx, l, f)
+	}
+
+	for ctx.Err() == nil {
+		conn, err := t.connect()
+		if err != nil {
+			log.Printf("W! tunnel connect failed: %v, retry in %s", err, retry)
+			if !sleepCtx(ctx, retry) {
+				break
+			}
+			continue
+		}
+
+		remoteListeners := t.serveRemotes(ctx, conn)
+		t.wait(ctx, conn)
+
+		for _, l := range remoteListeners {
+			_ = l.Close()
+		}
+		t.disconnect(conn)
+
+		if ctx.Err() == nil {
+			log.Printf("W! tunnel disconnected, reconnect in %s", retry)
+			sleepCtx(ctx, retry)
+		}
+	}
+
+	return nil
+}
+
+func (t *Tunnel) connect() (*Connect, error) {
+	conn, err := t.Dial()
+
+	t.connMu.Lock()
+	t.conn, t.connErr = conn, err
+	t.connMu.Unlock()
+
+	return conn, err
+}
+
+func (t *Tunnel) disconnect(conn *Connect) {
+	t.connMu.Lock()
+	if t.conn == conn {
+		t.conn, t.connErr = nil, errors.New("tunnel is reconnecting")
+	}
+	t.connMu.Unlock()
+
+	_ = conn.Close()
+}
+
+func (t *Tunnel) current() (*Connect, error) {
+	t.connMu.Lock()
+	defer t.connMu.Unlock()
+
+	if t.conn == nil && t.connErr == nil {
+		return nil, errors.New("tunnel is not connected")
+	}
+
+	return t.conn, t.connErr
+}
+
+// wait waits the connection broken by sending keepalive requests periodically.
+func (t *Tunnel) wait(ctx context.Context, conn *Connect) {
+	interval := t.KeepAlive
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	client := conn.Client
+	done := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (t *Tunnel) serveLocal(ctx context.Context, l net.Listener, f Forward) {
+	for {
+		local, err := l.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("W! forward %s accept failed: %v", f, err)
+			}
+			return
+		}
+
+		go func() {
+			if f.Type == ForwardDynamic {
+				t.serveSocks5(local, f)
+			} else {
+				t.dialRemote(local, f, f.Target)
+			}
+		}()
+	}
+}
+
+func (t *Tunnel) dialRemote(local net.Conn, f Forward, target string) {
+	conn, err := t.current()
+	if err != nil {
+		log.Printf("W! forward %s: %v", f, err)
+		_ = local.Close()
+		return
+	}
+
+	remote, err := conn.Dial("tcp", target)
+	if err != nil {
+		log.Printf("W! forward %s dial %s failed: %v", f, target, err)
+		_ = local.Close()
+		return
+	}
+
+	pipe(local, remote)
+}
+
+func (t *Tunnel) serveRemotes(ctx context.Context, conn *Connect) (listeners []net.Listener) {
+	for _, f := range t.Forwards {
+		if f.Type != ForwardRemote {
+			continue
+		}
+
+		l, err := conn.Client.Listen("tcp", f.Listen)
+		if err != nil {
+			log.Printf("W! forward %s listen on remote failed: %v", f, err)
+			continue
+		}
+		listeners = append(listeners, l)
+		log.Printf("I! forward %s started", f)
+
+		go func(l net.Listener, f Forward) {
+			for {
+				remote, err := l.Accept()
+				if err != nil {
+					if ctx.Err() == nil && err != io.EOF {
+						log.Printf("W! forward %s accept failed: %v", f, err)
+					}
+					return
+				}
+
+				go func() {
+					local, err := net.Dial("tcp", f.Target)
+					if err != nil {
+						log.Printf("W! forward %s dial %s failed: %v", f, f.Target, err)
+						_ = remote.Close()
+						return
+					}
+
+					pipe(remote, local)
+				}()
+			}
+		}(l, f)
+	}
+
+	return listeners
+}
+
+// pipe copies data between the two connections until either one is closed.
+func pipe(a, b net.Conn) {
+	defer a.Close()
+	defer b.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+
+	<-done
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
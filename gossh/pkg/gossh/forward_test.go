@@ -0,0 +1,179 @@
+package gossh_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/bingoohuang/ngg/gossh/pkg/gossh"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseForward(t *testing.T) {
+	f, err := ParseForward(ForwardLocal, "13306:db1:3306")
+	assert.Nil(t, err)
+	assert.Equal(t, Forward{Type: ForwardLocal, Listen: "127.0.0.1:13306", Target: "db1:3306"}, f)
+
+	f, err = ParseForward(ForwardRemote, "0.0.0.0:8080:[::1]:80")
+	assert.Nil(t, err)
+	assert.Equal(t, Forward{Type: ForwardRemote, Listen: "0.0.0.0:8080", Target: "[::1]:80"}, f)
+
+	f, err = ParseForward(ForwardDynamic, "1080")
+	assert.Nil(t, err)
+	assert.Equal(t, Forward{Type: ForwardDynamic, Listen: "127.0.0.1:1080"}, f)
+
+	_, err = ParseForward(ForwardLocal, "13306:db1")
+	assert.NotNil(t, err)
+}
+
+type rw struct {
+	*bytes.Reader
+	bytes.Buffer
+}
+
+func (r *rw) Read(p []byte) (int, error) { return r.Reader.Read(p) }
+
+func TestSocks5Handshake(t *testing.T) {
+	req := []byte{5, 1, 0, 5, 1, 0, 3, 7}
+	req = append(req, "db1.lan"...)
+	req = append(req, 0x0c, 0xea) // 3306
+
+	c := &rw{Reader: bytes.NewReader(req)}
+	target, err := Socks5Handshake(c)
+	assert.Nil(t, err)
+	assert.Equal(t, "db1.lan:3306", target)
+	assert.Equal(t, []byte{5, 0}, c.Bytes())
+
+	c = &rw{Reader: bytes.NewReader([]byte{5, 1, 2})}
+	_, err = Socks5Handshake(c)
+	assert.ErrorIs(t, err, ErrSocks5)
+	assert.Equal(t, []byte{5, 0xFF}, c.Bytes())
+}
+
+func TestTunnelLocalForward(t *testing.T) {
+	echoAddr := listenServe(t, func(c net.Conn) {
+		defer c.Close()
+		_, _ = io.Copy(c, c)
+	})
+	sshAddr := startSSHServer(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	listen := l.Addr().String()
+	_ = l.Close()
+
+	f, err := ParseForward(ForwardLocal, listen+":"+echoAddr)
+	assert.Nil(t, err)
+
+	tunnel := &Tunnel{
+		Dial: func() (*Connect, error) {
+			c := &Connect{}
+			cc := &ssh.ClientConfig{User: "test", HostKeyCallback: ssh.InsecureIgnoreHostKey(), Timeout: time.Second}
+			return c, c.CreateClient(sshAddr, cc)
+		},
+		Forwards:      []Forward{f},
+		RetryInterval: 10 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tunnel.Run(ctx) }()
+
+	assert.Eventually(t, func() bool { return echo(listen, "hello") == "hello" }, 3*time.Second, 20*time.Millisecond)
+
+	cancel()
+	assert.Nil(t, <-done)
+}
+
+func echo(addr, msg string) string {
+	c, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		return ""
+	}
+	defer c.Close()
+
+	_ = c.SetDeadline(time.Now().Add(time.Second))
+	if _, err := c.Write([]byte(msg)); err != nil {
+		return ""
+	}
+	buf := make([]byte, len(msg))
+	if _, err := io.ReadFull(c, buf); err != nil {
+		return ""
+	}
+	return string(buf)
+}
+
+func listenServe(t *testing.T, serve func(net.Conn)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(c)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+// startSSHServer starts an in-process ssh server which only supports the direct-tcpip channels.
+func startSSHServer(t *testing.T) string {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	assert.Nil(t, err)
+
+	conf := &ssh.ServerConfig{NoClientAuth: true}
+	conf.AddHostKey(signer)
+
+	return listenServe(t, func(c net.Conn) {
+		_, chans, reqs, err := ssh.NewServerConn(c, conf)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+
+		for nc := range chans {
+			var target struct {
+				Host     string
+				Port     uint32
+				OrigHost string
+				OrigPort uint32
+			}
+			if nc.ChannelType() != "direct-tcpip" || ssh.Unmarshal(nc.ExtraData(), &target) != nil {
+				_ = nc.Reject(ssh.UnknownChannelType, "unsupported")
+				continue
+			}
+
+			remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+			if err != nil {
+				_ = nc.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			ch, chReqs, err := nc.Accept()
+			if err != nil {
+				_ = remote.Close()
+				continue
+			}
+			go ssh.DiscardRequests(chReqs)
+			go func() {
+				defer ch.Close()
+				defer remote.Close()
+				go func() { _, _ = io.Copy(remote, ch) }()
+				_, _ = io.Copy(ch, remote)
+			}()
+		}
+	})
+}
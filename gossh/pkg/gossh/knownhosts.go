@@ -0,0 +1,121 @@
+package gossh
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyCheck defines how to verify the host key of the remote server.
+type HostKeyCheck string
+
+const (
+	// HostKeyCheckNo skips the host key verification.
+	HostKeyCheckNo HostKeyCheck = "no"
+	// HostKeyCheckYes rejects the hosts which are unknown or mismatched in known_hosts.
+	HostKeyCheckYes HostKeyCheck = "yes"
+	// HostKeyCheckTOFU trusts on first use, the unknown host key will be appended to known_hosts.
+	HostKeyCheckTOFU HostKeyCheck = "tofu"
+)
+
+// DefaultKnownHostsFile returns ~/.ssh/known_hosts.
+func DefaultKnownHostsFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
+
+var knownHostsLock sync.Mutex
+
+// HostKeyCallback creates the ssh.HostKeyCallback by the check mode and known_hosts file.
+func HostKeyCallback(check HostKeyCheck, file string) (ssh.HostKeyCallback, error) {
+	switch check {
+	case "", HostKeyCheckNo:
+		return ssh.InsecureIgnoreHostKey(), nil
+	case HostKeyCheckYes, HostKeyCheckTOFU:
+	default:
+		return nil, fmt.Errorf("unknown host key check mode %q", check)
+	}
+
+	if file == "" {
+		file = DefaultKnownHostsFile()
+	}
+
+	if check == HostKeyCheckTOFU {
+		if err := touchFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsLock.Lock()
+		defer knownHostsLock.Unlock()
+
+		// reload every time, so that the keys appended by TOFU are visible to the later hosts.
+		callback, err := knownhosts.New(file)
+		if err != nil {
+			return fmt.Errorf("load known_hosts %s: %w", file, err)
+		}
+
+		err = callback(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if err == nil || !errors.As(err, &keyErr) {
+			return err
+		}
+
+		if len(keyErr.Want) > 0 {
+			return fmt.Errorf("host key mismatch for %s, possible MITM attack: %w", hostname, err)
+		}
+
+		if check != HostKeyCheckTOFU {
+			return fmt.Errorf("host %s is unknown in %s: %w", hostname, file, err)
+		}
+
+		return appendKnownHost(file, hostname, remote, key)
+	}, nil
+}
+
+func appendKnownHost(file, hostname string, remote net.Addr, key ssh.PublicKey) error {
+	addresses := []string{knownhosts.Normalize(hostname)}
+	if remote != nil {
+		if r := knownhosts.Normalize(remote.String()); r != addresses[0] {
+			addresses = append(addresses, r)
+		}
+	}
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintln(f, knownhosts.Line(addresses, key)); err != nil {
+		return err
+	}
+
+	log.Printf("I! host %s added to %s with key %s", hostname, file, ssh.FingerprintSHA256(key))
+	return nil
+}
+
+func touchFile(file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
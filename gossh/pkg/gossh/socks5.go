@@ -0,0 +1,146 @@
+package gossh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+)
+
+// SOCKS5 protocol constants, see https://www.rfc-editor.org/rfc/rfc1928.
+const (
+	socks5Version    = 0x05
+	socks5NoAuth     = 0x00
+	socks5NoMethods  = 0xFF
+	socks5CmdConnect = 0x01
+
+	socks5AtypIPv4   = 0x01
+	socks5AtypDomain = 0x03
+	socks5AtypIPv6   = 0x04
+
+	socks5Succeeded        = 0x00
+	socks5GeneralFailure   = 0x01
+	socks5HostUnreachable  = 0x04
+	socks5CmdNotSupported  = 0x07
+	socks5AtypNotSupported = 0x08
+)
+
+// ErrSocks5 is the error of socks5 handshake.
+var ErrSocks5 = errors.New("socks5")
+
+// serveSocks5 serves the SOCKS5 CONNECT request, and dials the target from the remote host.
+func (t *Tunnel) serveSocks5(local net.Conn, f Forward) {
+	target, err := Socks5Handshake(local)
+	if err != nil {
+		log.Printf("W! forward %s: %v", f, err)
+		_ = local.Close()
+		return
+	}
+
+	conn, err := t.current()
+	if err != nil {
+		log.Printf("W! forward %s: %v", f, err)
+		_ = Socks5Reply(local, socks5GeneralFailure)
+		_ = local.Close()
+		return
+	}
+
+	remote, err := conn.Dial("tcp", target)
+	if err != nil {
+		log.Printf("W! forward %s dial %s failed: %v", f, target, err)
+		_ = Socks5Reply(local, socks5HostUnreachable)
+		_ = local.Close()
+		return
+	}
+
+	if err := Socks5Reply(local, socks5Succeeded); err != nil {
+		_ = local.Close()
+		_ = remote.Close()
+		return
+	}
+
+	pipe(local, remote)
+}
+
+// Socks5Handshake reads the method negotiation and the CONNECT request, returns the target address.
+// Only the NO AUTHENTICATION REQUIRED method and the CONNECT command are supported.
+func Socks5Handshake(rw io.ReadWriter) (target string, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(rw, head[:]); err != nil {
+		return "", fmt.Errorf("%w: read greeting: %w", ErrSocks5, err)
+	}
+	if head[0] != socks5Version {
+		return "", fmt.Errorf("%w: unsupported version %d", ErrSocks5, head[0])
+	}
+
+	methods := make([]byte, head[1])
+	if _, err := io.ReadFull(rw, methods); err != nil {
+		return "", fmt.Errorf("%w: read methods: %w", ErrSocks5, err)
+	}
+
+	method := byte(socks5NoMethods)
+	for _, m := range methods {
+		if m == socks5NoAuth {
+			method = socks5NoAuth
+			break
+		}
+	}
+	if _, err := rw.Write([]byte{socks5Version, method}); err != nil {
+		return "", fmt.Errorf("%w: write method: %w", ErrSocks5, err)
+	}
+	if method == socks5NoMethods {
+		return "", fmt.Errorf("%w: no acceptable methods", ErrSocks5)
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(rw, req[:]); err != nil {
+		return "", fmt.Errorf("%w: read request: %w", ErrSocks5, err)
+	}
+	if req[1] != socks5CmdConnect {
+		_ = Socks5Reply(rw, socks5CmdNotSupported)
+		return "", fmt.Errorf("%w: unsupported command %d", ErrSocks5, req[1])
+	}
+
+	var host string
+	switch req[3] {
+	case socks5AtypIPv4, socks5AtypIPv6:
+		ip := make(net.IP, net.IPv4len)
+		if req[3] == socks5AtypIPv6 {
+			ip = make(net.IP, net.IPv6len)
+		}
+		if _, err := io.ReadFull(rw, ip); err != nil {
+			return "", fmt.Errorf("%w: read address: %w", ErrSocks5, err)
+		}
+		host = ip.String()
+	case socks5AtypDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(rw, n[:]); err != nil {
+			return "", fmt.Errorf("%w: read domain length: %w", ErrSocks5, err)
+		}
+		domain := make([]byte, n[0])
+		if _, err := io.ReadFull(rw, domain); err != nil {
+			return "", fmt.Errorf("%w: read domain: %w", ErrSocks5, err)
+		}
+		host = string(domain)
+	default:
+		_ = Socks5Reply(rw, socks5AtypNotSupported)
+		return "", fmt.Errorf("%w: unsupported address type %d", ErrSocks5, req[3])
+	}
+
+	var port [2]byte
+	if _, err := io.ReadFull(rw, port[:]); err != nil {
+		return "", fmt.Errorf("%w: read port: %w", ErrSocks5, err)
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:])))), nil
+}
+
+// Socks5Reply writes the reply with the status code, the bound address is always 0.0.0.0:0.
+func Socks5Reply(w io.Writer, code byte) error {
+	// VER REP RSV ATYP BND.ADDR(4 bytes) BND.PORT(2 bytes)
+	_, err := w.Write([]byte{socks5Version, code, 0x00, socks5AtypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
@@ -44,7 +44,6 @@ func ParseDirectServer(server string) (ServerConfig, bool) {
 
 	if commaPos == -1 {
 		sc.Addr = right
-		sc.Port = "22"
 	} else {
 		sc.Addr = right[:commaPos]
 		sc.Port = right[commaPos+1:]
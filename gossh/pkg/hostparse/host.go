@@ -172,9 +172,10 @@ func SubstituteProps(s string, props map[string][]string) string {
 	return s
 }
 
+// SplitHostPort splits the addr into host and port, the port is empty when not given.
 func SplitHostPort(addr string) (string, string) {
 	if !strings.Contains(addr, ":") {
-		return addr, "22"
+		return addr, ""
 	}
 
 	pos := strings.Index(addr, ":")
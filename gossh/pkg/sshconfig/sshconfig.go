@@ -0,0 +1,263 @@
+package sshconfig
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bingoohuang/ngg/ss"
+)
+
+// Config is the parsed OpenSSH client config like ~/.ssh/config.
+type Config struct {
+	blocks []*block
+}
+
+type block struct {
+	patterns []string
+	params   []param
+}
+
+type param struct {
+	key    string // lower-cased keyword
+	values []string
+}
+
+// Entry is the resolved settings for a host alias.
+type Entry struct {
+	Alias         string
+	HostName      string
+	User          string
+	Port          string
+	IdentityFiles []string
+	// ProxyJump lists the jump hosts in order, the first one is connected first.
+	ProxyJump    []string
+	ForwardAgent bool
+}
+
+// DefaultFile returns the default ssh config file path ~/.ssh/config.
+func DefaultFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".ssh", "config")
+}
+
+// ParseFile parses the ssh config file, a missing file results an empty Config.
+func ParseFile(file string) (*Config, error) {
+	f, err := os.Open(ss.ExpandHome(file))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+// Parse parses the ssh config content, only Host blocks are supported, Match blocks are skipped.
+func Parse(r io.Reader) (*Config, error) {
+	// keywords before any Host line apply to all hosts.
+	cur := &block{patterns: []string{"*"}}
+	c := &Config{blocks: []*block{cur}}
+	skip := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, values := splitLine(line)
+		if key == "" {
+			continue
+		}
+
+		switch key {
+		case "host":
+			cur = &block{patterns: values}
+			c.blocks = append(c.blocks, cur)
+			skip = false
+			continue
+		case "match":
+			skip = true
+			continue
+		}
+
+		if !skip && len(values) > 0 {
+			cur.params = append(cur.params, param{key: key, values: values})
+		}
+	}
+
+	return c, scanner.Err()
+}
+
+// splitLine splits a line like `Key value`, `Key=value` or `Key "quoted value"`.
+func splitLine(line string) (key string, values []string) {
+	pos := strings.IndexAny(line, " \t=")
+	if pos < 0 {
+		return strings.ToLower(line), nil
+	}
+
+	key = strings.ToLower(line[:pos])
+	rest := strings.TrimLeft(line[pos:], " \t")
+	rest = strings.TrimPrefix(rest, "=")
+
+	var sb strings.Builder
+	quoted := false
+	for _, r := range strings.TrimSpace(rest) {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case (r == ' ' || r == '\t') && !quoted:
+			if sb.Len() > 0 {
+				values = append(values, sb.String())
+				sb.Reset()
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if sb.Len() > 0 {
+		values = append(values, sb.String())
+	}
+
+	return key, values
+}
+
+// Get returns the first obtained value of the keyword for the alias, like ssh does.
+func (c *Config) Get(alias, key string) string {
+	if vv := c.values(alias, key, false); len(vv) > 0 {
+		return vv[0]
+	}
+
+	return ""
+}
+
+// GetAll returns all the values of the keyword for the alias, like IdentityFile.
+func (c *Config) GetAll(alias, key string) []string {
+	return c.values(alias, key, true)
+}
+
+func (c *Config) values(alias, key string, all bool) (values []string) {
+	key = strings.ToLower(key)
+	for _, b := range c.blocks {
+		if !b.match(alias) {
+			continue
+		}
+		for _, p := range b.params {
+			if p.key != key {
+				continue
+			}
+			if !all {
+				return p.values
+			}
+			values = append(values, p.values...)
+		}
+	}
+
+	return values
+}
+
+// Resolve resolves the settings of the host alias.
+func (c *Config) Resolve(alias string) Entry {
+	e := Entry{
+		Alias:    alias,
+		HostName: c.Get(alias, "HostName"),
+		User:     c.Get(alias, "User"),
+		Port:     c.Get(alias, "Port"),
+	}
+
+	if e.HostName == "" {
+		e.HostName = alias
+	} else {
+		e.HostName = strings.ReplaceAll(e.HostName, "%h", alias)
+	}
+
+	for _, f := range c.GetAll(alias, "IdentityFile") {
+		if !strings.EqualFold(f, "none") {
+			e.IdentityFiles = append(e.IdentityFiles, ss.ExpandHome(f))
+		}
+	}
+
+	if jump := c.Get(alias, "ProxyJump"); jump != "" && !strings.EqualFold(jump, "none") {
+		for _, hop := range strings.Split(jump, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				e.ProxyJump = append(e.ProxyJump, hop)
+			}
+		}
+	}
+
+	e.ForwardAgent = strings.EqualFold(c.Get(alias, "ForwardAgent"), "yes")
+
+	return e
+}
+
+func (b *block) match(alias string) bool {
+	matched := false
+	for _, p := range b.patterns {
+		if neg := strings.HasPrefix(p, "!"); neg {
+			if Match(p[1:], alias) {
+				return false
+			}
+		} else if Match(p, alias) {
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+// Match matches the ssh host pattern which supports * and ? wildcards.
+func Match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(s); i >= 0; i-- {
+				if Match(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+
+	return len(s) == 0
+}
+
+// ParseJump parses a ProxyJump hop like [user@]host[:port].
+func ParseJump(hop string) (user, host, port string) {
+	if p := strings.LastIndex(hop, "@"); p >= 0 {
+		user, hop = hop[:p], hop[p+1:]
+	}
+
+	if strings.HasPrefix(hop, "[") {
+		if p := strings.Index(hop, "]"); p > 0 {
+			host, hop = hop[1:p], hop[p+1:]
+			return user, host, strings.TrimPrefix(hop, ":")
+		}
+	}
+
+	if p := strings.LastIndex(hop, ":"); p >= 0 {
+		return user, hop[:p], hop[p+1:]
+	}
+
+	return user, hop, ""
+}
@@ -0,0 +1,62 @@
+package sshconfig_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/bingoohuang/ngg/gossh/pkg/sshconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+const sample = `
+# global
+User nobody
+
+Host bastion
+    HostName 10.0.0.1
+    Port 2222
+    IdentityFile /keys/bastion
+
+Host db-* !db-skip
+    User = dba
+    ProxyJump bastion,admin@10.0.0.2:22
+    ForwardAgent yes
+
+Match host foo
+    User ignored
+
+Host *
+    IdentityFile /keys/default
+    User fallback
+`
+
+func TestResolve(t *testing.T) {
+	c, err := Parse(strings.NewReader(sample))
+	assert.Nil(t, err)
+
+	e := c.Resolve("bastion")
+	assert.Equal(t, "10.0.0.1", e.HostName)
+	assert.Equal(t, "2222", e.Port)
+	assert.Equal(t, "nobody", e.User)
+	assert.Equal(t, []string{"/keys/bastion", "/keys/default"}, e.IdentityFiles)
+	assert.Nil(t, e.ProxyJump)
+
+	e = c.Resolve("db-1")
+	assert.Equal(t, "db-1", e.HostName)
+	assert.Equal(t, []string{"bastion", "admin@10.0.0.2:22"}, e.ProxyJump)
+	assert.True(t, e.ForwardAgent)
+
+	e = c.Resolve("db-skip")
+	assert.Nil(t, e.ProxyJump)
+	assert.False(t, e.ForwardAgent)
+}
+
+func TestParseJump(t *testing.T) {
+	assert.Equal(t, []string{"admin", "10.0.0.2", "22"}, slice3(ParseJump("admin@10.0.0.2:22")))
+	assert.Equal(t, []string{"", "bastion", ""}, slice3(ParseJump("bastion")))
+	assert.Equal(t, []string{"u", "::1", "2022"}, slice3(ParseJump("u@[::1]:2022")))
+}
+
+func slice3(a, b, c string) []string {
+	return []string{a, b, c}
+}
@@ -0,0 +1,510 @@
+package gossh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/bingoohuang/ngg/ss"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Playbook is the structured tasks to be executed among the hosts, defined in a yaml or toml file.
+type Playbook struct {
+	Name string `toml:"name" yaml:"name"`
+	// Vars are the global variables.
+	Vars map[string]string `toml:"vars" yaml:"vars"`
+	// GroupVars are the variables for the host groups, keyed by group name.
+	GroupVars map[string]map[string]string `toml:"groupVars" yaml:"groupVars"`
+	// HostVars are the variables for the hosts, keyed by host ID.
+	HostVars map[string]map[string]string `toml:"hostVars" yaml:"hostVars"`
+
+	Tasks []*Task `toml:"tasks" yaml:"tasks"`
+	// Handlers are the tasks which run only when notified by changed tasks, at the end of the playbook.
+	Handlers []*Task `toml:"handlers" yaml:"handlers"`
+
+	dir string
+}
+
+// Task is a step of the playbook, one of Shell, Local, Upload and Template should be set.
+type Task struct {
+	Name string `toml:"name" yaml:"name"`
+	// Hosts are the target host groups separated by /, empty or all for all hosts.
+	Hosts string `toml:"hosts" yaml:"hosts"`
+
+	// Shell is the command executed in the remote host.
+	Shell string `toml:"shell" yaml:"shell"`
+	// Creates skips the Shell if the remote path exists.
+	Creates string `toml:"creates" yaml:"creates"`
+	// Local is the command executed in the local host.
+	Local string `toml:"local" yaml:"local"`
+	// Upload uploads a local file to the remote host.
+	Upload *Transfer `toml:"upload" yaml:"upload"`
+	// Template renders the local file by Go template with the variables and then uploads it.
+	Template *Transfer `toml:"template" yaml:"template"`
+
+	// Notify are the handler names to run when the task changed something.
+	Notify []string `toml:"notify" yaml:"notify"`
+	// IgnoreErrors continues to the next task when the task failed.
+	IgnoreErrors bool `toml:"ignoreErrors" yaml:"ignoreErrors"`
+}
+
+// Transfer defines the file transfer from Src (local) to Dest (remote).
+type Transfer struct {
+	Src  string `toml:"src" yaml:"src"`
+	Dest string `toml:"dest" yaml:"dest"`
+	// Mode is the octal file mode like 0644.
+	Mode string `toml:"mode" yaml:"mode"`
+}
+
+// LoadPlaybook loads the playbook from the yaml or toml file by its extension.
+func LoadPlaybook(file string) (*Playbook, error) {
+	file = ss.ExpandHome(file)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read playbook %s: %w", file, err)
+	}
+
+	p := &Playbook{dir: filepath.Dir(file)}
+	switch ext := strings.ToLower(filepath.Ext(file)); ext {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(data, p)
+	case ".toml":
+		err = toml.Unmarshal(data, p)
+	default:
+		return nil, fmt.Errorf("unsupported playbook format %s, should be .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse playbook %s: %w", file, err)
+	}
+
+	return p, p.validate()
+}
+
+func (p *Playbook) validate() error {
+	handlers := make(map[string]bool)
+	for i, t := range p.Handlers {
+		if t.Name == "" {
+			return fmt.Errorf("handler #%d has no name", i+1)
+		}
+		handlers[t.Name] = true
+	}
+
+	for i, t := range append(append([]*Task{}, p.Tasks...), p.Handlers...) {
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("task #%d", i+1)
+		}
+
+		actions := 0
+		for _, set := range []bool{t.Shell != "", t.Local != "", t.Upload != nil, t.Template != nil} {
+			if set {
+				actions++
+			}
+		}
+		if actions != 1 {
+			return fmt.Errorf("%s should have exactly one of shell, local, upload and template", t.Name)
+		}
+
+		for _, n := range t.Notify {
+			if !handlers[n] {
+				return fmt.Errorf("%s notifies unknown handler %s", t.Name, n)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ResolveVars resolves the variables for the host, the latter overrides the former:
+// global vars, group vars (by sorted group names), host vars and the host properties.
+func (p *Playbook) ResolveVars(h *Host) map[string]string {
+	vars := map[string]string{
+		"HostID":   h.ID,
+		"HostAddr": h.Addr,
+		"HostUser": h.User,
+	}
+
+	for k, v := range p.Vars {
+		vars[k] = v
+	}
+
+	groups := make([]string, 0, len(h.groups))
+	for g := range h.groups {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		for k, v := range p.GroupVars[g] {
+			vars[k] = v
+		}
+	}
+
+	for k, v := range p.HostVars[h.ID] {
+		vars[k] = v
+	}
+
+	for k, v := range h.Properties {
+		if len(v) > 0 {
+			vars[k] = v[0]
+		}
+	}
+
+	return vars
+}
+
+// Targets tells whether the task targets the host.
+func (t *Task) Targets(h *Host) bool {
+	if t.Hosts == "" || t.Hosts == "all" {
+		return true
+	}
+
+	for _, g := range strings.Split(t.Hosts, "/") {
+		if h.groups[g] == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Step is the resolved task for a host.
+type Step struct {
+	Task *Task
+	// Cmd is the rendered command for Shell or Local task.
+	Cmd string
+	// Creates is the rendered Creates of the Shell task.
+	Creates string
+	// Src is the local file for Upload or Template task.
+	Src string
+	// Dest is the rendered remote file for Upload or Template task.
+	Dest string
+	// Content is the rendered content of Template task.
+	Content []byte
+	Mode    os.FileMode
+}
+
+func (s Step) String() string {
+	t := s.Task
+	switch {
+	case t.Shell != "":
+		if s.Creates != "" {
+			return fmt.Sprintf("shell: %s (unless %s exists)", s.Cmd, s.Creates)
+		}
+		return "shell: " + s.Cmd
+	case t.Local != "":
+		return "local: " + s.Cmd
+	case t.Upload != nil:
+		return fmt.Sprintf("upload: %s => %s", s.Src, s.Dest)
+	default:
+		return fmt.Sprintf("template: %s => %s (%d bytes)", s.Src, s.Dest, len(s.Content))
+	}
+}
+
+// Resolve renders the task for the host with the variables.
+func (p *Playbook) Resolve(t *Task, h *Host, vars map[string]string) (s Step, err error) {
+	s.Task = t
+	render := func(text string) string {
+		if err != nil || text == "" {
+			return text
+		}
+
+		var out string
+		out, err = renderText(t.Name, text, vars)
+		return h.SubstituteResultVars(out)
+	}
+
+	switch {
+	case t.Shell != "":
+		s.Cmd, s.Creates = render(t.Shell), render(t.Creates)
+	case t.Local != "":
+		s.Cmd = render(t.Local)
+	default:
+		tr := t.Upload
+		if tr == nil {
+			tr = t.Template
+		}
+
+		if s.Src = ss.ExpandHome(render(tr.Src)); s.Src != "" && !filepath.IsAbs(s.Src) {
+			s.Src = filepath.Join(p.dir, s.Src)
+		}
+		s.Dest = render(tr.Dest)
+		if err == nil && tr.Mode != "" {
+			var mode uint64
+			if mode, err = strconv.ParseUint(tr.Mode, 8, 32); err == nil {
+				s.Mode = os.FileMode(mode)
+			}
+		}
+		if err == nil && t.Template != nil {
+			var data []byte
+			if data, err = os.ReadFile(s.Src); err == nil {
+				var content string
+				content, err = renderText(s.Src, string(data), vars)
+				s.Content = []byte(content)
+			}
+		}
+	}
+
+	if err != nil {
+		return s, fmt.Errorf("%s: %w", t.Name, err)
+	}
+
+	return s, nil
+}
+
+func renderText(name, text string, vars map[string]string) (string, error) {
+	tpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// RunPlaybook runs the playbook among the hosts task by task, and the notified handlers at last.
+// When dryRun is true, only the resolved plan for each host is printed.
+func (g *GoSSH) RunPlaybook(p *Playbook, stdout io.Writer, dryRun bool) error {
+	if p.Name != "" {
+		_, _ = fmt.Fprintf(stdout, "PLAY [%s]\n", p.Name)
+	}
+
+	if dryRun {
+		return p.printPlan(g.Hosts, stdout)
+	}
+
+	failed := make(map[*Host]error)
+	notified := make(map[*Host]map[string]bool)
+
+	runTasks := func(tasks []*Task, handler bool) {
+		for _, t := range tasks {
+			_, _ = fmt.Fprintf(stdout, "\nTASK [%s]\n", t.Name)
+			for _, h := range g.Hosts {
+				if failed[h] != nil || !t.Targets(h) || handler && !notified[h][t.Name] {
+					continue
+				}
+
+				changed, err := p.runTask(t, h, stdout)
+				switch {
+				case err != nil && t.IgnoreErrors:
+					_, _ = fmt.Fprintf(stdout, "ignored: [%s] %v\n", h.ID, err)
+				case err != nil:
+					_, _ = fmt.Fprintf(stdout, "failed: [%s] %v\n", h.ID, err)
+					failed[h] = err
+				case changed:
+					_, _ = fmt.Fprintf(stdout, "changed: [%s]\n", h.ID)
+					for _, n := range t.Notify {
+						if notified[h] == nil {
+							notified[h] = make(map[string]bool)
+						}
+						notified[h][n] = true
+					}
+				default:
+					_, _ = fmt.Fprintf(stdout, "ok: [%s]\n", h.ID)
+				}
+			}
+		}
+	}
+
+	runTasks(p.Tasks, false)
+	if len(notified) > 0 {
+		runTasks(p.Handlers, true)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("playbook failed on %d host(s)", len(failed))
+	}
+
+	return nil
+}
+
+func (p *Playbook) printPlan(hosts Hosts, stdout io.Writer) error {
+	for _, h := range hosts {
+		vars := p.ResolveVars(h)
+		_, _ = fmt.Fprintf(stdout, "\n---> %s %s <---\n", h.ID, h.Addr)
+
+		keys := make([]string, 0, len(vars))
+		for k := range vars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			_, _ = fmt.Fprintf(stdout, "var %s = %s\n", k, vars[k])
+		}
+
+		printSteps := func(kind string, tasks []*Task) error {
+			for _, t := range tasks {
+				if !t.Targets(h) {
+					continue
+				}
+
+				s, err := p.Resolve(t, h, vars)
+				if err != nil {
+					return err
+				}
+
+				_, _ = fmt.Fprintf(stdout, "%s [%s] %s\n", kind, t.Name, s)
+				if len(t.Notify) > 0 {
+					_, _ = fmt.Fprintf(stdout, "    notify when changed: %s\n", strings.Join(t.Notify, ", "))
+				}
+			}
+			return nil
+		}
+
+		if err := printSteps("task", p.Tasks); err != nil {
+			return err
+		}
+		if err := printSteps("handler", p.Handlers); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runTask runs the task in the host, returns whether something changed.
+func (p *Playbook) runTask(t *Task, h *Host, stdout io.Writer) (changed bool, err error) {
+	s, err := p.Resolve(t, h, p.ResolveVars(h))
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case t.Shell != "":
+		if s.Creates != "" {
+			if exists, err := h.remoteExists(s.Creates); err != nil || exists {
+				return false, err
+			}
+		}
+
+		out, err := h.Run(s.Cmd)
+		writeOutput(stdout, out)
+		return err == nil, err
+	case t.Local != "":
+		out, err := exec.Command("/bin/bash", "-c", s.Cmd).CombinedOutput()
+		writeOutput(stdout, out)
+		return err == nil, err
+	case t.Upload != nil:
+		if s.Content, err = os.ReadFile(s.Src); err != nil {
+			return false, err
+		}
+		fallthrough
+	default:
+		return h.writeRemoteFile(s.Dest, s.Content, s.Mode)
+	}
+}
+
+func writeOutput(stdout io.Writer, out []byte) {
+	if len(out) > 0 {
+		_, _ = stdout.Write(out)
+		if out[len(out)-1] != '\n' {
+			_, _ = fmt.Fprintln(stdout)
+		}
+	}
+}
+
+// Run runs the command in a new ssh session of the host, returns the combined output.
+func (h *Host) Run(cmd string) ([]byte, error) {
+	if h.client == nil {
+		var err error
+		if h.client, err = h.GetGosshConnect(); err != nil {
+			return nil, err
+		}
+	}
+
+	session, err := h.client.Client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	timer := time.AfterFunc(cmdTimeout(), func() { _ = session.Close() })
+	defer timer.Stop()
+
+	return session.CombinedOutput(cmd)
+}
+
+// cmdTimeout returns the CmdTimeout parsed from the Config, or the default one when it is not set.
+func cmdTimeout() time.Duration {
+	if timeout, ok := viper.Get("CmdTimeout").(time.Duration); ok && timeout > 0 {
+		return timeout
+	}
+
+	return defaultCmdTimeout
+}
+
+func (h *Host) remoteExists(remote string) (bool, error) {
+	sf, err := h.GetSftpClient()
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := sf.Stat(remote); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// writeRemoteFile writes the content to the remote file if it is different, returns whether it changed.
+func (h *Host) writeRemoteFile(remote string, content []byte, mode os.FileMode) (bool, error) {
+	sf, err := h.GetSftpClient()
+	if err != nil {
+		return false, err
+	}
+
+	if f, err := sf.Open(remote); err == nil {
+		old, err := io.ReadAll(f)
+		_ = f.Close()
+		if err == nil && bytes.Equal(old, content) {
+			if mode == 0 {
+				return false, nil
+			}
+			if stat, err := sf.Stat(remote); err == nil && stat.Mode().Perm() == mode.Perm() {
+				return false, nil
+			}
+		}
+	}
+
+	if err := sf.MkdirAll(path.Dir(remote)); err != nil {
+		return false, fmt.Errorf("mkdir %s: %w", path.Dir(remote), err)
+	}
+
+	f, err := sf.Create(remote)
+	if err != nil {
+		return false, fmt.Errorf("create %s: %w", remote, err)
+	}
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return false, fmt.Errorf("write %s: %w", remote, err)
+	}
+	if err := f.Close(); err != nil {
+		return false, err
+	}
+
+	if mode != 0 {
+		if err := sf.Chmod(remote, mode); err != nil {
+			return false, fmt.Errorf("chmod %s: %w", remote, err)
+		}
+	}
+
+	return true, nil
+}
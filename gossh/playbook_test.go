@@ -0,0 +1,47 @@
+package gossh
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaybook(t *testing.T) {
+	p, err := LoadPlaybook("testdata/playbook/playbook.yaml")
+	assert.Nil(t, err)
+	assert.Len(t, p.Tasks, 3)
+
+	c := Config{SSHConfig: "-", Hosts: []string{
+		"10.0.0.1:22 root/na id=h1 group=web",
+		"10.0.0.2:22 root/na id=h2 group=db env=dev",
+	}}
+	hosts := c.parseHosts()
+
+	vars := p.ResolveVars(hosts[0])
+	assert.Equal(t, "80", vars["port"])
+	assert.Equal(t, "prod", vars["env"])
+
+	// host properties override the host vars.
+	vars = p.ResolveVars(hosts[1])
+	assert.Equal(t, "8080", vars["port"])
+	assert.Equal(t, "dev", vars["env"])
+
+	s, err := p.Resolve(p.Tasks[0], hosts[0], p.ResolveVars(hosts[0]))
+	assert.Nil(t, err)
+	assert.Equal(t, "/etc/app/prod.conf", s.Dest)
+	assert.Equal(t, "listen=10.0.0.1:22:80\nenv=prod\n", string(s.Content))
+	assert.Equal(t, "-rw-r--r--", s.Mode.String())
+
+	var out bytes.Buffer
+	c.Playbook, c.DryRun = "testdata/playbook/playbook.yaml", true
+	gs := &GoSSH{Config: &c, Hosts: hosts}
+	assert.Nil(t, gs.Run(context.Background(), &out))
+	assert.Contains(t, out.String(), "handler [restart app] shell: systemctl restart app-dev")
+	assert.Contains(t, out.String(), "task [local build] local: echo h1")
+}
+
+func TestCmdTimeout(t *testing.T) {
+	assert.Equal(t, defaultCmdTimeout, cmdTimeout())
+}
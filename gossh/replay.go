@@ -0,0 +1,60 @@
+package gossh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/bingoohuang/ngg/gossh/pkg/asciicast"
+	"github.com/bingoohuang/ngg/ss"
+)
+
+// ReplayConfig is the config of the `gossh replay` subcommand,
+// which plays back the recorded sessions or searches across the recordings for a command.
+type ReplayConfig struct {
+	Dir     string  `help:"recordings dir, default ~/.gossh/casts"`
+	Search  string  `help:"search the recordings for the command by regexp" short:"s"`
+	Speed   float64 `help:"playback speed, default 1"`
+	MaxIdle string  `help:"max idle time between events when playing back(eg. 2s), empty for no limit"`
+}
+
+// Replay plays back the recording files one by one, or searches the recordings when Search is set.
+func (c ReplayConfig) Replay(ctx context.Context, stdout io.Writer, files ...string) error {
+	if c.Search != "" {
+		re, err := regexp.Compile(c.Search)
+		if err != nil {
+			return fmt.Errorf("invalid search regexp %s: %w", c.Search, err)
+		}
+
+		matches, err := asciicast.Search(ss.ExpandHome(ss.Or(c.Dir, "~/.gossh/casts")), re)
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			_, _ = fmt.Fprintln(stdout, m)
+		}
+		return nil
+	}
+
+	if len(files) == 0 {
+		return errors.New("no recording files to replay")
+	}
+
+	maxIdle, _ := time.ParseDuration(c.MaxIdle)
+	for _, f := range files {
+		cast, err := asciicast.ReadFile(ss.ExpandHome(f))
+		if err != nil {
+			return fmt.Errorf("read %s: %w", f, err)
+		}
+
+		_, _ = fmt.Fprintf(stdout, "\n---> %s %s <---\n", cast.Header.Title, time.Unix(cast.Header.Timestamp, 0).Format(time.RFC3339))
+		if err := cast.Play(ctx, stdout, c.Speed, maxIdle); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package gossh
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/bingoohuang/ngg/ss"
+)
+
+// Run runs the playbook if given, or the commands on the hosts by the exec mode,
+// or starts a repl when there are no commands.
+// The port forwardings are kept open while the commands are running,
+// and when there are only port forwardings, it blocks until ctx is done, like `ssh -N`.
+func (g *GoSSH) Run(ctx context.Context, stdout io.Writer) (err error) {
+	hostGroup := ss.Or(g.Config.Group, "default")
+
+	var playbook *Playbook
+	if g.Config.Playbook != "" {
+		if playbook, err = LoadPlaybook(ss.ExpandHome(g.Config.Playbook)); err != nil {
+			return err
+		}
+		if g.Config.DryRun {
+			return g.RunPlaybook(playbook, stdout, true)
+		}
+	}
+
+	if g.HasForwards() {
+		if len(g.Cmds) == 0 && playbook == nil {
+			return g.Forward(ctx, hostGroup)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		forwardErr := make(chan error, 1)
+		go func() { forwardErr <- g.Forward(ctx, hostGroup) }()
+		defer func() {
+			cancel()
+			if e := <-forwardErr; e != nil && err == nil {
+				err = fmt.Errorf("port forwarding: %w", e)
+			}
+		}()
+	}
+
+	if playbook != nil {
+		return g.RunPlaybook(playbook, stdout, false)
+	}
+
+	g.exec(stdout, hostGroup)
+	return nil
+}
+
+func (g *GoSSH) exec(stdout io.Writer, hostGroup string) {
+	if len(g.Cmds) == 0 {
+		Repl(g, g.Hosts, stdout, hostGroup)
+		return
+	}
+
+	switch g.Config.ExecMode {
+	case ExecModeHostByHost:
+		for _, host := range g.Hosts {
+			ExecCmds(g, host, stdout, ExecOption{}, hostGroup)
+		}
+	default:
+		ExecCmds(g, NewExecModeCmdByCmd(), stdout, ExecOption{}, hostGroup)
+	}
+}
@@ -3,16 +3,20 @@ package gossh
 import (
 	"fmt"
 	"io"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/bingoohuang/ngg/gossh/pkg/asciicast"
 	"github.com/bingoohuang/ngg/gossh/pkg/cmdtype"
 	"github.com/bingoohuang/ngg/gossh/pkg/gossh"
 	"github.com/bingoohuang/ngg/ss"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	"golang.org/x/crypto/ssh"
+	xterm "golang.org/x/term"
 )
 
 // SSHCmd means SSH command.
@@ -146,6 +150,16 @@ func (h *Host) setupSession(stdout io.Writer) error {
 		return err
 	}
 
+	if h.ForwardAgent {
+		if a := sshAgent(); a != nil {
+			if err := gossh.ForwardAgent(h.client.Client, session, a); err != nil {
+				return err
+			}
+		} else {
+			log.Printf("W! ssh-agent is not available for forwarding to %s", h.Addr)
+		}
+	}
+
 	// disable echoing input/output speed = 14.4kbaud
 	modes := ssh.TerminalModes{ssh.ECHO: 0, ssh.TTY_OP_ISPEED: 14400, ssh.TTY_OP_OSPEED: 14400}
 
@@ -154,7 +168,8 @@ func (h *Host) setupSession(stdout io.Writer) error {
 		term = "xterm-256color" // alternative to vt100
 	}
 
-	if err := session.RequestPty(term, 800, 400, modes); err != nil {
+	width, height := ptySize()
+	if err := session.RequestPty(term, height, width, modes); err != nil {
 		return err
 	}
 
@@ -172,6 +187,13 @@ func (h *Host) setupSession(stdout io.Writer) error {
 		return err
 	}
 
+	if h.recorder, err = h.createRecorder(term, width, height); err != nil {
+		log.Printf("W! failed to record session of %s: %v", h.Addr, err)
+	} else if h.recorder != nil {
+		r = io.TeeReader(r, h.recorder.Output())
+		w = recordWriteCloser{WriteCloser: w, input: h.recorder.Input()}
+	}
+
 	tryReader := gossh.NewTryReader(r)
 	if v := h.Prop("initial_cmd"); v != "" {
 		ExecuteInitialCmd(v, w)
@@ -188,6 +210,50 @@ func (h *Host) setupSession(stdout io.Writer) error {
 	return nil
 }
 
+// ptySize returns the size of the local terminal for the remote pty,
+// or a large one to avoid wrapping the output when the stdout is not a terminal.
+func ptySize() (width, height int) {
+	if w, h, err := xterm.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 && h > 0 {
+		return w, h
+	}
+
+	return 400, 800
+}
+
+// createRecorder creates the asciicast recorder for the session when RecordDir is set.
+func (h *Host) createRecorder(term string, width, height int) (*asciicast.Recorder, error) {
+	dir := viper.GetString("RecordDir")
+	if dir == "" {
+		return nil, nil
+	}
+
+	addr := strings.NewReplacer(":", "_", "/", "_").Replace(h.Addr)
+	name := fmt.Sprintf("%s-%s-%s.cast", h.ID, addr, time.Now().Format("20060102150405"))
+	file := filepath.Join(ss.ExpandHome(dir), name)
+
+	header := asciicast.Header{
+		Width:  width,
+		Height: height,
+		Title:  fmt.Sprintf("%s %s@%s", h.ID, h.User, h.Addr),
+		Env:    map[string]string{"TERM": term},
+	}
+	return asciicast.Create(file, header)
+}
+
+// recordWriteCloser records the data written to the session stdin as input events.
+type recordWriteCloser struct {
+	io.WriteCloser
+	input io.Writer
+}
+
+func (w recordWriteCloser) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	if n > 0 {
+		_, _ = w.input.Write(p[:n])
+	}
+	return n, err
+}
+
 // ExecuteInitialCmd executes initial command.
 func ExecuteInitialCmd(initialCmd string, w io.Writer) {
 	for _, v := range gossh.ConvertKeys(initialCmd) {
@@ -0,0 +1,2 @@
+listen={{.HostAddr}}:{{.port}}
+env={{.env}}
@@ -14,17 +14,21 @@ import (
 
 type consumeCmd struct {
 	kt.ConsumerConfig `squash:"1"`
+	kt.SerdeArgs      `squash:"1"`
 
 	Grep         string `help:"grep message"`
+	Filter       string `help:"Filter expression on the message, like value.user.age > 30 && key =~ 'id-'"`
+	Select       string `help:"Select fields of the message by jj paths, like key,value.user.name,headers.trace-id"`
 	N            int64  `help:"Max number of messages to consume"`
 	Web          bool   `help:"Start web server for HTTP requests and responses event"`
 	Context      string `help:"Web server context path if web is enable"`
 	Port         int    `help:"Web server port if web is enable"`
-	KeyEncoder   string `default:"string" enum:"hex,base64,string"`
-	ValueEncoder string `default:"string" enum:"hex,base64,string"`
+	KeyEncoder   string `default:"string" enum:"hex,base64,string,avro,protobuf,jsonschema"`
+	ValueEncoder string `default:"string" enum:"hex,base64,string,avro,protobuf,jsonschema"`
 
-	sseSender *kt.SSESender
-	grepExpr  *regexp.Regexp
+	sseSender  *kt.SSESender
+	grepExpr   *regexp.Regexp
+	filterExpr *kt.Filter
 }
 
 func (c *consumeCmd) Run(*cobra.Command, []string) (err error) {
@@ -38,11 +42,25 @@ func (c *consumeCmd) Run(*cobra.Command, []string) (err error) {
 		}
 	}
 
+	if c.Filter != "" {
+		if c.filterExpr, err = kt.ParseFilter(c.Filter); err != nil {
+			return err
+		}
+	}
+
 	c.parseWeb()
 
-	ve := kt.ParseBytesEncoder(c.ValueEncoder)
-	ke := kt.ParseBytesEncoder(c.KeyEncoder)
-	c.MessageConsumer = kt.NewPrintMessageConsumer(ke, ve, c.sseSender, c.grepExpr, c.N)
+	ve, err := c.SerdeArgs.BytesEncoder(c.ValueEncoder, c.Topic, false)
+	if err != nil {
+		return err
+	}
+	ke, err := c.SerdeArgs.BytesEncoder(c.KeyEncoder, c.Topic, true)
+	if err != nil {
+		return err
+	}
+	pc := kt.NewPrintMessageConsumer(ke, ve, c.sseSender, c.grepExpr, c.N)
+	pc.Filter, pc.Select = c.filterExpr, kt.SelectPath(c.Select)
+	c.MessageConsumer = pc
 
 	_, err = kt.StartConsume(c.ConsumerConfig)
 	return err
@@ -104,5 +122,13 @@ More examples:
  - -10           Omit "newest", same with above
  - oldest+10     To skip the first 15 messages starting with the oldest Offset
  - +10           Omit "oldest",, same with above
+Filter and select work on the message document (JSON) of
+  {"topic", "partition", "offset", "timestamp", "key", "value", "headers", "size"}
+by jj paths, the key and value are embedded as JSON if they are valid JSON after decoding:
+ - --filter 'value.user.age > 30 && key =~ "id-"'
+ - --filter 'headers.trace-id == "abc" || timestamp >= "2024-05-01 10:00"'
+ - --filter '!(partition == 0) && value.items.#(price>100)'
+ - --select key,value.user.name          prints {"key":...,"name":...}
+ - --select '{"id":key,"age":value.user.age}'
 `
 }
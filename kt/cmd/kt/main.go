@@ -23,6 +23,7 @@ func main() {
 	root.CreateCmd(rootCmd, "perf", "produce messages for performance test", &perfProduceCmd{})
 	root.CreateCmd(rootCmd, "produce", "produce messages", &produceCmd{})
 	root.CreateCmd(rootCmd, "consume", "consume messages", &consumeCmd{})
+	root.CreateCmd(rootCmd, "mirror", "mirror messages to another topic or cluster", &mirrorCmd{})
 	root.CreateCmd(rootCmd, "topic", "topic info", &topicCmd{})
 	root.CreateCmd(rootCmd, "group", "consumer group information and modification", &groupCmd{})
 
@@ -46,6 +47,7 @@ const ktHelpLong = `
 4. 生产消息性能压测
     1. 随机字符串写入压测: kt perf
     2. 使用 JSON 模板生成写入压测： kt perf --json_template '{"id":"@objectId","sex":"@random(male,female)"}'
+5. 复制/回放消息到另一个集群或主题: kt mirror -t orders --target-brokers 192.168.2.1:9092 --target-topic orders.copy
 `
 
 type versionCmd struct{}
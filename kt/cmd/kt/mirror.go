@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/ngg/kt/pkg/kt"
+	"github.com/spf13/cobra"
+)
+
+type mirrorCmd struct {
+	kt.CommonArgs `squash:"1"`
+	kt.SerdeArgs  `squash:"1"`
+
+	Offsets       string        `short:"o" default:"oldest:newest" help:"Specifies what messages to mirror by partition and offset range, same as consume"`
+	TargetBrokers string        `help:"Target kafka brokers (defaults to the source brokers)"`
+	TargetTopic   string        `help:"Target topic (defaults to the source topic)"`
+	TargetVersion string        `help:"Target kafka protocol version (defaults to the source version)"`
+	TargetSasl    string        `help:"Target sasl user:password (defaults to the source sasl)"`
+	Repartition   bool          `help:"Re-partition by key in the target topic, instead of keeping the source partitions"`
+	Compress      string        `help:"Kafka message compress codec" enum:"gzip,snappy,lz4"`
+	Qps           float32       `help:"The maximum number of messages to mirror per second (0 for no limit)"`
+	Filter        string        `help:"Filter expression on the message, same as consume"`
+	KeyEncoder    string        `default:"string" enum:"hex,base64,string,avro,protobuf,jsonschema" help:"Key encoder for filter"`
+	ValueEncoder  string        `default:"string" enum:"hex,base64,string,avro,protobuf,jsonschema" help:"Value encoder for filter"`
+	Checkpoint    string        `help:"Checkpoint file to save the progress and resume from (defaults to kt-mirror.{topic}.{target-topic}.json)"`
+	Timeout       time.Duration `help:"Stop mirroring a partition after no messages for the duration"`
+}
+
+func (c *mirrorCmd) Run(*cobra.Command, []string) (err error) {
+	if err := c.CommonArgs.Validate(); err != nil {
+		return err
+	}
+
+	target := c.CommonArgs
+	if c.TargetBrokers != "" {
+		target.Brokers = c.TargetBrokers
+	}
+	if c.TargetTopic != "" {
+		target.Topic = c.TargetTopic
+	}
+	if c.TargetVersion != "" {
+		target.Version = c.TargetVersion
+	}
+	if c.TargetSasl != "" {
+		target.SASL = c.TargetSasl
+	}
+	if err := target.Validate(); err != nil {
+		return err
+	}
+
+	if target.Topic == c.Topic && strings.Join(target.KafkaBrokers, ",") == strings.Join(c.KafkaBrokers, ",") {
+		return fmt.Errorf("the target topic %s is the same as the source one", target.Topic)
+	}
+
+	conf := kt.MirrorConfig{
+		Source:      c.CommonArgs,
+		Target:      target,
+		Offsets:     c.Offsets,
+		Repartition: c.Repartition,
+		Compress:    kafkaCompression(c.Compress),
+		Qps:         c.Qps,
+		Checkpoint:  c.Checkpoint,
+		Timeout:     c.Timeout,
+	}
+	if conf.Checkpoint == "" {
+		conf.Checkpoint = fmt.Sprintf("kt-mirror.%s.%s.json", c.Topic, target.Topic)
+	}
+
+	if c.Filter != "" {
+		if conf.Filter, err = kt.ParseFilter(c.Filter); err != nil {
+			return err
+		}
+		if conf.KeyEncoder, err = c.SerdeArgs.BytesEncoder(c.KeyEncoder, c.Topic, true); err != nil {
+			return err
+		}
+		if conf.ValEncoder, err = c.SerdeArgs.BytesEncoder(c.ValueEncoder, c.Topic, false); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := kt.CreateCancelContext()
+	defer cancel()
+
+	out := make(chan kt.PrintContext)
+	go func() {
+		defer close(out)
+		err = kt.StartMirror(ctx, conf, out)
+	}()
+	kt.PrintOutStats(out)
+
+	return err
+}
+
+func (c *mirrorCmd) LongHelp() string {
+	return `Mirror copies the messages from the source topic to the target topic, in the same or another cluster.
+The keys, values, headers and timestamps are kept, and the partitions are kept unless --repartition.
+
+The offsets are the same as consume, defaults to oldest:newest, which copies the messages existing now.
+The progress is saved in the checkpoint file periodically and when interrupted (via ^C),
+run the same command again to resume from the checkpoint, remove the file to start over.
+
+Examples:
+Copy the topic to another cluster:
+  $ kt mirror -b 192.168.1.1:9092 -t orders --target-brokers 192.168.2.1:9092
+Replay the partition 0 from offset 1000 to 2000 into another topic, with at most 100 messages per second:
+  $ kt mirror -t orders -o 0=1000:2000 --target-topic orders.replay --qps 100
+Copy only the matched messages, re-partitioned by key:
+  $ kt mirror -t orders --target-topic orders.vip --repartition --filter 'value.amount > 1000'
+Keep mirroring the new messages until no messages in 1 minute:
+  $ kt mirror -t orders -o newest --target-topic orders.copy --timeout 1m
+`
+}
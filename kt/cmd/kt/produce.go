@@ -16,10 +16,11 @@ import (
 
 type produceCmd struct {
 	kt.CommonArgs `squash:"1"`
+	kt.SerdeArgs  `squash:"1"`
 	Timeout       time.Duration `default:"3s"`
 
-	KeyDecoder   string `default:"string" enum:"hex,base64,string"`
-	ValueDecoder string `default:"string" enum:"hex,base64,string"`
+	KeyDecoder   string `default:"string" enum:"hex,base64,string,avro,protobuf,jsonschema"`
+	ValueDecoder string `default:"string" enum:"hex,base64,string,avro,protobuf,jsonschema"`
 	Partition    int32  `help:"Partition to produce to (defaults to 0)"`
 	Batch        int    `default:"1" help:"Max size of a batch before sending it off"`
 	Json         bool   `help:"Interpret stdin line as JSON"`
@@ -39,11 +40,11 @@ func (c *produceCmd) Run(*cobra.Command, []string) (err error) {
 		return err
 	}
 
-	c.keyDecoder, err = kt.ParseStringDecoder(c.KeyDecoder)
+	c.keyDecoder, err = c.SerdeArgs.StringDecoder(c.KeyDecoder, c.Topic, true)
 	if err != nil {
 		return err
 	}
-	c.valDecoder, err = kt.ParseStringDecoder(c.ValueDecoder)
+	c.valDecoder, err = c.SerdeArgs.StringDecoder(c.ValueDecoder, c.Topic, false)
 	if err != nil {
 		return err
 	}
@@ -71,12 +72,27 @@ func (c *produceCmd) Run(*cobra.Command, []string) (err error) {
 }
 
 type Message struct {
-	Key       *string `json:"key,omitempty"`
-	Value     *string `json:"value,omitempty"`
-	K         *string `json:"k,omitempty"`
-	V         *string `json:"v,omitempty"`
-	Partition *int32  `json:"partition"`
-	P         *int32  `json:"p"`
+	Key       *textOrJSON `json:"key,omitempty"`
+	Value     *textOrJSON `json:"value,omitempty"`
+	K         *textOrJSON `json:"k,omitempty"`
+	V         *textOrJSON `json:"v,omitempty"`
+	Partition *int32      `json:"partition"`
+	P         *int32      `json:"p"`
+}
+
+// textOrJSON is a JSON string, or the raw text of other JSON values like objects,
+// so that the value to be encoded by avro/protobuf/jsonschema can be given as a JSON object directly.
+type textOrJSON string
+
+func (t *textOrJSON) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = textOrJSON(s)
+		return nil
+	}
+
+	*t = textOrJSON(data)
+	return nil
 }
 
 func kafkaCompression(codecName string) sarama.CompressionCodec {
@@ -200,7 +216,7 @@ func (c *produceCmd) deserializeLines(in chan string, out chan Message, partitio
 }
 
 func (c *produceCmd) parseLine(l string, partitionCount int32) Message {
-	v := &l
+	v := (*textOrJSON)(&l)
 	if len(l) == 0 {
 		v = nil
 	}
@@ -234,7 +250,7 @@ func (c *produceCmd) setPartition(msg *Message, partitionCount int32) {
 	case c.Partitioner == "rand":
 		part = randPartition(partitionCount)
 	case msg.Key != nil && c.Partitioner == "hash":
-		part = hashCodePartition(*msg.Key, partitionCount)
+		part = hashCodePartition(string(*msg.Key), partitionCount)
 	}
 
 	msg.Partition = &part
@@ -280,13 +296,13 @@ func (c *produceCmd) makeSaramaMessage(msg Message) (*sarama.Message, error) {
 		sm  = &sarama.Message{Codec: c.compress}
 	)
 
-	if v := kt.FirstNotNil(msg.Key, msg.K); v != "" {
+	if v := string(kt.FirstNotNil(msg.Key, msg.K)); v != "" {
 		if sm.Key, err = c.keyDecoder.Decode(v); err != nil {
 			return sm, fmt.Errorf("failed to decode key as string, err=%v", err)
 		}
 	}
 
-	if v := kt.FirstNotNil(msg.Value, msg.V); v != "" {
+	if v := string(kt.FirstNotNil(msg.Value, msg.V)); v != "" {
 		if data, ok := readFile(v); ok {
 			sm.Value = data
 		} else if sm.Value, err = c.valDecoder.Decode(v); err != nil {
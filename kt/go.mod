@@ -18,16 +18,20 @@ require (
 	github.com/bingoohuang/ngg/jj v0.0.0-20241218101748-d3d30a7be766
 	github.com/bingoohuang/ngg/ss v0.0.0-20241218101748-d3d30a7be766
 	github.com/bingoohuang/ngg/ver v0.0.0-20241218101748-d3d30a7be766
+	github.com/bufbuild/protocompile v0.14.1
 	github.com/elliotchance/pie/v2 v2.9.1
 	github.com/joho/godotenv v1.5.1
+	github.com/linkedin/goavro/v2 v2.12.0
 	github.com/onsi/ginkgo v1.16.5
 	github.com/onsi/gomega v1.36.1
 	github.com/rcrowley/go-metrics v0.0.0-20201227073835-cf1acfcdf475
 	github.com/samber/lo v1.47.0
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
 	github.com/spf13/cobra v1.8.1
 	github.com/twmb/franz-go v1.18.0
 	github.com/twmb/franz-go/pkg/kmsg v1.9.0
 	github.com/vmihailenco/tagparser/v2 v2.0.0
+	google.golang.org/protobuf v1.35.1
 )
 
 require (
@@ -0,0 +1,138 @@
+package kt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MirrorCheckpoint is the mirroring progress saved in a file, to resume the interrupted mirroring.
+type MirrorCheckpoint struct {
+	Topic       string `json:"topic"`
+	TargetTopic string `json:"targetTopic"`
+	// Offsets are the next offsets to mirror of the source partitions.
+	Offsets map[int32]int64 `json:"offsets"`
+
+	mu sync.Mutex
+}
+
+// LoadMirrorCheckpoint loads the checkpoint from the file, an empty checkpoint is returned if the file does not exist.
+func LoadMirrorCheckpoint(file, topic, targetTopic string) (*MirrorCheckpoint, error) {
+	cp := &MirrorCheckpoint{Topic: topic, TargetTopic: targetTopic, Offsets: map[int32]int64{}}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cp, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("bad checkpoint file %s: %w", file, err)
+	}
+	if cp.Topic != topic || cp.TargetTopic != targetTopic {
+		return nil, fmt.Errorf("checkpoint file %s is for %s -> %s, not %s -> %s",
+			file, cp.Topic, cp.TargetTopic, topic, targetTopic)
+	}
+	if cp.Offsets == nil {
+		cp.Offsets = map[int32]int64{}
+	}
+
+	return cp, nil
+}
+
+// Next returns the next offset to mirror of the partition.
+func (c *MirrorCheckpoint) Next(partition int32) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	next, ok := c.Offsets[partition]
+	return next, ok
+}
+
+// Update updates the next offset to mirror of the partition.
+func (c *MirrorCheckpoint) Update(partition int32, next int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Offsets[partition] = next
+}
+
+// Save saves the checkpoint to the file, by writing a temporary file and renaming it.
+func (c *MirrorCheckpoint) Save(file string) error {
+	c.mu.Lock()
+	data, err := json.Marshal(c)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
+
+// mirrorProgress tracks the in-flight messages of a source partition,
+// the next offset only advances when all the messages before are acknowledged by the target,
+// so that no message is lost when resuming from the checkpoint.
+type mirrorProgress struct {
+	mu      sync.Mutex
+	next    int64
+	pending []int64
+	acked   map[int64]bool
+}
+
+func newMirrorProgress(start int64) *mirrorProgress {
+	return &mirrorProgress{next: start, acked: map[int64]bool{}}
+}
+
+// send registers the offset to be sent, which must be called before sending in the offset order.
+func (p *mirrorProgress) send(offset int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending = append(p.pending, offset)
+}
+
+// skip marks the offset done without sending, like the filtered ones.
+func (p *mirrorProgress) skip(offset int64) {
+	p.send(offset)
+	p.ack(offset)
+}
+
+// ack marks the offset acknowledged by the target.
+func (p *mirrorProgress) ack(offset int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.acked[offset] = true
+	for len(p.pending) > 0 && p.acked[p.pending[0]] {
+		delete(p.acked, p.pending[0])
+		p.next = p.pending[0] + 1
+		p.pending = p.pending[1:]
+	}
+}
+
+// nextOffset returns the next offset to mirror.
+func (p *mirrorProgress) nextOffset() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.next
+}
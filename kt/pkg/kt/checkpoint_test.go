@@ -0,0 +1,66 @@
+package kt
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMirrorCheckpoint(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	cp, err := LoadMirrorCheckpoint(file, "orders", "orders.copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cp.Next(0); ok {
+		t.Fatal("empty checkpoint should have no offsets")
+	}
+
+	cp.Update(0, 100)
+	cp.Update(2, 7)
+	if err := cp.Save(file); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadMirrorCheckpoint(file, "orders", "orders.copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int32]int64{0: 100, 2: 7}; !reflect.DeepEqual(loaded.Offsets, want) {
+		t.Fatalf("offsets = %v, want %v", loaded.Offsets, want)
+	}
+
+	if _, err := LoadMirrorCheckpoint(file, "orders", "other"); err == nil {
+		t.Fatal("checkpoint of another target topic should fail")
+	}
+}
+
+func TestMirrorProgress(t *testing.T) {
+	p := newMirrorProgress(10)
+
+	p.send(10)
+	p.send(11)
+	p.skip(12)
+	p.send(15)
+
+	p.ack(11)
+	if next := p.nextOffset(); next != 10 {
+		t.Fatalf("next = %d, want 10 before 10 acked", next)
+	}
+
+	p.ack(10)
+	if next := p.nextOffset(); next != 13 {
+		t.Fatalf("next = %d, want 13", next)
+	}
+
+	p.ack(15)
+	if next := p.nextOffset(); next != 16 {
+		t.Fatalf("next = %d, want 16", next)
+	}
+
+	p.skip(16)
+	if next := p.nextOffset(); next != 17 {
+		t.Fatalf("next = %d, want 17", next)
+	}
+}
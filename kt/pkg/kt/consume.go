@@ -328,6 +328,10 @@ type PrintMessageConsumer struct {
 	ValEncoder, KeyEncoder BytesEncoder
 	sseSender              *SSESender
 	Grep                   *regexp.Regexp
+	// Filter filters the messages by the expression on the message document, see ParseFilter.
+	Filter *Filter
+	// Select is the jj path to project the message document, see SelectPath.
+	Select string
 
 	N, n int64
 }
@@ -347,11 +351,6 @@ func (p *PrintMessageConsumer) Consume(m *sarama.ConsumerMessage) {
 		return
 	}
 
-	n := atomic.AddInt64(&p.n, 1)
-	if p.N > 0 && n >= p.N {
-		defer os.Exit(1)
-	}
-
 	var key string
 	var val []byte
 	switch m.Topic {
@@ -366,17 +365,33 @@ func (p *PrintMessageConsumer) Consume(m *sarama.ConsumerMessage) {
 		}
 
 		val = ss.Json(valObj)
-		fmt.Printf("#%03d %s\n", n, val)
-
 	default:
-
 		val = []byte(p.ValEncoder.Encode(m.Value))
 		if !RawMessageFlag && jj.ValidBytes(val) {
 			val = jj.FreeInnerJSON(val)
 		}
 
 		key = p.KeyEncoder.Encode(m.Key)
+	}
+
+	if p.Filter != nil || p.Select != "" {
+		doc := MessageDoc(m, []byte(key), val)
+		if p.Filter != nil && !p.Filter.Match(doc) {
+			return
+		}
+		if p.Select != "" {
+			val = []byte(jj.GetBytes(doc, p.Select).Raw)
+		}
+	}
+
+	n := atomic.AddInt64(&p.n, 1)
+	if p.N > 0 && n >= p.N {
+		defer os.Exit(1)
+	}
 
+	if p.Select != "" || m.Topic == "__consumer_offsets" {
+		fmt.Printf("#%03d %s\n", n, val)
+	} else {
 		fmt.Printf("#%03d topic: %s offset: %d partition: %d timestamp: %s valueSize: %s key: [=[%s]=] value: [=[%s]=]\n",
 			n, m.Topic, m.Offset, m.Partition,
 			m.Timestamp.Format("2006-01-02 15:04:05.000"),
@@ -0,0 +1,530 @@
+package kt
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingoohuang/ngg/jj"
+)
+
+// messageDoc is the JSON document of a consumed message, which the filter and select paths evaluate on.
+type messageDoc struct {
+	Topic     string            `json:"topic"`
+	Partition int32             `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Timestamp string            `json:"timestamp"`
+	Key       json.RawMessage   `json:"key"`
+	Value     json.RawMessage   `json:"value"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Size      int               `json:"size"`
+}
+
+// MessageDoc creates the JSON document of the message with the encoded key and value,
+// the key and value are embedded as JSON if they are valid JSON, or else as strings.
+func MessageDoc(m *sarama.ConsumerMessage, key, val []byte) []byte {
+	d := messageDoc{
+		Topic:     m.Topic,
+		Partition: m.Partition,
+		Offset:    m.Offset,
+		Timestamp: m.Timestamp.Format(timestampLayout),
+		Key:       docValue(key),
+		Value:     docValue(val),
+		Size:      len(m.Value),
+	}
+
+	for _, h := range m.Headers {
+		if h == nil {
+			continue
+		}
+		if d.Headers == nil {
+			d.Headers = map[string]string{}
+		}
+		d.Headers[string(h.Key)] = string(h.Value)
+	}
+
+	data, _ := json.Marshal(d)
+	return data
+}
+
+const timestampLayout = "2006-01-02 15:04:05.000"
+
+func docValue(v []byte) json.RawMessage {
+	if jj.ValidBytes(v) {
+		return v
+	}
+
+	data, _ := json.Marshal(string(v))
+	return data
+}
+
+// SelectPath converts the comma separated select fields to the jj multipath, like
+// key,value.user.name to {key,value.user.name}, which results {"key":...,"name":...}.
+// A jj multipath or a single path with modifiers can be used directly, like {"id":key,"age":value.user.age}.
+func SelectPath(fields string) string {
+	fields = strings.TrimSpace(fields)
+	if fields == "" || strings.HasPrefix(fields, "{") || strings.HasPrefix(fields, "[") || strings.HasPrefix(fields, "@") {
+		return fields
+	}
+
+	return "{" + fields + "}"
+}
+
+// Filter is a boolean expression evaluated on the message document, like
+//
+//	value.user.age > 30 && key =~ "id-"
+//	headers.trace-id == "abc" || timestamp >= "2024-05-01 10:00"
+//	!(partition == 0) && value.items.#(price>100)
+//
+// The operands are the jj paths on the document of topic, partition, offset, timestamp,
+// key, value, headers and size, or the string, number, true, false and null literals.
+// The operators are ==, !=, >, >=, <, <=, =~ (regex match), !~ (regex not match), &&, || and !.
+// A path alone is true when it exists and is not false, null, 0 or "".
+// Strings in time formats like 2006-01-02 15:04:05.000 are compared as times.
+type Filter struct {
+	Expr string
+	root filterNode
+}
+
+// ParseFilter parses the filter expression.
+func ParseFilter(expr string) (*Filter, error) {
+	p := &filterParser{lexer: filterLexer{src: expr}}
+	root, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("bad filter %q: %w", expr, err)
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("bad filter %q: unexpected %s at %d", expr, p.tok.text, p.tok.pos)
+	}
+
+	return &Filter{Expr: expr, root: root}, nil
+}
+
+// Match tells whether the message document matches the filter.
+func (f *Filter) Match(doc []byte) bool { return f.root.eval(doc) }
+
+type filterNode interface {
+	eval(doc []byte) bool
+}
+
+type (
+	andNode struct{ left, right filterNode }
+	orNode  struct{ left, right filterNode }
+	notNode struct{ node filterNode }
+	truthy  struct{ operand operand }
+	cmpNode struct {
+		op          string
+		left, right operand
+	}
+	regexNode struct {
+		left operand
+		re   *regexp.Regexp
+		not  bool
+	}
+)
+
+func (n andNode) eval(doc []byte) bool { return n.left.eval(doc) && n.right.eval(doc) }
+func (n orNode) eval(doc []byte) bool  { return n.left.eval(doc) || n.right.eval(doc) }
+func (n notNode) eval(doc []byte) bool { return !n.node.eval(doc) }
+
+func (n truthy) eval(doc []byte) bool {
+	r := n.operand.value(doc)
+	switch r.Type {
+	case jj.Null, jj.False:
+		return false
+	case jj.Number:
+		return r.Num != 0
+	case jj.String:
+		return r.Str != ""
+	default:
+		return true
+	}
+}
+
+func (n regexNode) eval(doc []byte) bool {
+	r := n.left.value(doc)
+	return r.Exists() && n.re.MatchString(r.String()) != n.not
+}
+
+func (n cmpNode) eval(doc []byte) bool {
+	a, b := n.left.value(doc), n.right.value(doc)
+	c, ok := compareResults(a, b)
+	switch n.op {
+	case "==":
+		return ok && c == 0 || !ok && a.Type == b.Type && a.String() == b.String()
+	case "!=":
+		return !(ok && c == 0 || !ok && a.Type == b.Type && a.String() == b.String())
+	}
+
+	if !ok {
+		return false
+	}
+
+	switch n.op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	default: // <=
+		return c <= 0
+	}
+}
+
+// compareResults compares numbers, numbers with numeric strings, times and strings.
+func compareResults(a, b jj.Result) (int, bool) {
+	if a.Type == jj.Number || b.Type == jj.Number {
+		x, ok1 := resultNumber(a)
+		y, ok2 := resultNumber(b)
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		return compareOrdered(x, y), true
+	}
+
+	if a.Type != jj.String || b.Type != jj.String {
+		return 0, false
+	}
+
+	if x, ok := parseFilterTime(a.Str); ok {
+		if y, ok := parseFilterTime(b.Str); ok {
+			return x.Compare(y), true
+		}
+	}
+
+	return strings.Compare(a.Str, b.Str), true
+}
+
+func resultNumber(r jj.Result) (float64, bool) {
+	switch r.Type {
+	case jj.Number:
+		return r.Num, true
+	case jj.String:
+		f, err := strconv.ParseFloat(r.Str, 64)
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+func compareOrdered(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+var filterTimeLayouts = []string{
+	timestampLayout, time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02",
+}
+
+func parseFilterTime(s string) (time.Time, bool) {
+	if len(s) < len("2006-01-02") || s[4] != '-' {
+		return time.Time{}, false
+	}
+
+	for _, layout := range filterTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// operand is a jj path or a literal.
+type operand struct {
+	path    string
+	literal jj.Result
+}
+
+func (o operand) value(doc []byte) jj.Result {
+	if o.path != "" {
+		return jj.GetBytes(doc, o.path)
+	}
+	return o.literal
+}
+
+type filterParser struct {
+	lexer filterLexer
+	tok   filterToken
+}
+
+func (p *filterParser) next() (err error) {
+	p.tok, err = p.lexer.next()
+	return err
+}
+
+func (p *filterParser) parse() (filterNode, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return p.parseOr()
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokOp && p.tok.text == "||" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokOp && p.tok.text == "&&" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	switch {
+	case p.tok.kind == tokOp && p.tok.text == "!":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node: node}, nil
+	case p.tok.kind == tokLParen:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, fmt.Errorf("missing ) at %d", p.tok.pos)
+		}
+		return node, p.next()
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokOp || !isCompareOp(p.tok.text) {
+		return truthy{operand: left}, nil
+	}
+
+	op := p.tok.text
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if op == "=~" || op == "!~" {
+		if p.tok.kind != tokString {
+			return nil, fmt.Errorf("regex string expected after %s at %d", op, p.tok.pos)
+		}
+		re, err := regexp.Compile(p.tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("bad regex %q: %w", p.tok.text, err)
+		}
+		return regexNode{left: left, re: re, not: op == "!~"}, p.next()
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return cmpNode{op: op, left: left, right: right}, nil
+}
+
+func isCompareOp(op string) bool {
+	switch op {
+	case "==", "!=", ">", ">=", "<", "<=", "=~", "!~":
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOperand() (o operand, err error) {
+	t := p.tok
+	switch t.kind {
+	case tokString:
+		o.literal = jj.Result{Type: jj.String, Str: t.text, Raw: strconv.Quote(t.text)}
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return o, fmt.Errorf("bad number %s at %d", t.text, t.pos)
+		}
+		o.literal = jj.Result{Type: jj.Number, Num: f, Raw: t.text}
+	case tokPath:
+		switch t.text {
+		case "true":
+			o.literal = jj.Result{Type: jj.True, Raw: "true"}
+		case "false":
+			o.literal = jj.Result{Type: jj.False, Raw: "false"}
+		case "null":
+			o.literal = jj.Result{Type: jj.Null, Raw: "null"}
+		default:
+			o.path = t.text
+		}
+	case tokEOF:
+		return o, fmt.Errorf("unexpected end")
+	default:
+		return o, fmt.Errorf("unexpected %s at %d", t.text, t.pos)
+	}
+
+	return o, p.next()
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPath
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type filterLexer struct {
+	src string
+	pos int
+}
+
+func (l *filterLexer) next() (filterToken, error) {
+	for l.pos < len(l.src) && isFilterSpace(l.src[l.pos]) {
+		l.pos++
+	}
+
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return filterToken{kind: tokEOF, text: "end", pos: start}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return filterToken{kind: tokLParen, text: "(", pos: start}, nil
+	case c == ')':
+		l.pos++
+		return filterToken{kind: tokRParen, text: ")", pos: start}, nil
+	case c == '"' || c == '\'':
+		return l.lexString(c)
+	case c >= '0' && c <= '9' || c == '-' && l.pos+1 < len(l.src) && l.src[l.pos+1] >= '0' && l.src[l.pos+1] <= '9':
+		l.pos++
+		for l.pos < len(l.src) && strings.IndexByte("0123456789.eE+-", l.src[l.pos]) >= 0 {
+			l.pos++
+		}
+		return filterToken{kind: tokNumber, text: l.src[start:l.pos], pos: start}, nil
+	}
+
+	for _, op := range []string{"&&", "||", "==", "!=", ">=", "<=", "=~", "!~", ">", "<", "!"} {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return filterToken{kind: tokOp, text: op, pos: start}, nil
+		}
+	}
+
+	return l.lexPath()
+}
+
+func (l *filterLexer) lexString(quote byte) (filterToken, error) {
+	start := l.pos
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos++
+		case quote:
+			l.pos++
+			raw := l.src[start:l.pos]
+			if quote == '\'' {
+				return filterToken{kind: tokString, text: raw[1 : len(raw)-1], pos: start}, nil
+			}
+			s, err := strconv.Unquote(raw)
+			if err != nil {
+				return filterToken{}, fmt.Errorf("bad string %s at %d", raw, start)
+			}
+			return filterToken{kind: tokString, text: s, pos: start}, nil
+		}
+	}
+
+	return filterToken{}, fmt.Errorf("unterminated string at %d", start)
+}
+
+// lexPath reads a jj path, which ends at a space or an operator,
+// the query parts like #(age>40) and the escaped chars like \. are kept as is.
+func (l *filterLexer) lexPath() (filterToken, error) {
+	start := l.pos
+	depth := 0
+
+loop:
+	for ; l.pos < len(l.src); l.pos++ {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\':
+			l.pos++
+		case c == '(' && l.pos > start && l.src[l.pos-1] == '#':
+			depth++
+		case depth > 0:
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				depth--
+			}
+		case isFilterSpace(c) || strings.IndexByte("()=!<>&|", c) >= 0:
+			break loop
+		}
+	}
+
+	if depth > 0 {
+		return filterToken{}, fmt.Errorf("unclosed ( in path at %d", start)
+	}
+	if l.pos == start {
+		return filterToken{}, fmt.Errorf("unexpected %c at %d", l.src[start], start)
+	}
+
+	end := min(l.pos, len(l.src))
+	return filterToken{kind: tokPath, text: l.src[start:end], pos: start}, nil
+}
+
+func isFilterSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
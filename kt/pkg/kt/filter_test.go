@@ -0,0 +1,73 @@
+package kt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingoohuang/ngg/jj"
+)
+
+func TestFilter(t *testing.T) {
+	m := &sarama.ConsumerMessage{
+		Topic:     "users",
+		Partition: 2,
+		Offset:    100,
+		Timestamp: time.Date(2024, 5, 1, 10, 30, 0, 0, time.Local),
+		Headers:   []*sarama.RecordHeader{{Key: []byte("trace-id"), Value: []byte("abc")}},
+	}
+	doc := MessageDoc(m, []byte("id-23"), []byte(`{"user":{"name":"bingoo","age":35},"items":[{"price":50},{"price":150}]}`))
+
+	cases := []struct {
+		expr  string
+		match bool
+	}{
+		{`value.user.age > 30 && key =~ "id-"`, true},
+		{`value.user.age > 40 || key =~ '^x'`, false},
+		{`value.user.age >= 35 && value.user.age <= 35`, true},
+		{`value.user.name == "bingoo" && value.user.name != "other"`, true},
+		{`headers.trace-id == "abc"`, true},
+		{`headers.missing`, false},
+		{`!headers.missing && headers.trace-id`, true},
+		{`timestamp >= "2024-05-01" && timestamp < "2024-05-01 10:31"`, true},
+		{`timestamp > "2024-05-02"`, false},
+		{`!(partition == 0) && offset == 100 && topic == "users"`, true},
+		{`value.items.#(price>100)`, true},
+		{`value.items.#(price>200)`, false},
+		{`value.items.# == 2`, true},
+		{`value.user.name !~ "^b"`, false},
+		{`value.user.vip == null && value.user.age == "35"`, true},
+	}
+
+	for _, c := range cases {
+		f, err := ParseFilter(c.expr)
+		if err != nil {
+			t.Fatalf("parse %s: %v", c.expr, err)
+		}
+		if got := f.Match(doc); got != c.match {
+			t.Errorf("filter %s = %v, want %v", c.expr, got, c.match)
+		}
+	}
+
+	for _, expr := range []string{`key ==`, `(key == "a"`, `key =~ 1`, `key =~ "("`, `"abc`, `key == "a" )`} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("filter %s should be invalid", expr)
+		}
+	}
+}
+
+func TestSelectPath(t *testing.T) {
+	m := &sarama.ConsumerMessage{Topic: "users", Timestamp: time.Now()}
+	doc := MessageDoc(m, []byte("not json"), []byte(`{"user":{"name":"bingoo","age":35}}`))
+
+	cases := map[string]string{
+		"key,value.user.name":             `{"key":"not json","name":"bingoo"}`,
+		`{"id":key,"age":value.user.age}`: `{"id":"not json","age":35}`,
+		"value.user.age":                  `{"age":35}`,
+	}
+	for fields, want := range cases {
+		if got := jj.GetBytes(doc, SelectPath(fields)).Raw; got != want {
+			t.Errorf("select %s = %s, want %s", fields, got, want)
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package kt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// MirrorConfig is the config of mirroring messages from the source topic to the target topic.
+type MirrorConfig struct {
+	// Source is the source cluster and topic.
+	Source CommonArgs
+	// Target is the target cluster and topic.
+	Target CommonArgs
+	// Offsets is the offset ranges of the source partitions, see ParseOffsets.
+	Offsets string
+	// Repartition partitions the messages by key in the target topic, or else keeps the source partitions.
+	Repartition bool
+	Compress    sarama.CompressionCodec
+	// Qps is the max messages to mirror per second, 0 for no limit.
+	Qps float32
+	// Filter filters the messages to mirror, the key and value are encoded by KeyEncoder and ValEncoder.
+	Filter                 *Filter
+	KeyEncoder, ValEncoder BytesEncoder
+	// Checkpoint is the file to save the progress and resume from.
+	Checkpoint         string
+	CheckpointInterval time.Duration
+	// Timeout stops mirroring a partition after no messages for the duration, 0 for no timeout.
+	Timeout time.Duration
+}
+
+type mirror struct {
+	MirrorConfig
+
+	source     *Consumer
+	producer   sarama.AsyncProducer
+	checkpoint *MirrorCheckpoint
+	throttle   ThrottleFn
+	progress   map[int32]*mirrorProgress
+	results    map[int32]*mirrorResult
+
+	cancel   context.CancelFunc
+	errOnce  sync.Once
+	firstErr error
+}
+
+// mirrorIdleInterval is the interval to check the high water mark when no messages arrive.
+var mirrorIdleInterval = time.Second
+
+// mirrorIdleTicks is the number of the idle intervals in a row to give up the end offset below the high water mark,
+// so that a stalled fetch does not end the mirror early.
+const mirrorIdleTicks = 5
+
+type mirrorMeta struct {
+	partition int32
+	offset    int64
+}
+
+// mirrorResult is the mirroring result of a source partition.
+type mirrorResult struct {
+	Partition int32 `json:"partition"`
+	Start     int64 `json:"start"`
+	Next      int64 `json:"next"`
+	Mirrored  int64 `json:"mirrored"` // acknowledged by the target
+	Filtered  int64 `json:"filtered"`
+
+	size int64
+}
+
+// StartMirror mirrors the messages of the offset ranges from the source topic to the target topic,
+// the keys, values, headers and timestamps are kept, the results of partitions are sent to out.
+// The progress is saved in the checkpoint file periodically and at last,
+// the mirroring starts from the checkpoint offsets if the file exists.
+func StartMirror(ctx context.Context, conf MirrorConfig, out chan<- PrintContext) error {
+	m := &mirror{MirrorConfig: conf, progress: map[int32]*mirrorProgress{}, results: map[int32]*mirrorResult{}}
+	ctx, m.cancel = context.WithCancel(ctx)
+	defer m.cancel()
+
+	var err error
+	if m.checkpoint, err = LoadMirrorCheckpoint(conf.Checkpoint, conf.Source.Topic, conf.Target.Topic); err != nil {
+		return err
+	}
+
+	if err := m.setupSource(); err != nil {
+		return err
+	}
+	defer LogClose("source client", m.source.Client.SaramaClient)
+	defer LogClose("consumer", m.source.Consumer)
+
+	partitions, err := m.source.findPartitions()
+	if err != nil {
+		return err
+	}
+	if len(partitions) == 0 {
+		return fmt.Errorf("no partitions to mirror")
+	}
+
+	results := make([]*mirrorResult, len(partitions))
+	ends := make([]int64, len(partitions))
+	for i, p := range partitions {
+		results[i] = &mirrorResult{Partition: p}
+		m.results[p] = results[i]
+		if ends[i], err = m.prepare(results[i]); err != nil {
+			return fmt.Errorf("mirror partition %d: %w", p, err)
+		}
+	}
+
+	targetClient, err := m.setupProducer(partitions)
+	if err != nil {
+		return err
+	}
+	defer LogClose("target client", targetClient)
+
+	var ticker *time.Ticker
+	m.throttle, ticker = CreateThrottle(ctx, conf.Qps)
+	if ticker != nil {
+		defer ticker.Stop()
+	}
+
+	var acks sync.WaitGroup
+	acks.Add(2)
+	go m.readSuccesses(&acks)
+	go m.readErrors(&acks)
+
+	stopSaving := m.saveCheckpointPeriodically()
+
+	var wg sync.WaitGroup
+	for i, r := range results {
+		if r.Start > ends[i] {
+			log.Printf("nothing to mirror for partition %d in [%d, %d]", r.Partition, r.Start, ends[i])
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *mirrorResult, end int64) {
+			defer wg.Done()
+			if err := m.mirrorPartition(ctx, r, end); err != nil {
+				m.fail(fmt.Errorf("mirror partition %d: %w", r.Partition, err))
+			}
+		}(r, ends[i])
+	}
+	wg.Wait()
+
+	m.producer.AsyncClose()
+	acks.Wait()
+	stopSaving()
+
+	if err := m.saveCheckpoint(); err != nil {
+		m.fail(err)
+	}
+
+	for _, r := range results {
+		r.Next = m.progress[r.Partition].nextOffset()
+		pc := PrintContext{Output: r, Done: make(chan struct{}), MessageNum: int(r.Mirrored), ValueSize: int(r.size)}
+		out <- pc
+		<-pc.Done
+	}
+
+	return m.firstErr
+}
+
+func (m *mirror) setupSource() (err error) {
+	m.source = &Consumer{Topic: m.Source.Topic}
+	if m.source.Client, err = m.Source.SetupClient("kt-mirror-" + CurrentUserName()); err != nil {
+		return err
+	}
+	if m.source.Consumer, err = sarama.NewConsumerFromClient(m.source.Client.SaramaClient); err != nil {
+		return err
+	}
+	if m.source.Offsets, err = ParseOffsets(m.Offsets); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *mirror) setupProducer(partitions []int32) (sarama.Client, error) {
+	sc := sarama.NewConfig()
+	sc.Version = m.Target.KafkaVersion
+	sc.ClientID = "kt-mirror-" + CurrentUserName()
+	sc.Producer.RequiredAcks = sarama.WaitForAll
+	sc.Producer.Return.Successes = true
+	sc.Producer.Return.Errors = true
+	sc.Producer.Compression = m.Compress
+	// one in-flight request per broker keeps the order of messages in a partition
+	sc.Net.MaxOpenRequests = 1
+	sc.Producer.Partitioner = sarama.NewManualPartitioner
+	if m.Repartition {
+		sc.Producer.Partitioner = sarama.NewHashPartitioner
+	}
+
+	if err := m.Target.SetupAuth(sc); err != nil {
+		return nil, err
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, fmt.Errorf("configuration validate: %w", err)
+	}
+
+	client, err := sarama.NewClient(m.Target.KafkaBrokers, sc)
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.Repartition {
+		targetPartitions, err := client.Partitions(m.Target.Topic)
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to read partitions for target topic %s: %w", m.Target.Topic, err)
+		}
+		for _, p := range partitions {
+			if int(p) >= len(targetPartitions) {
+				client.Close()
+				return nil, fmt.Errorf("target topic %s has only %d partitions, less than source partition %d, try to repartition",
+					m.Target.Topic, len(targetPartitions), p)
+			}
+		}
+	}
+
+	if m.producer, err = sarama.NewAsyncProducerFromClient(client); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// prepare resolves the offset range of the partition, the start is moved to the checkpoint offset if exists.
+func (m *mirror) prepare(r *mirrorResult) (end int64, err error) {
+	p := r.Partition
+	interval, ok := m.source.Offsets[p]
+	if !ok {
+		interval = m.source.Offsets[-1]
+	}
+
+	if r.Start, err = m.source.resolveOffset(interval.Start, p); err != nil {
+		return 0, fmt.Errorf("resolve start offset %s: %w", interval.Start, err)
+	}
+	if end, err = m.source.resolveOffset(interval.End, p); err != nil {
+		return 0, fmt.Errorf("resolve end offset %s: %w", interval.End, err)
+	}
+
+	if next, ok := m.checkpoint.Next(p); ok && next > r.Start {
+		log.Printf("resume partition %d from checkpoint offset %d", p, next)
+		r.Start = next
+	}
+
+	m.progress[p] = newMirrorProgress(r.Start)
+	return end, nil
+}
+
+func (m *mirror) mirrorPartition(ctx context.Context, r *mirrorResult, end int64) error {
+	p, start := r.Partition, r.Start
+	progress := m.progress[p]
+
+	log.Printf("start to mirror topic: %s partition: %d in [%d, %d] to topic: %s",
+		m.Source.Topic, p, start, end, m.Target.Topic)
+
+	pc, err := m.source.ConsumePartition(m.Source.Topic, p, start)
+	if errors.Is(err, sarama.ErrOffsetOutOfRange) {
+		log.Printf("W! offset %d of partition %d is out of range, start from the oldest", start, p)
+		pc, err = m.source.ConsumePartition(m.Source.Topic, p, sarama.OffsetOldest)
+	}
+	if err != nil {
+		return err
+	}
+	defer LogClose(fmt.Sprintf("partition consumer %v", p), pc)
+
+	var timer *time.Timer
+	timeout := make(<-chan time.Time)
+	resetTimeout := func() {
+		if m.Timeout > 0 {
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.NewTimer(m.Timeout)
+			timeout = timer.C
+		}
+	}
+	resetTimeout()
+
+	// the end offset may never arrive when it is compacted or a transaction marker,
+	// so stop when the high water mark has passed it and no more messages arrive.
+	idle := time.NewTicker(mirrorIdleInterval)
+	defer idle.Stop()
+	last, idleTicks := start-1, 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timeout:
+			log.Printf("mirroring from partition %v timed out after %s", p, m.Timeout)
+			return nil
+		case <-idle.C:
+			hwm := pc.HighWaterMarkOffset()
+			if hwm <= end {
+				continue
+			}
+			if idleTicks++; last >= hwm-1 || idleTicks >= mirrorIdleTicks {
+				log.Printf("W! partition %d has no more messages after offset %d, %d offsets short of end offset %d",
+					p, last, end-last, end)
+				return nil
+			}
+		case err, ok := <-pc.Errors():
+			if !ok {
+				return nil
+			}
+			return err
+		case msg, ok := <-pc.Messages():
+			if !ok {
+				return nil
+			}
+			last, idleTicks = msg.Offset, 0
+			resetTimeout()
+			if msg.Offset > end {
+				return nil
+			}
+
+			if m.match(msg) {
+				if !m.throttle() {
+					return nil
+				}
+				m.send(msg, progress)
+			} else {
+				progress.skip(msg.Offset)
+				r.Filtered++
+			}
+
+			if msg.Offset >= end {
+				return nil
+			}
+		}
+	}
+}
+
+func (m *mirror) match(msg *sarama.ConsumerMessage) bool {
+	if m.Filter == nil {
+		return true
+	}
+
+	doc := MessageDoc(msg, []byte(m.KeyEncoder.Encode(msg.Key)), []byte(m.ValEncoder.Encode(msg.Value)))
+	return m.Filter.Match(doc)
+}
+
+func (m *mirror) send(msg *sarama.ConsumerMessage, progress *mirrorProgress) {
+	pm := &sarama.ProducerMessage{
+		Topic:     m.Target.Topic,
+		Timestamp: msg.Timestamp,
+		Metadata:  mirrorMeta{partition: msg.Partition, offset: msg.Offset},
+	}
+	if !m.Repartition {
+		pm.Partition = msg.Partition
+	}
+	// keep the nil key and value (tombstone) as they are
+	if msg.Key != nil {
+		pm.Key = sarama.ByteEncoder(msg.Key)
+	}
+	if msg.Value != nil {
+		pm.Value = sarama.ByteEncoder(msg.Value)
+	}
+	for _, h := range msg.Headers {
+		if h != nil {
+			pm.Headers = append(pm.Headers, *h)
+		}
+	}
+
+	progress.send(msg.Offset)
+	m.producer.Input() <- pm
+}
+
+func (m *mirror) readSuccesses(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for pm := range m.producer.Successes() {
+		meta := pm.Metadata.(mirrorMeta)
+		m.progress[meta.partition].ack(meta.offset)
+
+		r := m.results[meta.partition]
+		r.Mirrored++
+		if pm.Value != nil {
+			r.size += int64(pm.Value.Length())
+		}
+	}
+}
+
+func (m *mirror) readErrors(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for pe := range m.producer.Errors() {
+		meta := pe.Msg.Metadata.(mirrorMeta)
+		m.fail(fmt.Errorf("failed to mirror partition %d offset %d: %w", meta.partition, meta.offset, pe.Err))
+	}
+}
+
+func (m *mirror) fail(err error) {
+	log.Printf("E! %v", err)
+	m.errOnce.Do(func() {
+		m.firstErr = err
+		m.cancel()
+	})
+}
+
+func (m *mirror) saveCheckpoint() error {
+	for p, progress := range m.progress {
+		m.checkpoint.Update(p, progress.nextOffset())
+	}
+
+	return m.checkpoint.Save(m.Checkpoint)
+}
+
+func (m *mirror) saveCheckpointPeriodically() (stop func()) {
+	interval := m.CheckpointInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := m.saveCheckpoint(); err != nil {
+					log.Printf("E! save checkpoint %s: %v", m.Checkpoint, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,131 @@
+package kt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+// markerConsumer simulates a transaction marker after the yielded messages,
+// which advances the high water mark without a message.
+type markerConsumer struct {
+	*mocks.Consumer
+}
+
+func (c markerConsumer) ConsumePartition(topic string, partition int32, offset int64) (sarama.PartitionConsumer, error) {
+	pc, err := c.Consumer.ConsumePartition(topic, partition, offset)
+	if err != nil {
+		return nil, err
+	}
+	return markerPartitionConsumer{PartitionConsumer: pc}, nil
+}
+
+type markerPartitionConsumer struct {
+	sarama.PartitionConsumer
+}
+
+func (pc markerPartitionConsumer) HighWaterMarkOffset() int64 {
+	return pc.PartitionConsumer.HighWaterMarkOffset() + 1
+}
+
+func TestMirrorPartition(t *testing.T) {
+	defer func(d time.Duration) { mirrorIdleInterval = d }(mirrorIdleInterval)
+	mirrorIdleInterval = 10 * time.Millisecond
+
+	// the end offset 3 is a transaction marker, which never arrives
+	m := runMirrorPartition(t, 3, nil, 0)
+	if r := m.results[0]; r.Mirrored != 3 || r.size != 6 {
+		t.Fatalf("mirrored %d messages of %d bytes, want 3 messages of 6 bytes", r.Mirrored, r.size)
+	}
+	if next := m.progress[0].nextOffset(); next != 3 {
+		t.Fatalf("next offset %d, want 3", next)
+	}
+
+	// the fetch stalls for a while after the high water mark has passed the end offset
+	m = runMirrorPartition(t, 2, nil, 2*mirrorIdleInterval)
+	if r := m.results[0]; r.Mirrored != 3 {
+		t.Fatalf("mirrored %d messages, want 3 after the stalled fetch", r.Mirrored)
+	}
+
+	// only the acknowledged messages are counted
+	m = runMirrorPartition(t, 2, sarama.ErrNotEnoughReplicas, 0)
+	if r := m.results[0]; r.Mirrored != 2 || r.size != 3 {
+		t.Fatalf("mirrored %d messages of %d bytes, want 2 messages of 3 bytes", r.Mirrored, r.size)
+	}
+	if !errors.Is(m.firstErr, sarama.ErrNotEnoughReplicas) {
+		t.Fatalf("first error %v", m.firstErr)
+	}
+	if next := m.progress[0].nextOffset(); next != 2 {
+		t.Fatalf("next offset %d, want 2 before the failed message", next)
+	}
+}
+
+// runMirrorPartition mirrors the messages a, bb and ccc until the end offset,
+// the last message fails to produce when lastErr is not nil, and arrives after the delay.
+func runMirrorPartition(t *testing.T, end int64, lastErr error, delay time.Duration) *mirror {
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+
+	consumer := mocks.NewConsumer(t, config)
+	pc := consumer.ExpectConsumePartition("orders", 0, 0)
+	pc.YieldMessage(&sarama.ConsumerMessage{Key: []byte("k"), Value: []byte("a")})
+	pc.YieldMessage(&sarama.ConsumerMessage{Key: []byte("k"), Value: []byte("bb")})
+	last := &sarama.ConsumerMessage{Key: []byte("k"), Value: []byte("ccc")}
+	if delay > 0 {
+		time.AfterFunc(delay, func() { pc.YieldMessage(last) })
+	} else {
+		pc.YieldMessage(last)
+	}
+
+	producer := mocks.NewAsyncProducer(t, config)
+	producer.ExpectInputAndSucceed()
+	producer.ExpectInputAndSucceed()
+	if lastErr != nil {
+		producer.ExpectInputAndFail(lastErr)
+	} else {
+		producer.ExpectInputAndSucceed()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := &mirrorResult{Partition: 0}
+	m := &mirror{
+		MirrorConfig: MirrorConfig{Source: CommonArgs{Topic: "orders"}, Target: CommonArgs{Topic: "orders.copy"}},
+		source:       &Consumer{Consumer: markerConsumer{Consumer: consumer}},
+		producer:     producer,
+		throttle:     func() bool { return true },
+		progress:     map[int32]*mirrorProgress{0: newMirrorProgress(0)},
+		results:      map[int32]*mirrorResult{0: r},
+		cancel:       cancel,
+	}
+
+	var acks sync.WaitGroup
+	acks.Add(2)
+	go m.readSuccesses(&acks)
+	go m.readErrors(&acks)
+
+	done := make(chan error, 1)
+	go func() { done <- m.mirrorPartition(ctx, r, end) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("mirror partition should stop after the high water mark passes the end offset")
+	}
+
+	m.producer.AsyncClose()
+	acks.Wait()
+
+	if err := consumer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
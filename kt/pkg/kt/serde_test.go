@@ -0,0 +1,296 @@
+package kt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry is an in-memory schema registry.
+type fakeRegistry struct {
+	mu       sync.Mutex
+	schemas  []Schema
+	subjects map[string][]int
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/subjects/"):
+		subject := strings.TrimSuffix(strings.TrimPrefix(path, "/subjects/"), "/versions")
+		var s Schema
+		_ = json.NewDecoder(r.Body).Decode(&s)
+		for _, id := range f.subjects[subject] {
+			if f.schemas[id-1].Schema == s.Schema {
+				_ = json.NewEncoder(w).Encode(map[string]int{"id": id})
+				return
+			}
+		}
+		s.ID, s.Subject = len(f.schemas)+1, subject
+		f.schemas = append(f.schemas, s)
+		f.subjects[subject] = append(f.subjects[subject], s.ID)
+		s.Version = len(f.subjects[subject])
+		f.schemas[s.ID-1] = s
+		_ = json.NewEncoder(w).Encode(map[string]int{"id": s.ID})
+	case strings.HasPrefix(path, "/schemas/ids/"):
+		var id int
+		_ = json.Unmarshal([]byte(strings.TrimPrefix(path, "/schemas/ids/")), &id)
+		if id < 1 || id > len(f.schemas) {
+			http.Error(w, `{"error_code":40403}`, http.StatusNotFound)
+			return
+		}
+		s := f.schemas[id-1]
+		_ = json.NewEncoder(w).Encode(Schema{Schema: s.Schema, SchemaType: s.SchemaType, References: s.References})
+	case strings.HasPrefix(path, "/subjects/"):
+		subject, version, _ := strings.Cut(strings.TrimPrefix(path, "/subjects/"), "/versions/")
+		ids := f.subjects[subject]
+		if len(ids) == 0 {
+			http.Error(w, `{"error_code":40401}`, http.StatusNotFound)
+			return
+		}
+		id := ids[len(ids)-1]
+		if version != "latest" {
+			var v int
+			_ = json.Unmarshal([]byte(version), &v)
+			id = ids[v-1]
+		}
+		_ = json.NewEncoder(w).Encode(f.schemas[id-1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeRegistry(t *testing.T) (*fakeRegistry, string) {
+	f := &fakeRegistry{subjects: map[string][]int{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv.URL
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func assertRoundTrip(t *testing.T, s Serde, topic, in string) []byte {
+	t.Helper()
+
+	data, err := s.Encode(topic, []byte(in))
+	if err != nil {
+		t.Fatalf("encode %s: %v", in, err)
+	}
+
+	out, err := s.Decode(topic, data)
+	if err != nil {
+		t.Fatalf("decode %s: %v", in, err)
+	}
+
+	var want, got any
+	_ = json.Unmarshal([]byte(in), &want)
+	_ = json.Unmarshal(out, &got)
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("round trip %s, got %s", in, out)
+	}
+
+	return data
+}
+
+const userAvsc = `{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"age","type":"int"}]}`
+
+func TestAvroSerde(t *testing.T) {
+	_, url := newFakeRegistry(t)
+
+	// register the local schema and produce in the wire format
+	producer := &SerdeArgs{SchemaRegistry: url, ValueSchema: writeFile(t, "user.avsc", userAvsc)}
+	s, err := producer.NewSerde("avro", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := assertRoundTrip(t, s, "users", `{"name":"bingoo","age":30}`)
+	if id, _, ok := splitConfluentFrame(data); !ok || id != 1 {
+		t.Fatalf("expect framed with id 1, got %x", data)
+	}
+
+	// consume by the schema registry only
+	consumer := &SerdeArgs{SchemaRegistry: url}
+	enc, err := consumer.BytesEncoder("avro", "users", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := enc.Encode(data); got != `{"name":"bingoo","age":30}` {
+		t.Fatalf("got %s", got)
+	}
+
+	// the latest schema of the subject is used for producing without local schema
+	s, _ = consumer.NewSerde("avro", false)
+	assertRoundTrip(t, s, "users", `{"name":"huang","age":18}`)
+
+	// offline with the local schema only, the framed message is decoded without the header
+	offline := &SerdeArgs{ValueSchema: producer.ValueSchema}
+	s, _ = offline.NewSerde("avro", false)
+	if out, err := s.Decode("users", data); err != nil || string(out) != `{"name":"bingoo","age":30}` {
+		t.Fatalf("got %s, %v", out, err)
+	}
+
+	// the plain payload may start with the magic byte 0 too
+	data = assertRoundTrip(t, s, "users", `{"name":"","age":1234567}`)
+	if _, _, ok := splitConfluentFrame(data); !ok {
+		t.Fatalf("expect looking like framed, got %x", data)
+	}
+
+	// the schema id of the plain payload is not found in the registry, decoded by the local schema
+	s, _ = (&SerdeArgs{SchemaRegistry: url, ValueSchema: producer.ValueSchema}).NewSerde("avro", false)
+	if out, err := s.Decode("users", data); err != nil || string(out) != `{"name":"","age":1234567}` {
+		t.Fatalf("got %s, %v", out, err)
+	}
+
+	if _, err := (&SerdeArgs{}).NewSerde("avro", false); err == nil {
+		t.Fatal("expect error without registry and local schema")
+	}
+}
+
+func TestAvroReferences(t *testing.T) {
+	f, url := newFakeRegistry(t)
+	f.schemas = []Schema{
+		{ID: 1, Subject: "shop.Address", Version: 1, Schema: `{"type":"record","name":"Address","namespace":"shop","fields":[{"name":"city","type":"string"}]}`},
+		{ID: 2, Subject: "users-value", Version: 1, Schema: `{"type":"record","name":"User","namespace":"shop","fields":[
+{"name":"name","type":"string"},{"name":"home","type":"Address"},{"name":"work","type":["null","shop.Address"]}]}`,
+			References: []SchemaReference{{Name: "shop.Address", Subject: "shop.Address", Version: 1}}},
+	}
+	f.subjects["shop.Address"] = []int{1}
+	f.subjects["users-value"] = []int{2}
+
+	s, err := (&SerdeArgs{SchemaRegistry: url}).NewSerde("avro", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRoundTrip(t, s, "users", `{"name":"bingoo","home":{"city":"beijing"},"work":{"shop.Address":{"city":"shanghai"}}}`)
+}
+
+func TestAvroFieldOrder(t *testing.T) {
+	const orderAvsc = `{"type":"record","name":"Order","namespace":"shop","fields":[
+{"name":"id","type":"string"},
+{"name":"customer","type":["null",{"type":"record","name":"Customer","fields":[{"name":"name","type":"string"},{"name":"city","type":"string"}]}]},
+{"name":"items","type":{"type":"array","items":{"type":"record","name":"Item","fields":[{"name":"sku","type":"string"},{"name":"count","type":"int"}]}}},
+{"name":"tags","type":{"type":"map","values":"Customer"}},
+{"name":"at","type":{"type":"long","logicalType":"timestamp-millis"}}]}`
+
+	s, err := (&SerdeArgs{ValueSchema: writeFile(t, "order.avsc", orderAvsc)}).NewSerde("avro", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := `{"id":"o-1","customer":{"shop.Customer":{"name":"bingoo","city":"beijing"}},` +
+		`"items":[{"sku":"a-1","count":2}],"tags":{"z":{"name":"z","city":"z"},"a":{"name":"a","city":"a"}},"at":1700000000000}`
+	data := assertRoundTrip(t, s, "orders", in)
+	out, _ := s.Decode("orders", data)
+	want := `{"id":"o-1","customer":{"shop.Customer":{"name":"bingoo","city":"beijing"}},` +
+		`"items":[{"sku":"a-1","count":2}],"tags":{"a":{"name":"a","city":"a"},"z":{"name":"z","city":"z"}},"at":1700000000000}`
+	if string(out) != want {
+		t.Fatalf("got %s", out)
+	}
+}
+
+func TestProtobufSerde(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "common.proto"), []byte(`syntax = "proto3";
+package shop;
+message Money { string currency = 1; int64 units = 2; }
+`), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "order.proto"), []byte(`syntax = "proto3";
+package shop;
+import "common.proto";
+message Order {
+  string id = 1;
+  message Item { string sku = 1; Money price = 2; }
+  repeated Item items = 2;
+}
+`), 0o644)
+
+	args := &SerdeArgs{ValueSchema: filepath.Join(dir, "order.proto") + "#shop.Order.Item"}
+	s, err := args.NewSerde("protobuf", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRoundTrip(t, s, "orders", `{"sku":"a-1","price":{"currency":"CNY","units":"100"}}`)
+
+	// the schema registry with references
+	f, url := newFakeRegistry(t)
+	common, _ := os.ReadFile(filepath.Join(dir, "common.proto"))
+	order, _ := os.ReadFile(filepath.Join(dir, "order.proto"))
+	f.schemas = []Schema{
+		{ID: 1, Subject: "common.proto", Version: 1, Schema: string(common), SchemaType: "PROTOBUF"},
+		{ID: 2, Subject: "orders-value", Version: 1, Schema: string(order), SchemaType: "PROTOBUF",
+			References: []SchemaReference{{Name: "common.proto", Subject: "common.proto", Version: 1}}},
+	}
+	f.subjects["common.proto"] = []int{1}
+	f.subjects["orders-value"] = []int{2}
+
+	s, err = (&SerdeArgs{SchemaRegistry: url}).NewSerde("protobuf", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := assertRoundTrip(t, s, "orders", `{"id":"o-1","items":[{"sku":"a-1"}]}`)
+	if data[5] != 0 {
+		t.Fatalf("expect message indexes [0], got %x", data)
+	}
+
+	// the nested message Order.Item in the wire format with indexes [0, 0]
+	item, _ := (&SerdeArgs{ValueSchema: args.ValueSchema}).NewSerde("protobuf", false)
+	payload, _ := item.Encode("orders", []byte(`{"sku":"a-2"}`))
+	framed := appendConfluentFrame(2, []int{0, 0}, payload)
+	out, err := s.Decode("orders", framed)
+	if err != nil || string(out) != `{"sku":"a-2"}` {
+		t.Fatalf("got %s, %v", out, err)
+	}
+}
+
+func TestJSONSchemaSerde(t *testing.T) {
+	file := writeFile(t, "user.json", `{"type":"object","required":["name"],"properties":{"name":{"type":"string"},"age":{"type":"integer","minimum":0}}}`)
+	s, err := (&SerdeArgs{ValueSchema: file}).NewSerde("jsonschema", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertRoundTrip(t, s, "users", `{"name":"bingoo","age":30}`)
+	if _, err := s.Encode("users", []byte(`{"age":-1}`)); err == nil {
+		t.Fatal("expect validation error")
+	}
+
+	enc := SerdeBytesEncoder{Serde: s, Topic: "users"}
+	if got := enc.Encode([]byte(`{"age":"x"}`)); !strings.HasPrefix(got, `{"age":"x"} (!`) {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestMessageIndexes(t *testing.T) {
+	for _, indexes := range [][]int{{0}, {1}, {0, 2, 1}} {
+		framed := appendConfluentFrame(7, indexes, []byte("payload"))
+		id, payload, ok := splitConfluentFrame(framed)
+		if !ok || id != 7 {
+			t.Fatalf("bad frame %x", framed)
+		}
+		got, rest, err := readMessageIndexes(payload)
+		if err != nil || !reflect.DeepEqual(indexes, got) || string(rest) != "payload" {
+			t.Fatalf("indexes %v, got %v %q %v", indexes, got, rest, err)
+		}
+	}
+
+	// the corrupt count of indexes
+	if _, _, err := readMessageIndexes(binary.AppendVarint(nil, 1<<40)); err == nil {
+		t.Fatal("expect error for the count larger than the payload")
+	}
+}
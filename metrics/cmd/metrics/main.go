@@ -17,8 +17,18 @@ func main() {
 	port := flag.Int("port", 0, "http port")
 	dur := flag.Duration("dur", 100*time.Millisecond, "generate interval")
 	typ := flag.String("type", "", "metric type, e.g. RT, QPS, SuccessRate, FailRate, HitRate, Cur")
+	mergeMode := flag.Bool("merge", false, "merge the RT sketches in the metrics log files of args (stdin if none) across intervals and hosts")
+	mergeKey := flag.String("key", "", "only merge the key with -merge, e.g. key1#key2#key3")
+	mergeWindow := flag.Duration("window", 0, "merge by time window with -merge, e.g. 1m, 0 to merge all intervals")
+	mergeHost := flag.Bool("host", false, "merge per host with -merge, default merge across hosts")
 
 	flag.Parse()
+	if *mergeMode {
+		if err := merge(flag.Args(), *mergeKey, *mergeWindow, *mergeHost); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	if *port > 0 {
 		http.HandleFunc("/none", func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/json; charset=utf8")
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bingoohuang/ngg/metrics/metric"
+	"github.com/bingoohuang/ngg/metrics/pkg/sketch"
+)
+
+type mergeGroup struct {
+	Window time.Time
+	Key    string
+	Host   string
+}
+
+// merge merges the RT sketches in the metrics log files across intervals and hosts,
+// and prints the merged quantiles.
+func merge(files []string, key string, window time.Duration, perHost bool) error {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	sketches := map[mergeGroup]*sketch.Sketch{}
+	for _, file := range files {
+		if err := mergeFile(file, key, window, perHost, sketches); err != nil {
+			return err
+		}
+	}
+
+	groups := make([]mergeGroup, 0, len(sketches))
+	for g := range sketches {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if !a.Window.Equal(b.Window) {
+			return a.Window.Before(b.Window)
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Host < b.Host
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "WINDOW\tKEY\tHOST\tCOUNT\tAVG\tMIN\tP50\tP90\tP99\tP999\tMAX")
+	for _, g := range groups {
+		s := sketches[g]
+		win := "-"
+		if !g.Window.IsZero() {
+			win = g.Window.Format(time.DateTime)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\n",
+			win, g.Key, orDash(g.Host), s.Count(), s.Sum()/float64(s.Count()), s.Min(),
+			s.Quantile(0.5), s.Quantile(0.9), s.Quantile(0.99), s.Quantile(0.999), s.Max())
+	}
+
+	return w.Flush()
+}
+
+func mergeFile(file, key string, window time.Duration, perHost bool, sketches map[mergeGroup]*sketch.Sketch) error {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		var l metric.Line
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			return fmt.Errorf("%s:%d: %w", file, lineNo, err)
+		}
+		if l.LogType != metric.KeyRT || l.Sketch == "" || key != "" && l.Key != key {
+			continue
+		}
+
+		s, err := sketch.Parse(l.Sketch)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", file, lineNo, err)
+		}
+
+		g := mergeGroup{Key: l.Key}
+		if perHost {
+			g.Host = l.Hostname
+		}
+		if window > 0 {
+			t, err := time.ParseInLocation(metric.TimeLayout, l.Time, time.Local)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", file, lineNo, err)
+			}
+			g.Window = t.Truncate(window)
+		}
+
+		if m, ok := sketches[g]; ok {
+			if err := m.Merge(s); err != nil {
+				return fmt.Errorf("%s:%d: %w", file, lineNo, err)
+			}
+		} else {
+			sketches[g] = s
+		}
+	}
+
+	return scanner.Err()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -1,12 +1,17 @@
 package metric_test
 
 import (
+	"encoding/json"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/bingoohuang/ngg/metrics/metric"
 	"github.com/bingoohuang/ngg/metrics/pkg/ks"
+	"github.com/bingoohuang/ngg/metrics/pkg/sketch"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -180,3 +185,36 @@ func TestFloat64(t *testing.T) {
 
 	assert.True(t, metric.FloatEquals(a, b))
 }
+
+func TestRTQuantiles(t *testing.T) {
+	dir := t.TempDir()
+	r := metric.NewRunner(metric.AppName("quantile"), metric.LogPath(dir), metric.MetricsInterval(100*time.Millisecond))
+	r.Start()
+	defer r.Stop()
+
+	now := time.Now()
+	for i := 1; i <= 1000; i++ {
+		r.RT("key1").RecordSince(now.Add(-time.Duration(i) * time.Millisecond))
+	}
+
+	// the records may be split into several intervals, merge them all.
+	merged := sketch.New(sketch.DefaultRelativeAccuracy)
+	assert.Eventually(t, func() bool {
+		merged = sketch.New(sketch.DefaultRelativeAccuracy)
+		data, _ := os.ReadFile(filepath.Join(dir, "metrics-key.quantile.log"))
+		for _, l := range strings.Split(string(data), "\n") {
+			var line metric.Line
+			if l == "" || json.Unmarshal([]byte(l), &line) != nil {
+				continue
+			}
+			assert.Greater(t, line.P99, 0.0)
+			s, err := sketch.Parse(line.Sketch)
+			assert.Nil(t, err)
+			assert.Nil(t, merged.Merge(s))
+		}
+		return merged.Count() == 1000
+	}, 5*time.Second, 20*time.Millisecond)
+
+	assert.InEpsilon(t, 500, merged.Quantile(0.5), 0.02)
+	assert.InEpsilon(t, 990, merged.Quantile(0.99), 0.02)
+}
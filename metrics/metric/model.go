@@ -8,6 +8,7 @@ import (
 
 	"github.com/bingoohuang/ngg/metrics/pkg/ks"
 	"github.com/bingoohuang/ngg/metrics/pkg/lineprotocol"
+	"github.com/bingoohuang/ngg/metrics/pkg/sketch"
 )
 
 // LogType means the logMetrics type.
@@ -80,6 +81,14 @@ type Line struct {
 	Min  float64  `json:"min"` // 每次采集区间（METRICS_INTERVAL）中 v1  最小/大值
 	Max  float64  `json:"max"` // 只对 RT 生效
 
+	P50    float64 `json:"p50,omitempty"`    // RT 每次采集区间（METRICS_INTERVAL）中的 50 分位数
+	P90    float64 `json:"p90,omitempty"`    // RT 90 分位数
+	P99    float64 `json:"p99,omitempty"`    // RT 99 分位数
+	P999   float64 `json:"p999,omitempty"`   // RT 99.9 分位数
+	Sketch string  `json:"sketch,omitempty"` // RT 分位数草图（DDSketch）的 base64 编码，可跨区间、跨主机合并
+
+	sketch *sketch.Sketch
+
 	V1 float64 `json:"v1"` // 小数
 	V2 float64 `json:"v2"` // 只有比率类型的时候，才用到v2
 	V3 float64 `json:"v3"` // RT 当 [300-400) ms 时 v3 = 1
@@ -108,6 +117,9 @@ func (l *Line) ToLineProtocol() (string, error) {
 	if l.LogType == KeyRT {
 		fields["min"] = l.Min
 		fields["max"] = l.Max
+		if l.Sketch != "" {
+			fields["p50"], fields["p90"], fields["p99"], fields["p999"] = l.P50, l.P90, l.P99, l.P999
+		}
 	}
 
 	if l.Ks != nil {
@@ -124,6 +136,41 @@ func (l *Line) ToLineProtocol() (string, error) {
 		t)
 }
 
+// fillQuantiles fills the quantiles and the encoded sketch of RT.
+func (l *Line) fillQuantiles() {
+	if l.sketch == nil || l.sketch.Count() == 0 {
+		return
+	}
+
+	l.P50 = l.sketch.Quantile(0.5)
+	l.P90 = l.sketch.Quantile(0.9)
+	l.P99 = l.sketch.Quantile(0.99)
+	l.P999 = l.sketch.Quantile(0.999)
+	l.Sketch = l.sketch.String()
+}
+
+// keyLabel is a key label like k1="xxx" of the metric line.
+type keyLabel struct {
+	Name, Value string
+}
+
+// keyLabels returns the k1...kn labels from the keys and the non-empty extra keys,
+// the prometheus exposition and all the sinks use the same labels.
+func (l *Line) keyLabels() (labels []keyLabel) {
+	for i, k := range l.Keys {
+		labels = append(labels, keyLabel{Name: fmt.Sprintf("k%d", i+1), Value: k})
+	}
+	if l.Ks != nil {
+		for i := 3; i < len(l.Ks.Keys); i++ {
+			if v := l.Ks.Keys[i]; v != "" {
+				labels = append(labels, keyLabel{Name: fmt.Sprintf("k%d", i+1), Value: v})
+			}
+		}
+	}
+
+	return labels
+}
+
 func (l *Line) hasExtraKeys() bool {
 	if l.Ks != nil {
 		for i := 3; i <= len(l.Ks.Keys); i++ {
@@ -187,6 +234,7 @@ func (r *Runner) AsyncPut(keys Key, logType LogType, v1, v2 float64, vx ...float
 		select {
 		case r.C <- line:
 		default: // bypass, async.
+			r.dropped.Add(1)
 		}
 	} else {
 		r.C <- line
@@ -21,6 +21,12 @@ type Option struct {
 	MaxBackups      int           `default:"7" env:"MAX_BACKUPS"`             // 最大保留天数
 	AutoDrop        bool          `env:"AUTO_DROP"`                           // 在指标来不及处理时，是否自动扔弃
 	Debug           bool          `env:"DEBUG"`                               // 开启测试模式
+	PromAddr        string        `env:"PROM_ADDR"`                           // Prometheus 指标暴露地址，例如 :9100，为空时不暴露
+	Sinks           string        `env:"SINKS"`                               // 指标推送地址，多个以逗号分隔，例如 influx+http://127.0.0.1:8086/write?db=metrics,statsd://127.0.0.1:8125
+	SinkBuffer      int           `default:"100" env:"SINK_BUFFER"`           // 每个推送目标缓冲的批次数，缓冲满时扔弃
+	SinkRetries     int           `default:"3" env:"SINK_RETRIES"`            // 推送失败时的重试次数，重试耗尽时扔弃
+
+	sinks []Sink
 }
 
 // OptionFn defines the function for options setting.
@@ -56,6 +62,11 @@ func LogPath(v string) OptionFn { return func(o *Option) { o.LogPath = v } }
 // MaxBackups sets max backups of metrics logMetrics files.
 func MaxBackups(v int) OptionFn { return func(o *Option) { o.MaxBackups = v } }
 
+// WithSinks adds the sinks to push the metrics lines.
+func WithSinks(sinks ...Sink) OptionFn {
+	return func(o *Option) { o.sinks = append(o.sinks, sinks...) }
+}
+
 // createOption creates Option by option functions.
 func createOption(ofs ...OptionFn) *Option {
 	o := &Option{}
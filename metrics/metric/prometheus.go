@@ -0,0 +1,232 @@
+package metric
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// rtBuckets are the upper bounds (ms) of the RT histogram, matching the v3-v9 boundaries,
+// v3: [300-400) ms, ..., v8: [800-900) ms, v9: [900-∞) ms.
+var rtBuckets = []float64{300, 400, 500, 600, 700, 800, 900}
+
+// promSeries is a cumulative series for the Prometheus exposition.
+type promSeries struct {
+	logType LogType
+	labels  string // rendered labels, like key="a#b",k1="a",k2="b"
+
+	v1, v2  float64
+	buckets [7]float64 // accumulated v3-v9
+}
+
+// promState keeps the cumulative values since the runner started,
+// because the lines in the cache are reset after every METRICS_INTERVAL.
+type promState struct {
+	sync.Mutex
+	series map[cacheKey]*promSeries
+}
+
+// PrometheusHandler returns an http.Handler to expose the recorded metrics in the Prometheus text format,
+// or the OpenMetrics format when the scraper accepts application/openmetrics-text.
+// The metrics are accumulated only after the first call of PrometheusHandler.
+func (r *Runner) PrometheusHandler() http.Handler {
+	r.promOnce.Do(func() {
+		r.prom.Store(&promState{series: make(map[cacheKey]*promSeries)})
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		openMetrics := strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text")
+		if openMetrics {
+			w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		}
+
+		bw := bufio.NewWriter(w)
+		r.WritePrometheus(bw, openMetrics)
+		_ = bw.Flush()
+	})
+}
+
+func (r *Runner) startPrometheus() {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r.PrometheusHandler())
+	go func() {
+		if err := http.ListenAndServe(r.option.PromAddr, mux); err != nil {
+			log.Printf("W! prometheus listen on %s failed: %v", r.option.PromAddr, err)
+		}
+	}()
+}
+
+// exposeLine accumulates the raw line into the Prometheus series.
+func (r *Runner) exposeLine(l *Line) {
+	p := r.prom.Load()
+	if p == nil || l.LogType == HB {
+		return
+	}
+
+	k := l.makeCacheKey()
+
+	p.Lock()
+	defer p.Unlock()
+
+	s, ok := p.series[k]
+	if !ok {
+		s = &promSeries{logType: l.LogType, labels: r.promLabels(l)}
+		p.series[k] = s
+	}
+
+	if l.LogType.isSimple() {
+		s.v1 = l.V1
+		return
+	}
+
+	s.v1 += l.V1
+	s.v2 += l.V2
+	for i, v := range []float64{l.V3, l.V4, l.V5, l.V6, l.V7, l.V8, l.V9} {
+		s.buckets[i] += v
+	}
+}
+
+func (r *Runner) promLabels(l *Line) string {
+	var b strings.Builder
+	label := func(name, value string) {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(value))
+		b.WriteByte('"')
+	}
+
+	label("app", r.AppName)
+	label("key", l.Key)
+	for _, kl := range l.keyLabels() {
+		label(kl.Name, kl.Value)
+	}
+
+	return b.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(s string) string { return labelValueReplacer.Replace(s) }
+
+// promFamily defines how a log type is exposed.
+type promFamily struct {
+	name, typ, help string
+	value           func(s *promSeries) float64
+}
+
+var promFamilies = map[LogType][]promFamily{
+	KeyQPS: {{name: "metrics_qps", typ: "counter", help: "Accumulated business count.", value: func(s *promSeries) float64 { return s.v1 }}},
+	KeySuccessRate: {
+		{name: "metrics_success", typ: "counter", help: "Accumulated success count.", value: func(s *promSeries) float64 { return s.v1 }},
+		{name: "metrics_success_calls", typ: "counter", help: "Accumulated call count of success rate.", value: func(s *promSeries) float64 { return s.v2 }},
+	},
+	KeyFailRate: {
+		{name: "metrics_fail", typ: "counter", help: "Accumulated failure count.", value: func(s *promSeries) float64 { return s.v1 }},
+		{name: "metrics_fail_calls", typ: "counter", help: "Accumulated call count of fail rate.", value: func(s *promSeries) float64 { return s.v2 }},
+	},
+	KeyHitRate: {
+		{name: "metrics_hit", typ: "counter", help: "Accumulated hit count.", value: func(s *promSeries) float64 { return s.v1 }},
+		{name: "metrics_hit_calls", typ: "counter", help: "Accumulated call count of hit rate.", value: func(s *promSeries) float64 { return s.v2 }},
+	},
+	KeyCUR: {{name: "metrics_cur", typ: "gauge", help: "Current value.", value: func(s *promSeries) float64 { return s.v1 }}},
+}
+
+// WritePrometheus writes the cumulative metrics in the Prometheus text format (or OpenMetrics).
+func (r *Runner) WritePrometheus(w io.Writer, openMetrics bool) {
+	p := r.prom.Load()
+	if p == nil {
+		if openMetrics {
+			_, _ = io.WriteString(w, "# EOF\n")
+		}
+		return
+	}
+
+	p.Lock()
+	byType := make(map[LogType][]promSeries)
+	for _, s := range p.series {
+		byType[s.logType] = append(byType[s.logType], *s)
+	}
+	p.Unlock()
+
+	for _, series := range byType {
+		sort.Slice(series, func(i, j int) bool { return series[i].labels < series[j].labels })
+	}
+
+	header := func(name, typ, help string) {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	if series := byType[KeyRT]; len(series) > 0 {
+		header("metrics_rt_milliseconds", "histogram", "Round-trip time in milliseconds.")
+		for _, s := range series {
+			below := s.v2 // the count of RT < 300ms is not recorded in v3-v9.
+			for _, b := range s.buckets {
+				below -= b
+			}
+			cumulative := below
+			for i, le := range rtBuckets {
+				_, _ = fmt.Fprintf(w, "metrics_rt_milliseconds_bucket{%s,le=\"%s\"} %s\n", s.labels, formatFloat(le), formatFloat(cumulative))
+				cumulative += s.buckets[i]
+			}
+			_, _ = fmt.Fprintf(w, "metrics_rt_milliseconds_bucket{%s,le=\"+Inf\"} %s\n", s.labels, formatFloat(s.v2))
+			_, _ = fmt.Fprintf(w, "metrics_rt_milliseconds_sum{%s} %s\n", s.labels, formatFloat(s.v1))
+			_, _ = fmt.Fprintf(w, "metrics_rt_milliseconds_count{%s} %s\n", s.labels, formatFloat(s.v2))
+		}
+	}
+
+	for _, logType := range []LogType{KeyQPS, KeySuccessRate, KeyFailRate, KeyHitRate, KeyCUR} {
+		series := byType[logType]
+		if len(series) == 0 {
+			continue
+		}
+
+		for _, f := range promFamilies[logType] {
+			family, sample := f.name, f.name
+			if f.typ == "counter" {
+				sample += "_total"
+				if !openMetrics { // the Prometheus text format names the counter family with the _total suffix.
+					family = sample
+				}
+			}
+			header(family, f.typ, f.help)
+
+			for _, s := range series {
+				_, _ = fmt.Fprintf(w, "%s{%s} %s\n", sample, s.labels, formatFloat(f.value(&s)))
+			}
+		}
+	}
+
+	r.writeStats(w, openMetrics)
+
+	if openMetrics {
+		_, _ = io.WriteString(w, "# EOF\n")
+	}
+}
+
+// writeStats writes the drop counters of the channel and sinks.
+func (r *Runner) writeStats(w io.Writer, openMetrics bool) {
+	app := escapeLabelValue(r.AppName)
+	stats := r.Stats()
+	family := "metrics_dropped_lines"
+	if !openMetrics {
+		family += "_total"
+	}
+	_, _ = fmt.Fprintf(w, "# HELP %s Lines dropped by the full channel or the failed sinks.\n# TYPE %s counter\n", family, family)
+	_, _ = fmt.Fprintf(w, "metrics_dropped_lines_total{app=\"%s\",sink=\"chan\"} %d\n", app, stats.Dropped)
+	for _, s := range stats.Sinks {
+		_, _ = fmt.Fprintf(w, "metrics_dropped_lines_total{app=\"%s\",sink=\"%s\"} %d\n", app, escapeLabelValue(s.Name), s.Dropped)
+	}
+}
+
+func formatFloat(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
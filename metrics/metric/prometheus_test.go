@@ -0,0 +1,65 @@
+package metric_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/ngg/metrics/metric"
+	"github.com/bingoohuang/ngg/metrics/pkg/ks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	r := metric.NewRunner(metric.AppName("promtest"), metric.LogPath(t.TempDir()))
+	r.Start()
+	defer r.Stop()
+
+	ts := httptest.NewServer(r.PrometheusHandler())
+	defer ts.Close()
+
+	now := time.Now()
+	r.RT("key1", "key2").Ks(ks.K4("a")).RecordSince(now.Add(-100 * time.Millisecond))
+	r.RT("key1", "key2").Ks(ks.K4("a")).RecordSince(now.Add(-450 * time.Millisecond))
+	r.RT("key1", "key2").Ks(ks.K4("a")).RecordSince(now.Add(-950 * time.Millisecond))
+	r.QPS1("key1")
+	r.QPS1("key1")
+	sr := r.SuccessRate("key1")
+	sr.IncrSuccess()
+	sr.IncrTotal()
+	sr.IncrTotal()
+
+	scrape := func(accept string) string {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+		req.Header.Set("Accept", accept)
+		rsp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		defer rsp.Body.Close()
+		body, _ := io.ReadAll(rsp.Body)
+		return string(body)
+	}
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(scrape(""), `metrics_success_calls_total{app="promtest",key="key1",k1="key1"} 2`)
+	}, 3*time.Second, 10*time.Millisecond)
+
+	text := scrape("")
+	labels := `app="promtest",key="key1#key2",k1="key1",k2="key2",k4="a"`
+	assert.Contains(t, text, "# TYPE metrics_rt_milliseconds histogram\n")
+	assert.Contains(t, text, `metrics_rt_milliseconds_bucket{`+labels+`,le="300"} 1`+"\n")
+	assert.Contains(t, text, `metrics_rt_milliseconds_bucket{`+labels+`,le="400"} 1`+"\n")
+	assert.Contains(t, text, `metrics_rt_milliseconds_bucket{`+labels+`,le="500"} 2`+"\n")
+	assert.Contains(t, text, `metrics_rt_milliseconds_bucket{`+labels+`,le="900"} 2`+"\n")
+	assert.Contains(t, text, `metrics_rt_milliseconds_bucket{`+labels+`,le="+Inf"} 3`+"\n")
+	assert.Contains(t, text, `metrics_rt_milliseconds_count{`+labels+`} 3`+"\n")
+	assert.Contains(t, text, "# TYPE metrics_qps_total counter\n")
+	assert.Contains(t, text, `metrics_qps_total{app="promtest",key="key1",k1="key1"} 2`+"\n")
+	assert.Contains(t, text, `metrics_success_total{app="promtest",key="key1",k1="key1"} 1`+"\n")
+
+	om := scrape("application/openmetrics-text; version=1.0.0")
+	assert.Contains(t, om, "# TYPE metrics_qps counter\n")
+	assert.True(t, strings.HasSuffix(om, "# EOF\n"))
+}
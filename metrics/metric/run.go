@@ -7,9 +7,12 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bingoohuang/ngg/metrics/pkg/rotate"
+	"github.com/bingoohuang/ngg/metrics/pkg/sketch"
 	"github.com/bingoohuang/ngg/metrics/pkg/util"
 )
 
@@ -46,6 +49,12 @@ type Runner struct {
 	HBInterval      time.Duration
 
 	autoDrop bool
+
+	prom     atomic.Pointer[promState]
+	promOnce sync.Once
+
+	sinks   []*sinkRunner
+	dropped atomic.Int64
 }
 
 type cacheKey struct {
@@ -98,9 +107,14 @@ func (r *Runner) Start() {
 	o := r.option
 	r.MetricsLogfile = createRotateFile(o, "metrics-key.")
 	r.HBLogfile = createRotateFile(o, "metrics-hb.")
+	r.createSinks()
 
 	go r.run()
 
+	if o.PromAddr != "" {
+		r.startPrometheus()
+	}
+
 	if r.option.Debug {
 		log.Printf("runner started")
 	}
@@ -134,6 +148,7 @@ func (r *Runner) run() {
 		case <-hbTicker.C:
 			r.logHB()
 		case <-r.stop:
+			r.stopSinks()
 			if r.option.Debug {
 				log.Printf("runner stopped")
 			}
@@ -143,12 +158,16 @@ func (r *Runner) run() {
 }
 
 func (r *Runner) logMetrics() {
-	if r.MetricsLogfile == nil {
+	if r.MetricsLogfile == nil && len(r.sinks) == 0 {
 		return
 	}
 
+	start := r.startTime
 	r.startTime = time.Now()
 
+	var lines []Line
+	defer func() { r.dispatch(start, lines) }()
+
 	for k, pv := range r.cache {
 		v := *pv
 		// 处理瞬间current > total的情况.
@@ -163,7 +182,8 @@ func (r *Runner) logMetrics() {
 			continue
 		}
 
-		r.writeLog(r.MetricsLogfile, v)
+		v.fillQuantiles()
+		lines = append(lines, r.writeLog(r.MetricsLogfile, v))
 
 		if v.LogType.isSimple() {
 			delete(r.cache, k)
@@ -178,15 +198,16 @@ func (r *Runner) logMetrics() {
 			pv.V8 -= v.V8
 			pv.V9 -= v.V9
 
-			// 重置 Min, Max
+			// 重置 Min, Max, 分位数草图
 			pv.Min = 0
 			pv.Max = 0
 			pv.N = 0
+			pv.sketch = nil
 		}
 	}
 }
 
-func (r *Runner) writeLog(file io.Writer, v Line) {
+func (r *Runner) writeLog(file io.Writer, v Line) Line {
 	v.Time = time.Now().Format(TimeLayout)
 	v.Hostname = util.Hostname
 	v.fulfilKeys()
@@ -196,7 +217,7 @@ func (r *Runner) writeLog(file io.Writer, v Line) {
 		log.Printf("LineProtocol: %s", s)
 	}
 	if file == nil {
-		return
+		return v
 	}
 
 	var obj any = v
@@ -215,9 +236,13 @@ func (r *Runner) writeLog(file io.Writer, v Line) {
 	if _, err := file.Write(content); err != nil {
 		log.Printf("W! fail to write log of metrics, error %+v", err)
 	}
+
+	return v
 }
 
 func (r *Runner) mergeLog(l *Line) {
+	r.exposeLine(l)
+
 	k := l.makeCacheKey()
 	if c, ok := r.cache[k]; ok {
 		if l.LogType.isSimple() { // 瞬值，直接更新日志
@@ -238,6 +263,8 @@ func (r *Runner) mergeLog(l *Line) {
 			minVal, maxVal := l.Min, l.Max
 			l.Min = Min(l.N, l.Min, l.V1)
 			l.Max = Max(l.N, l.Max, l.V1)
+			l.sketch = sketch.New(sketch.DefaultRelativeAccuracy)
+			l.sketch.Add(l.V1)
 			if r.option.Debug {
 				log.Printf("[%s][%s] n: %d, lastMin: %g, lastMax: %g, l.V1: %g, min: %g, max: %g",
 					l.LogType, l.Key, l.N, minVal, maxVal, l.V1, l.Min, l.Max)
@@ -250,15 +277,17 @@ func (r *Runner) mergeLog(l *Line) {
 }
 
 func (r *Runner) logHB() {
-	if r.HBLogfile == nil {
+	if r.HBLogfile == nil && len(r.sinks) == 0 {
 		return
 	}
 
-	r.writeLog(r.HBLogfile, Line{
+	now := time.Now()
+	l := r.writeLog(r.HBLogfile, Line{
 		Key:     r.AppName + ".hb",
 		LogType: HB,
 		V1:      1,
 	})
+	r.dispatch(now, []Line{l})
 }
 
 func (l *Line) updateMinMax(newLine *Line, r *Runner) {
@@ -275,6 +304,10 @@ func (l *Line) updateMinMax(newLine *Line, r *Runner) {
 	if l.LogType == KeyRT {
 		newMin = Min(l.N, l.Min, newLine.V1)
 		newMax = Max(l.N, l.Max, newLine.V1)
+		if l.sketch == nil {
+			l.sketch = sketch.New(sketch.DefaultRelativeAccuracy)
+		}
+		l.sketch.Add(newLine.V1)
 		if r.option.Debug {
 			log.Printf("[%s][%s] n: %d, lastMin: %g, lastMax: %g, n.V1: %g, min: %g, max: %g",
 				l.LogType, l.Key, l.N, l.Min, l.Max, newLine.V1, newMin, newMax)
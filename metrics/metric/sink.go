@@ -0,0 +1,233 @@
+package metric
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Batch is the lines written by the runner in one METRICS_INTERVAL (or one heartbeat).
+type Batch struct {
+	AppName string
+	Start   time.Time
+	End     time.Time
+	Lines   []Line
+}
+
+// Sink receives the metric lines written by the runner, like the metrics log files.
+type Sink interface {
+	// Name returns the name of the sink, used in the drop counters.
+	Name() string
+	// Write writes the batch to the backend, it will be retried when error returns.
+	Write(ctx context.Context, b *Batch) error
+	// Close closes the sink.
+	Close() error
+}
+
+// NewSink creates a Sink by the URL, the supported schemes:
+//
+//	influx+http://127.0.0.1:8086/write?db=metrics                    InfluxDB v1 line protocol over HTTP
+//	influx+http://127.0.0.1:8086/api/v2/write?org=o&bucket=b&token=t InfluxDB v2 line protocol over HTTP
+//	influx+udp://127.0.0.1:8089                                      InfluxDB line protocol over UDP
+//	statsd://127.0.0.1:8125?prefix=app&tags=dogstatsd                StatsD over UDP
+//	otlp+http://127.0.0.1:4318/v1/metrics                            OTLP/HTTP metrics in JSON encoding
+func NewSink(sinkURL string) (Sink, error) {
+	u, err := url.Parse(sinkURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse sink url %s: %w", sinkURL, err)
+	}
+
+	switch u.Scheme {
+	case "influx+http", "influx+https":
+		return NewInfluxHTTPSink(u), nil
+	case "influx+udp":
+		return NewInfluxUDPSink(u.Host)
+	case "statsd", "statsd+udp":
+		return NewStatsdSink(u.Host, u.Query().Get("prefix"), u.Query().Get("tags") == "dogstatsd")
+	case "otlp+http", "otlp+https":
+		return NewOTLPSink(strings.TrimPrefix(sinkURL, "otlp+")), nil
+	default:
+		return nil, fmt.Errorf("unknown sink scheme %s", u.Scheme)
+	}
+}
+
+// SinkStats is the statistics of a sink.
+type SinkStats struct {
+	Name    string
+	Sent    int64 // lines sent successfully
+	Dropped int64 // lines dropped because of the full buffer or the exhausted retries
+	Retries int64 // retries of the failed writes
+}
+
+// Stats is the statistics of the runner.
+type Stats struct {
+	// Dropped is the lines dropped by AUTO_DROP when the channel is full.
+	Dropped int64
+	Sinks   []SinkStats
+}
+
+// Stats returns the statistics of the runner.
+func (r *Runner) Stats() Stats {
+	s := Stats{Dropped: r.dropped.Load()}
+	for _, sr := range r.sinks {
+		s.Sinks = append(s.Sinks, SinkStats{
+			Name:    sr.Name(),
+			Sent:    sr.sent.Load(),
+			Dropped: sr.dropped.Load(),
+			Retries: sr.retries.Load(),
+		})
+	}
+
+	return s
+}
+
+// sinkRunner buffers the batches for a sink, and writes them in its own goroutine with retries,
+// so a slow or broken backend never blocks the runner.
+type sinkRunner struct {
+	Sink
+
+	C          chan *Batch
+	maxRetries int
+	backoff    time.Duration
+	wg         sync.WaitGroup
+
+	// ctx is canceled when stopping takes too long, to abort the pending writes and retries.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	sent, dropped, retries atomic.Int64
+}
+
+func newSinkRunner(s Sink, o *Option) *sinkRunner {
+	sr := &sinkRunner{
+		Sink:       s,
+		C:          make(chan *Batch, o.SinkBuffer),
+		maxRetries: o.SinkRetries,
+		backoff:    time.Second,
+	}
+	sr.ctx, sr.cancel = context.WithCancel(context.Background())
+	return sr
+}
+
+func (s *sinkRunner) start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for b := range s.C {
+			s.write(b)
+		}
+	}()
+}
+
+// put puts the batch into the buffer, or drops it when the buffer is full.
+func (s *sinkRunner) put(b *Batch) {
+	select {
+	case s.C <- b:
+	default:
+		s.dropped.Add(int64(len(b.Lines)))
+	}
+}
+
+func (s *sinkRunner) write(b *Batch) {
+	backoff := s.backoff
+	for i := 0; ; i++ {
+		ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+		err := s.Write(ctx, b)
+		cancel()
+		if err == nil {
+			s.sent.Add(int64(len(b.Lines)))
+			return
+		}
+
+		if i >= s.maxRetries || s.ctx.Err() != nil {
+			log.Printf("W! sink %s write failed, %d lines dropped, error %v", s.Name(), len(b.Lines), err)
+			s.dropped.Add(int64(len(b.Lines)))
+			return
+		}
+
+		s.retries.Add(1)
+		select {
+		case <-time.After(backoff):
+		case <-s.ctx.Done():
+		}
+		backoff *= 2
+	}
+}
+
+// sinkStopTimeout is the max time to flush the buffered batches when stopping.
+const sinkStopTimeout = 5 * time.Second
+
+// stop flushes the buffered batches in sinkStopTimeout, drops the left ones, and closes the sink.
+func (s *sinkRunner) stop() {
+	close(s.C)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(sinkStopTimeout):
+		s.cancel()
+		<-done
+	}
+	s.cancel()
+
+	if err := s.Close(); err != nil {
+		log.Printf("W! close sink %s error %v", s.Name(), err)
+	}
+}
+
+func (r *Runner) dispatch(start time.Time, lines []Line) {
+	if len(lines) == 0 || len(r.sinks) == 0 {
+		return
+	}
+
+	b := &Batch{AppName: r.AppName, Start: start, End: time.Now(), Lines: lines}
+	for _, s := range r.sinks {
+		s.put(b)
+	}
+}
+
+func (r *Runner) createSinks() {
+	o := r.option
+	sinks := append([]Sink{}, o.sinks...)
+	for _, sinkURL := range strings.Split(o.Sinks, ",") {
+		if sinkURL = strings.TrimSpace(sinkURL); sinkURL == "" {
+			continue
+		}
+
+		s, err := NewSink(sinkURL)
+		if err != nil {
+			log.Printf("W! create sink error %v", err)
+			continue
+		}
+		sinks = append(sinks, s)
+	}
+
+	for _, s := range sinks {
+		sr := newSinkRunner(s, o)
+		sr.start()
+		r.sinks = append(r.sinks, sr)
+	}
+}
+
+// stopSinks stops the sinks concurrently, so it takes sinkStopTimeout at most.
+func (r *Runner) stopSinks() {
+	var wg sync.WaitGroup
+	for _, s := range r.sinks {
+		wg.Add(1)
+		go func(s *sinkRunner) {
+			defer wg.Done()
+			s.stop()
+		}(s)
+	}
+	wg.Wait()
+}
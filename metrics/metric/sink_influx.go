@@ -0,0 +1,144 @@
+package metric
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// InfluxHTTPSink writes the lines in the InfluxDB line protocol over HTTP.
+type InfluxHTTPSink struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// NewInfluxHTTPSink creates an InfluxHTTPSink, the token query parameter is sent as the Authorization header.
+func NewInfluxHTTPSink(u *url.URL) *InfluxHTTPSink {
+	c := *u
+	c.Scheme = strings.TrimPrefix(c.Scheme, "influx+")
+	q := c.Query()
+	token := q.Get("token")
+	q.Del("token")
+	c.RawQuery = q.Encode()
+
+	return &InfluxHTTPSink{URL: c.String(), Token: token, Client: &http.Client{}}
+}
+
+// Name returns the name of the sink.
+func (s *InfluxHTTPSink) Name() string { return "influx+http" }
+
+// Write writes the batch.
+func (s *InfluxHTTPSink) Write(ctx context.Context, b *Batch) error {
+	body, err := influxLines(b)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Token "+s.Token)
+	}
+
+	rsp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+		return fmt.Errorf("influx write status %d: %s", rsp.StatusCode, msg)
+	}
+
+	return nil
+}
+
+// Close closes the sink.
+func (s *InfluxHTTPSink) Close() error { return nil }
+
+// InfluxUDPSink writes the lines in the InfluxDB line protocol over UDP.
+type InfluxUDPSink struct{ conn net.Conn }
+
+// NewInfluxUDPSink creates an InfluxUDPSink.
+func NewInfluxUDPSink(addr string) (*InfluxUDPSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial udp %s: %w", addr, err)
+	}
+
+	return &InfluxUDPSink{conn: conn}, nil
+}
+
+// Name returns the name of the sink.
+func (s *InfluxUDPSink) Name() string { return "influx+udp" }
+
+// Write writes the batch.
+func (s *InfluxUDPSink) Write(_ context.Context, b *Batch) error {
+	var lines []string
+	for _, l := range b.Lines {
+		line, err := l.ToLineProtocol()
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line)
+	}
+
+	return writePackets(s.conn, lines)
+}
+
+// Close closes the sink.
+func (s *InfluxUDPSink) Close() error { return s.conn.Close() }
+
+func influxLines(b *Batch) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, l := range b.Lines {
+		line, err := l.ToLineProtocol()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+// maxPacketSize keeps the UDP packets within the usual MTU.
+const maxPacketSize = 1432
+
+// writePackets writes the lines in UDP packets separated by newlines.
+func writePackets(conn net.Conn, lines []string) error {
+	var buf bytes.Buffer
+	flush := func() error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		_, err := conn.Write(buf.Bytes())
+		buf.Reset()
+		return err
+	}
+
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > maxPacketSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+
+	return flush()
+}
@@ -0,0 +1,218 @@
+package metric
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/bingoohuang/ngg/metrics/pkg/util"
+)
+
+// OTLPSink pushes the lines as OTLP/HTTP metrics in the JSON encoding,
+// RT as delta histograms with the v3-v9 bucket boundaries, rates as delta sums and CUR as gauges.
+type OTLPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewOTLPSink creates an OTLPSink, url like http://127.0.0.1:4318/v1/metrics.
+func NewOTLPSink(url string) *OTLPSink {
+	return &OTLPSink{URL: url, Client: &http.Client{}}
+}
+
+// Name returns the name of the sink.
+func (s *OTLPSink) Name() string { return "otlp" }
+
+// Close closes the sink.
+func (s *OTLPSink) Close() error { return nil }
+
+// Write writes the batch.
+func (s *OTLPSink) Write(ctx context.Context, b *Batch) error {
+	body, err := json.Marshal(otlpRequest(b))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+		return fmt.Errorf("otlp export status %d: %s", rsp.StatusCode, msg)
+	}
+
+	return nil
+}
+
+// The OTLP JSON structures, see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	AsDouble          *float64       `json:"asDouble,omitempty"`
+
+	// histogram only
+	Count          string    `json:"count,omitempty"`
+	Sum            *float64  `json:"sum,omitempty"`
+	BucketCounts   []string  `json:"bucketCounts,omitempty"`
+	ExplicitBounds []float64 `json:"explicitBounds,omitempty"`
+	Min            *float64  `json:"min,omitempty"`
+	Max            *float64  `json:"max,omitempty"`
+}
+
+type otlpData struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality,omitempty"`
+	IsMonotonic            bool            `json:"isMonotonic,omitempty"`
+}
+
+type otlpMetric struct {
+	Name      string    `json:"name"`
+	Unit      string    `json:"unit,omitempty"`
+	Gauge     *otlpData `json:"gauge,omitempty"`
+	Sum       *otlpData `json:"sum,omitempty"`
+	Histogram *otlpData `json:"histogram,omitempty"`
+}
+
+// otlpTemporalityDelta is AGGREGATION_TEMPORALITY_DELTA, the runner resets the values every interval.
+const otlpTemporalityDelta = 1
+
+func otlpString(v string) map[string]any { return map[string]any{"stringValue": v} }
+
+func otlpRequest(b *Batch) map[string]any {
+	start := strconv.FormatInt(b.Start.UnixNano(), 10)
+	end := strconv.FormatInt(b.End.UnixNano(), 10)
+
+	metrics := map[string]*otlpMetric{}
+	var names []string
+	add := func(name, unit, kind string, dp otlpDataPoint) {
+		m, ok := metrics[name]
+		if !ok {
+			m = &otlpMetric{Name: name, Unit: unit}
+			data := &otlpData{}
+			switch kind {
+			case "gauge":
+				m.Gauge = data
+			case "sum":
+				data.AggregationTemporality, data.IsMonotonic = otlpTemporalityDelta, true
+				m.Sum = data
+			case "histogram":
+				data.AggregationTemporality = otlpTemporalityDelta
+				m.Histogram = data
+			}
+			metrics[name] = m
+			names = append(names, name)
+		}
+
+		data := m.Gauge
+		if data == nil {
+			data = m.Sum
+		}
+		if data == nil {
+			data = m.Histogram
+		}
+		data.DataPoints = append(data.DataPoints, dp)
+	}
+
+	for _, l := range b.Lines {
+		attrs := otlpAttributes(l)
+		point := func(v float64) otlpDataPoint {
+			return otlpDataPoint{Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: end, AsDouble: &v}
+		}
+
+		switch l.LogType {
+		case KeyRT:
+			add("metrics.rt", "ms", "histogram", otlpHistogram(l, attrs, start, end))
+		case KeyQPS:
+			add("metrics.qps", "1", "sum", point(l.V1))
+		case KeySuccessRate:
+			add("metrics.success", "1", "sum", point(l.V1))
+			add("metrics.success.calls", "1", "sum", point(l.V2))
+		case KeyFailRate:
+			add("metrics.fail", "1", "sum", point(l.V1))
+			add("metrics.fail.calls", "1", "sum", point(l.V2))
+		case KeyHitRate:
+			add("metrics.hit", "1", "sum", point(l.V1))
+			add("metrics.hit.calls", "1", "sum", point(l.V2))
+		case KeyCUR:
+			dp := point(l.V1)
+			dp.StartTimeUnixNano = ""
+			add("metrics.cur", "", "gauge", dp)
+		case HB:
+			add("metrics.hb", "1", "sum", point(l.V1))
+		}
+	}
+
+	list := make([]*otlpMetric, 0, len(names))
+	for _, name := range names {
+		list = append(list, metrics[name])
+	}
+
+	return map[string]any{
+		"resourceMetrics": []any{map[string]any{
+			"resource": map[string]any{"attributes": []otlpKeyValue{
+				{Key: "service.name", Value: otlpString(b.AppName)},
+				{Key: "host.name", Value: otlpString(util.Hostname)},
+			}},
+			"scopeMetrics": []any{map[string]any{
+				"scope":   map[string]any{"name": "github.com/bingoohuang/ngg/metrics"},
+				"metrics": list,
+			}},
+		}},
+	}
+}
+
+func otlpAttributes(l Line) []otlpKeyValue {
+	attrs := []otlpKeyValue{{Key: "key", Value: otlpString(l.Key)}}
+	for _, kl := range l.keyLabels() {
+		attrs = append(attrs, otlpKeyValue{Key: kl.Name, Value: otlpString(kl.Value)})
+	}
+
+	return attrs
+}
+
+func otlpHistogram(l Line, attrs []otlpKeyValue, start, end string) otlpDataPoint {
+	buckets := []float64{l.V3, l.V4, l.V5, l.V6, l.V7, l.V8, l.V9}
+	below := l.V2 // the count of RT < 300ms
+	for _, b := range buckets {
+		below -= b
+	}
+
+	// explicit bounds 300, 400, ..., 900 make 8 buckets: (-∞,300], (300,400], ..., (900,+∞)
+	counts := []string{strconv.FormatFloat(below, 'f', 0, 64)}
+	for _, b := range buckets {
+		counts = append(counts, strconv.FormatFloat(b, 'f', 0, 64))
+	}
+
+	sum, minVal, maxVal := l.V1, l.Min, l.Max
+	return otlpDataPoint{
+		Attributes:        attrs,
+		StartTimeUnixNano: start,
+		TimeUnixNano:      end,
+		Count:             strconv.FormatFloat(l.V2, 'f', 0, 64),
+		Sum:               &sum,
+		BucketCounts:      counts,
+		ExplicitBounds:    rtBuckets,
+		Min:               &minVal,
+		Max:               &maxVal,
+	}
+}
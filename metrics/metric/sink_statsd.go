@@ -0,0 +1,94 @@
+package metric
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// StatsdSink writes the lines as StatsD metrics over UDP.
+// The aggregated values are sent as counters (c) and gauges (g), since the samples are already aggregated.
+type StatsdSink struct {
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+}
+
+// NewStatsdSink creates a StatsdSink, the extra keys (k4-k20) are sent as DogStatsD tags when dogstatsd is true.
+func NewStatsdSink(addr, prefix string, dogstatsd bool) (*StatsdSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial udp %s: %w", addr, err)
+	}
+
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+
+	return &StatsdSink{conn: conn, prefix: prefix, dogstatsd: dogstatsd}, nil
+}
+
+// Name returns the name of the sink.
+func (s *StatsdSink) Name() string { return "statsd" }
+
+var statsdReplacer = strings.NewReplacer("#", ".", ":", "_", "|", "_", "@", "_", " ", "_")
+
+// Write writes the batch.
+func (s *StatsdSink) Write(_ context.Context, b *Batch) error {
+	var lines []string
+	for _, l := range b.Lines {
+		name := s.prefix + statsdReplacer.Replace(l.Key)
+		tags := s.tags(l)
+		add := func(suffix string, v float64, typ string) {
+			lines = append(lines, fmt.Sprintf("%s%s:%s|%s%s", name, suffix, formatFloat(v), typ, tags))
+		}
+
+		switch l.LogType {
+		case KeyRT:
+			add(".rt.sum", l.V1, "c")
+			add(".rt.count", l.V2, "c")
+			add(".rt.min", l.Min, "g")
+			add(".rt.max", l.Max, "g")
+			if l.V2 > 0 {
+				add(".rt.avg", l.V1/l.V2, "g")
+			}
+		case KeyQPS:
+			add(".qps", l.V1, "c")
+		case KeySuccessRate:
+			add(".success", l.V1, "c")
+			add(".success.calls", l.V2, "c")
+		case KeyFailRate:
+			add(".fail", l.V1, "c")
+			add(".fail.calls", l.V2, "c")
+		case KeyHitRate:
+			add(".hit", l.V1, "c")
+			add(".hit.calls", l.V2, "c")
+		case KeyCUR:
+			add(".cur", l.V1, "g")
+		case HB:
+			add("", l.V1, "c")
+		}
+	}
+
+	return writePackets(s.conn, lines)
+}
+
+func (s *StatsdSink) tags(l Line) string {
+	if !s.dogstatsd {
+		return ""
+	}
+
+	var tags []string
+	for _, kl := range l.keyLabels() {
+		tags = append(tags, kl.Name+":"+statsdReplacer.Replace(kl.Value))
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+
+	return "|#" + strings.Join(tags, ",")
+}
+
+// Close closes the sink.
+func (s *StatsdSink) Close() error { return s.conn.Close() }
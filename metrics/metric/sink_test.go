@@ -0,0 +1,120 @@
+package metric_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/ngg/metrics/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+type bodies struct {
+	sync.Mutex
+	all []string
+}
+
+func (b *bodies) add(s string) {
+	b.Lock()
+	defer b.Unlock()
+	b.all = append(b.all, s)
+}
+
+func (b *bodies) contains(s string) bool {
+	b.Lock()
+	defer b.Unlock()
+	for _, body := range b.all {
+		if strings.Contains(body, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func recordServer(t *testing.T, b *bodies, header *atomic.Value) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		b.add(string(body))
+		if header != nil {
+			header.Store(r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// flakySink fails at the first write.
+type flakySink struct{ calls atomic.Int32 }
+
+func (s *flakySink) Name() string { return "flaky" }
+func (s *flakySink) Close() error { return nil }
+func (s *flakySink) Write(context.Context, *metric.Batch) error {
+	if s.calls.Add(1) == 1 {
+		return errors.New("temporary error")
+	}
+	return nil
+}
+
+func TestSinks(t *testing.T) {
+	var influx, otlp bodies
+	var auth atomic.Value
+	influxServer := recordServer(t, &influx, &auth)
+	otlpServer := recordServer(t, &otlp, nil)
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer pc.Close()
+	var statsd bodies
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			statsd.add(string(buf[:n]))
+		}
+	}()
+
+	influxSink, err := metric.NewSink("influx+" + influxServer.URL + "/write?db=metrics&token=abc")
+	assert.Nil(t, err)
+	statsdSink, err := metric.NewSink("statsd://" + pc.LocalAddr().String() + "?prefix=app")
+	assert.Nil(t, err)
+	otlpSink, err := metric.NewSink("otlp+" + otlpServer.URL + "/v1/metrics")
+	assert.Nil(t, err)
+	flaky := &flakySink{}
+
+	r := metric.NewRunner(metric.AppName("sinktest"), metric.LogPath(t.TempDir()),
+		metric.MetricsInterval(50*time.Millisecond),
+		metric.WithSinks(influxSink, statsdSink, otlpSink, flaky))
+	r.Start()
+	defer r.Stop()
+
+	r.QPS("key1", "key2").Record(3)
+	r.RT("key1").RecordSince(time.Now().Add(-450 * time.Millisecond))
+
+	assert.Eventually(t, func() bool {
+		return influx.contains("QPS,") && influx.contains("RT,") &&
+			statsd.contains("app.key1.key2.qps:3|c") && statsd.contains("app.key1.rt.count:1|c") &&
+			otlp.contains(`"name":"metrics.qps"`) && otlp.contains(`"name":"metrics.rt"`)
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, "Token abc", auth.Load())
+
+	assert.Eventually(t, func() bool {
+		for _, s := range r.Stats().Sinks {
+			if s.Name == "flaky" {
+				return s.Retries == 1 && s.Sent > 0 && s.Dropped == 0
+			}
+		}
+		return false
+	}, 5*time.Second, 20*time.Millisecond)
+}
@@ -0,0 +1,283 @@
+// Package sketch implements a mergeable quantile sketch with relative accuracy guarantees,
+// based on DDSketch (https://arxiv.org/abs/1908.10693).
+package sketch
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultRelativeAccuracy is the default relative accuracy of the quantiles, 1%.
+const DefaultRelativeAccuracy = 0.01
+
+// minIndexableValue is the minimal positive value to be indexed, the smaller values are counted as zeros.
+const minIndexableValue = 1e-9
+
+// Sketch is a DDSketch for the non-negative values, like round-trip times.
+// Any quantile q is returned with the relative error of at most RelativeAccuracy.
+// Sketches with the same RelativeAccuracy can be merged, e.g. across intervals and hosts.
+// Sketch is not safe for concurrent use.
+type Sketch struct {
+	relativeAccuracy float64
+	gamma            float64
+	logGamma         float64
+
+	bins  map[int]uint64
+	zeros uint64 // count of the values less than minIndexableValue
+
+	count         uint64
+	sum, min, max float64
+}
+
+// New creates a Sketch with the relative accuracy in (0, 1), e.g. 0.01 for 1%.
+func New(relativeAccuracy float64) *Sketch {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		relativeAccuracy = DefaultRelativeAccuracy
+	}
+
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &Sketch{
+		relativeAccuracy: relativeAccuracy,
+		gamma:            gamma,
+		logGamma:         math.Log(gamma),
+		bins:             make(map[int]uint64),
+	}
+}
+
+// RelativeAccuracy returns the relative accuracy of the sketch.
+func (s *Sketch) RelativeAccuracy() float64 { return s.relativeAccuracy }
+
+// Count returns the count of the added values.
+func (s *Sketch) Count() uint64 { return s.count }
+
+// Sum returns the sum of the added values.
+func (s *Sketch) Sum() float64 { return s.sum }
+
+// Min returns the minimal added value.
+func (s *Sketch) Min() float64 { return s.min }
+
+// Max returns the maximal added value.
+func (s *Sketch) Max() float64 { return s.max }
+
+// Add adds a value, the negative values are counted as zeros.
+func (s *Sketch) Add(v float64) { s.AddN(v, 1) }
+
+// AddN adds a value n times.
+func (s *Sketch) AddN(v float64, n uint64) {
+	if n == 0 || math.IsNaN(v) {
+		return
+	}
+	if v < 0 {
+		v = 0
+	}
+
+	if v < minIndexableValue {
+		s.zeros += n
+	} else {
+		s.bins[s.index(v)] += n
+	}
+
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	s.count += n
+	s.sum += v * float64(n)
+}
+
+func (s *Sketch) index(v float64) int { return int(math.Ceil(math.Log(v) / s.logGamma)) }
+
+// value returns the representative value of the bin, which is within the relative accuracy of all values in the bin.
+func (s *Sketch) value(index int) float64 {
+	return 2 * math.Pow(s.gamma, float64(index)) / (1 + s.gamma)
+}
+
+// Merge merges the other sketch into s.
+func (s *Sketch) Merge(o *Sketch) error {
+	if o == nil {
+		return nil
+	}
+	if s.relativeAccuracy != o.relativeAccuracy {
+		return fmt.Errorf("can not merge sketches with different relative accuracy %g and %g",
+			s.relativeAccuracy, o.relativeAccuracy)
+	}
+	if o.count == 0 {
+		return nil
+	}
+
+	for i, n := range o.bins {
+		s.bins[i] += n
+	}
+	s.zeros += o.zeros
+
+	if s.count == 0 || o.min < s.min {
+		s.min = o.min
+	}
+	if s.count == 0 || o.max > s.max {
+		s.max = o.max
+	}
+	s.count += o.count
+	s.sum += o.sum
+
+	return nil
+}
+
+// Quantile returns the approximate value at the quantile q in [0, 1], 0 when the sketch is empty.
+func (s *Sketch) Quantile(q float64) float64 {
+	if s.count == 0 || q < 0 || q > 1 {
+		return 0
+	}
+	if q == 0 {
+		return s.min
+	}
+	if q == 1 {
+		return s.max
+	}
+
+	rank := q * float64(s.count-1)
+	cumulative := float64(s.zeros)
+	if cumulative > rank {
+		return 0
+	}
+
+	indexes := make([]int, 0, len(s.bins))
+	for i := range s.bins {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	for _, i := range indexes {
+		if cumulative += float64(s.bins[i]); cumulative > rank {
+			return math.Max(s.min, math.Min(s.max, s.value(i)))
+		}
+	}
+
+	return s.max
+}
+
+const encodingVersion = 1
+
+// MarshalBinary encodes the sketch in a compact binary format.
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	indexes := make([]int, 0, len(s.bins))
+	for i := range s.bins {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	buf := make([]byte, 0, 1+8*4+binary.MaxVarintLen64*(3+2*len(indexes)))
+	buf = append(buf, encodingVersion)
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.relativeAccuracy))
+	buf = binary.AppendUvarint(buf, s.count)
+	buf = binary.AppendUvarint(buf, s.zeros)
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.sum))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.min))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.max))
+	buf = binary.AppendUvarint(buf, uint64(len(indexes)))
+
+	last := 0
+	for _, i := range indexes {
+		buf = binary.AppendVarint(buf, int64(i-last)) // delta encoding of the sorted indexes
+		buf = binary.AppendUvarint(buf, s.bins[i])
+		last = i
+	}
+
+	return buf, nil
+}
+
+// ErrInvalidEncoding is returned when decoding a malformed sketch.
+var ErrInvalidEncoding = errors.New("invalid sketch encoding")
+
+// UnmarshalBinary decodes the sketch encoded by MarshalBinary.
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != encodingVersion {
+		return ErrInvalidEncoding
+	}
+
+	d := &decoder{data: data[1:]}
+	*s = *New(d.float())
+	s.count = d.uvarint()
+	s.zeros = d.uvarint()
+	s.sum = d.float()
+	s.min = d.float()
+	s.max = d.float()
+
+	index := 0
+	for j, bins := uint64(0), d.uvarint(); j < bins && d.err == nil; j++ {
+		index += int(d.varint())
+		s.bins[index] = d.uvarint()
+	}
+
+	return d.err
+}
+
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) float() float64 {
+	if d.err != nil || len(d.data) < 8 {
+		d.err = ErrInvalidEncoding
+		return 0
+	}
+
+	f := math.Float64frombits(binary.LittleEndian.Uint64(d.data))
+	d.data = d.data[8:]
+	return f
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = ErrInvalidEncoding
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.err = ErrInvalidEncoding
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+// String encodes the sketch in base64, used in the metrics log lines.
+func (s *Sketch) String() string {
+	data, _ := s.MarshalBinary()
+	return base64.RawStdEncoding.EncodeToString(data)
+}
+
+// Parse parses the sketch encoded by String.
+func Parse(encoded string) (*Sketch, error) {
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode sketch: %w", err)
+	}
+
+	s := &Sketch{}
+	if err := s.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,61 @@
+package sketch_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/bingoohuang/ngg/metrics/pkg/sketch"
+	"github.com/stretchr/testify/assert"
+)
+
+func exactQuantile(sorted []float64, q float64) float64 {
+	return sorted[int(q*float64(len(sorted)-1))]
+}
+
+func TestSketch(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	a, b := sketch.New(0.01), sketch.New(0.01)
+	var all []float64
+	for i := 0; i < 20000; i++ {
+		v := r.ExpFloat64() * 100 // long tail latencies in ms
+		all = append(all, v)
+		if i%2 == 0 {
+			a.Add(v)
+		} else {
+			b.Add(v)
+		}
+	}
+	sort.Float64s(all)
+
+	assert.Nil(t, a.Merge(b))
+	assert.Equal(t, uint64(len(all)), a.Count())
+	assert.Equal(t, all[0], a.Min())
+	assert.Equal(t, all[len(all)-1], a.Max())
+
+	for _, q := range []float64{0.5, 0.9, 0.99, 0.999} {
+		exact, got := exactQuantile(all, q), a.Quantile(q)
+		assert.LessOrEqual(t, math.Abs(got-exact)/exact, 0.01+1e-9, "q=%g exact=%g got=%g", q, exact, got)
+	}
+
+	decoded, err := sketch.Parse(a.String())
+	assert.Nil(t, err)
+	assert.Equal(t, a.Count(), decoded.Count())
+	assert.InDelta(t, a.Sum(), decoded.Sum(), 1e-6)
+	assert.Equal(t, a.Quantile(0.99), decoded.Quantile(0.99))
+
+	assert.NotNil(t, a.Merge(sketch.New(0.02)))
+	_, err = sketch.Parse("AQ")
+	assert.NotNil(t, err)
+}
+
+func TestSketchZeros(t *testing.T) {
+	s := sketch.New(0.01)
+	assert.Equal(t, 0.0, s.Quantile(0.5))
+	s.Add(0)
+	s.Add(0)
+	s.Add(10)
+	assert.Equal(t, 0.0, s.Quantile(0.5))
+	assert.InDelta(t, 10, s.Quantile(1), 1e-9)
+}
@@ -0,0 +1,444 @@
+package mtls
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Authority is a small offline CA which keeps its files in a directory:
+// ca.crt/ca.key for the root, <name>.crt/<name>.key for the issued certs,
+// index.json for the issued serials and ca.crl for the revocation list.
+type Authority struct {
+	Dir string
+	*KeyPair
+
+	index *Index
+}
+
+// Cert status in the index.
+const (
+	StatusValid      = "valid"
+	StatusRevoked    = "revoked"
+	StatusSuperseded = "superseded"
+)
+
+// Index keeps the issued certs of the CA, like the index.txt of openssl ca.
+type Index struct {
+	CRLNumber int64         `json:"crlNumber"`
+	Certs     []*IndexEntry `json:"certs"`
+}
+
+// IndexEntry is an issued cert in the index.
+type IndexEntry struct {
+	Serial    string    `json:"serial"` // in hex
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	RevokedAt time.Time `json:"revokedAt,omitempty"`
+	Reason    int       `json:"reason,omitempty"`
+
+	IssueOptions
+}
+
+// IssueOptions defines the cert to issue.
+type IssueOptions struct {
+	// Name is the base name of the cert and key files, default the CommonName.
+	Name       string   `json:"-"`
+	CommonName string   `json:"commonName"`
+	DNSNames   []string `json:"dnsNames,omitempty"`
+	IPs        []string `json:"ips,omitempty"`
+	KeyAlgo    KeyAlgo  `json:"keyAlgo"`
+	// Validity is the valid duration since now, default 1 year.
+	Validity time.Duration `json:"validity"`
+	// Server and Client set the extended key usages, both are set if neither is set.
+	Server bool `json:"server,omitempty"`
+	Client bool `json:"client,omitempty"`
+	// Force reissues the cert of an existing name, the previous one is marked as superseded.
+	Force bool `json:"-"`
+}
+
+// reservedNames are the names of the CA's own files, which can not be used as the cert name.
+var reservedNames = map[string]bool{"ca": true, "index": true}
+
+// validateName checks the cert name is a plain file base name in the CA dir.
+func validateName(name string) error {
+	if name == "." || strings.Contains(name, "..") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid cert name %q, path separators and .. are not allowed", name)
+	}
+	if reservedNames[strings.ToLower(name)] {
+		return fmt.Errorf("cert name %q is reserved by the CA", name)
+	}
+
+	return nil
+}
+
+// InitAuthority creates the CA root cert and key in dir if they do not exist, and opens it.
+func InitAuthority(dir, commonName string, algo KeyAlgo, validity time.Duration) (*Authority, error) {
+	crtFile, keyFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key")
+	if fileExists(crtFile) && fileExists(keyFile) {
+		return OpenAuthority(dir)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("mkdir %s: %w", dir, err)
+	}
+
+	key, err := GenerateKey(algo)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := randSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName, Organization: []string{commonName}},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(validity),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("create the CA certificate: %w", err)
+	}
+
+	if err := writeKeyPair(crtFile, keyFile, der, key); err != nil {
+		return nil, err
+	}
+
+	return OpenAuthority(dir)
+}
+
+// OpenAuthority opens the CA in the dir.
+func OpenAuthority(dir string) (*Authority, error) {
+	crtFile, keyFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key")
+	kp, err := ReadCertificateAuthority(crtFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	kp.FilePair = FilePair{CertFile: crtFile, KeyFile: keyFile}
+
+	a := &Authority{Dir: dir, KeyPair: kp, index: &Index{}}
+	data, err := os.ReadFile(a.indexFile())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read index: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, a.index); err != nil {
+			return nil, fmt.Errorf("parse index: %w", err)
+		}
+	}
+
+	return a, nil
+}
+
+func (a *Authority) indexFile() string { return filepath.Join(a.Dir, "index.json") }
+
+// CRLFile returns the path of the CRL file.
+func (a *Authority) CRLFile() string { return filepath.Join(a.Dir, "ca.crl") }
+
+func (a *Authority) saveIndex() error {
+	data, err := json.MarshalIndent(a.index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(a.indexFile(), data, 0o600)
+}
+
+// Certs returns the issued certs in the index.
+func (a *Authority) Certs() []*IndexEntry { return a.index.Certs }
+
+// Issue issues a cert signed by the CA, and records it in the index.
+func (a *Authority) Issue(opts IssueOptions) (*IndexEntry, error) {
+	if opts.CommonName == "" {
+		return nil, errors.New("common name is required")
+	}
+	if opts.Name == "" {
+		opts.Name = opts.CommonName
+	}
+	if err := validateName(opts.Name); err != nil {
+		return nil, err
+	}
+
+	var previous *IndexEntry
+	for _, e := range a.index.Certs {
+		if e.Status == StatusValid && e.Name == opts.Name {
+			previous = e
+		}
+	}
+	if previous != nil && !opts.Force {
+		return nil, fmt.Errorf("cert %s already exists with serial %s, force to reissue it", opts.Name, previous.Serial)
+	}
+	if opts.KeyAlgo == "" {
+		opts.KeyAlgo = KeyECDSAP256
+	}
+	if opts.Validity <= 0 {
+		opts.Validity = 365 * 24 * time.Hour
+	}
+	if !opts.Server && !opts.Client {
+		opts.Server, opts.Client = true, true
+	}
+
+	var ips []net.IP
+	for _, s := range opts.IPs {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP SAN %s", s)
+		}
+		ips = append(ips, ip)
+	}
+
+	key, err := GenerateKey(opts.KeyAlgo)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := randSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	var extKeyUsage []x509.ExtKeyUsage
+	if opts.Server {
+		extKeyUsage = append(extKeyUsage, x509.ExtKeyUsageServerAuth)
+	}
+	if opts.Client {
+		extKeyUsage = append(extKeyUsage, x509.ExtKeyUsageClientAuth)
+	}
+
+	keyUsage := x509.KeyUsageDigitalSignature
+	if KeyAlgoOf(key.Public()) == KeyRSA2048 || KeyAlgoOf(key.Public()) == KeyRSA4096 {
+		// RSA key exchange needs the key encipherment usage.
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: opts.CommonName},
+		DNSNames:     opts.DNSNames,
+		IPAddresses:  ips,
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(opts.Validity),
+		KeyUsage:     keyUsage,
+		ExtKeyUsage:  extKeyUsage,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.Cert, key.Public(), a.Key)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate: %w", err)
+	}
+
+	crtFile, keyFile := filepath.Join(a.Dir, opts.Name+".crt"), filepath.Join(a.Dir, opts.Name+".key")
+	if err := writeKeyPair(crtFile, keyFile, der, key); err != nil {
+		return nil, err
+	}
+
+	if previous != nil {
+		previous.Status = StatusSuperseded
+	}
+
+	e := &IndexEntry{
+		Serial:       serial.Text(16),
+		Name:         opts.Name,
+		Status:       StatusValid,
+		NotBefore:    tmpl.NotBefore,
+		NotAfter:     tmpl.NotAfter,
+		IssueOptions: opts,
+	}
+	a.index.Certs = append(a.index.Certs, e)
+
+	return e, a.saveIndex()
+}
+
+// Find finds the valid cert by serial (hex) or name.
+func (a *Authority) Find(serialOrName string) *IndexEntry {
+	serialOrName = strings.ToLower(strings.TrimPrefix(serialOrName, "0x"))
+	for _, e := range a.index.Certs {
+		if e.Status == StatusValid && (e.Serial == serialOrName || e.Name == serialOrName) {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// Revoke revokes the cert by serial (hex) or name with the reason code (RFC 5280 5.3.1), and regenerates the CRL.
+func (a *Authority) Revoke(serialOrName string, reason int) (*IndexEntry, error) {
+	e := a.Find(serialOrName)
+	if e == nil {
+		return nil, fmt.Errorf("no valid cert found by %s", serialOrName)
+	}
+
+	e.Status = StatusRevoked
+	e.RevokedAt = time.Now()
+	e.Reason = reason
+	if err := a.saveIndex(); err != nil {
+		return nil, err
+	}
+
+	_, err := a.GenerateCRL(7 * 24 * time.Hour)
+	return e, err
+}
+
+// GenerateCRL generates the CRL of all revoked certs, writes it to ca.crl, and returns the PEM.
+func (a *Authority) GenerateCRL(nextUpdate time.Duration) ([]byte, error) {
+	var entries []x509.RevocationListEntry
+	for _, e := range a.index.Certs {
+		if e.Status != StatusRevoked {
+			continue
+		}
+
+		serial, ok := new(big.Int).SetString(e.Serial, 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid serial %s in index", e.Serial)
+		}
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: e.RevokedAt,
+			ReasonCode:     e.Reason,
+		})
+	}
+
+	a.index.CRLNumber++
+	now := time.Now()
+	tmpl := &x509.RevocationList{
+		RevokedCertificateEntries: entries,
+		Number:                    big.NewInt(a.index.CRLNumber),
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(nextUpdate),
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, tmpl, a.Cert, a.Key)
+	if err != nil {
+		return nil, fmt.Errorf("create CRL: %w", err)
+	}
+
+	crl := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+	if err := os.WriteFile(a.CRLFile(), crl, 0o644); err != nil {
+		return nil, fmt.Errorf("write CRL: %w", err)
+	}
+
+	return crl, a.saveIndex()
+}
+
+// Renew reissues the valid certs which will expire within the duration, with the same options.
+// The old ones are marked as superseded, and the new ones take over the same files.
+func (a *Authority) Renew(within time.Duration) (renewed []*IndexEntry, err error) {
+	deadline := time.Now().Add(within)
+
+	// copy the candidates first, because Issue appends to the index.
+	var candidates []*IndexEntry
+	for _, e := range a.index.Certs {
+		if e.Status == StatusValid && e.NotAfter.Before(deadline) {
+			candidates = append(candidates, e)
+		}
+	}
+
+	for _, e := range candidates {
+		opts := e.IssueOptions
+		opts.Name, opts.Force = e.Name, true
+
+		n, err := a.Issue(opts)
+		if err != nil {
+			return renewed, fmt.Errorf("renew %s: %w", e.Name, err)
+		}
+
+		renewed = append(renewed, n)
+	}
+
+	if len(renewed) > 0 {
+		err = a.saveIndex()
+	}
+
+	return renewed, err
+}
+
+// IsRevoked checks the cert against the CRL file of the CA.
+func (a *Authority) IsRevoked(cert *x509.Certificate) (bool, error) {
+	data, err := os.ReadFile(a.CRLFile())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return false, errors.New("pem decode CRL failed")
+	}
+
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		return false, err
+	}
+	if err := crl.CheckSignatureFrom(a.Cert); err != nil {
+		return false, fmt.Errorf("check CRL signature: %w", err)
+	}
+
+	for _, r := range crl.RevokedCertificateEntries {
+		if r.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// SortedCerts returns the issued certs sorted by the expiry time.
+func (a *Authority) SortedCerts() []*IndexEntry {
+	certs := append([]*IndexEntry{}, a.index.Certs...)
+	sort.SliceStable(certs, func(i, j int) bool { return certs[i].NotAfter.Before(certs[j].NotAfter) })
+	return certs
+}
+
+func randSerial() (*big.Int, error) {
+	// 生成证书的序列号
+	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
+	if err != nil {
+		return nil, fmt.Errorf("rand serialNumber: %w", err)
+	}
+
+	return serialNumber, nil
+}
+
+func writeKeyPair(crtFile, keyFile string, der []byte, key crypto.Signer) error {
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(crtFile, certPEM, 0o644); err != nil {
+		return fmt.Errorf("write certificate file: %w", err)
+	}
+
+	keyPEM, err := EncodePrivateKeyPEM(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		return fmt.Errorf("write private key file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuthority(t *testing.T) {
+	dir := t.TempDir()
+	a, err := InitAuthority(dir, "Test CA", KeyECDSAP384, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, algo := range KeyAlgos {
+		if algo == KeyRSA4096 {
+			continue // too slow
+		}
+
+		e, err := a.Issue(IssueOptions{
+			Name:       string(algo),
+			CommonName: "svc." + string(algo),
+			DNSNames:   []string{"svc.local"},
+			IPs:        []string{"127.0.0.1"},
+			KeyAlgo:    algo,
+			Validity:   time.Hour,
+		})
+		if err != nil {
+			t.Fatalf("issue %s: %v", algo, err)
+		}
+
+		kp, err := ReadCertificate(filepath.Join(dir, e.Name+".crt"), filepath.Join(dir, e.Name+".key"))
+		if err != nil {
+			t.Fatalf("read %s: %v", algo, err)
+		}
+		if got := KeyAlgoOf(kp.Cert.PublicKey); got != algo {
+			t.Fatalf("expect key algo %s, got %s", algo, got)
+		}
+		if _, err := tls.LoadX509KeyPair(filepath.Join(dir, e.Name+".crt"), filepath.Join(dir, e.Name+".key")); err != nil {
+			t.Fatalf("load key pair %s: %v", algo, err)
+		}
+	}
+
+	for _, name := range []string{"ca", "../x", "a/b", ".."} {
+		if _, err := a.Issue(IssueOptions{Name: name, CommonName: "bad"}); err == nil {
+			t.Fatalf("expect invalid name %s rejected", name)
+		}
+	}
+
+	// reissuing an existing name needs force, and the previous one is superseded.
+	if _, err := a.Issue(IssueOptions{Name: string(KeyRSA2048), CommonName: "svc"}); err == nil {
+		t.Fatal("expect reissuing an existing name rejected without force")
+	}
+	old := a.Find(string(KeyRSA2048))
+	e, err := a.Issue(IssueOptions{Name: string(KeyRSA2048), CommonName: "svc", Validity: time.Hour, Force: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.Status != StatusSuperseded || a.Find(string(KeyRSA2048)) != e {
+		t.Fatalf("expect the previous cert superseded by the reissued one")
+	}
+
+	if _, err := a.Revoke(string(KeyEd25519), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// reopen to check the index is persisted.
+	if a, err = OpenAuthority(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	kp, err := ReadCertificate(filepath.Join(dir, "ed25519.crt"), filepath.Join(dir, "ed25519.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := a.IsRevoked(kp.Cert); err != nil || !revoked {
+		t.Fatalf("expect revoked, got %v, %v", revoked, err)
+	}
+
+	renewed, err := a.Renew(2 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(renewed) != 3 {
+		t.Fatalf("expect 3 renewed, got %d", len(renewed))
+	}
+	if e := a.Find(string(KeyECDSAP256)); e == nil || e.Serial != renewed[0].Serial {
+		t.Fatalf("expect the renewed cert is valid")
+	}
+}
@@ -2,6 +2,7 @@ package mtls
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -12,8 +13,6 @@ import (
 	"math/big"
 	"os"
 	"time"
-
-	"go.uber.org/multierr"
 )
 
 // [Implementing mTLS in Go](https://ayada.dev/posts/implementing-mtls-in-go/)
@@ -80,9 +79,11 @@ func ReadCertificate(publicKeyFile, privateKeyFile string) (*KeyPair, error) {
 	}
 
 	privPemBlock, _ := pem.Decode(privKey)
+	if privPemBlock == nil {
+		return nil, fmt.Errorf("pem decode %s failed", privateKeyFile)
+	}
 
-	// Note that we use PKCS1 to parse the private key here.
-	parsedPrivKey, errParse := x509.ParsePKCS1PrivateKey(privPemBlock.Bytes)
+	parsedPrivKey, errParse := parsePrivateKey(privPemBlock.Bytes)
 	if errParse != nil {
 		return nil, fmt.Errorf("parse private key: %w", errParse)
 	}
@@ -95,6 +96,9 @@ func ReadCertificate(publicKeyFile, privateKeyFile string) (*KeyPair, error) {
 	}
 
 	publicPemBlock, _ := pem.Decode(pubKey)
+	if publicPemBlock == nil {
+		return nil, fmt.Errorf("pem decode %s failed", publicKeyFile)
+	}
 
 	parsedPubKey, errParse := x509.ParseCertificate(publicPemBlock.Bytes)
 	if errParse != nil {
@@ -146,21 +150,6 @@ func ReadCertificateAuthority(publicKeyFile, privateKeyFile string) (*KeyPair, e
 	return root, nil
 }
 
-func parsePrivateKey(privPemBlockBytes []byte) (*rsa.PrivateKey, error) {
-	// Note that we use PKCS8 to parse the private key here.
-	k1, err1 := x509.ParsePKCS8PrivateKey(privPemBlockBytes)
-	if err1 == nil {
-		return k1.(*rsa.PrivateKey), nil
-	}
-
-	k2, err2 := x509.ParsePKCS1PrivateKey(privPemBlockBytes)
-	if err2 == nil {
-		return k2, nil
-	}
-
-	return nil, fmt.Errorf("parse private key: %w", multierr.Append(err1, err2))
-}
-
 // GenerateAndSignCertificate method will use the root certificate's public and private key to generate a certificate and sign it.
 // The certificate's public and private keys will be stored in the files provided as argument to this function.
 // openssl req -newkey rsa:2048 -nodes -x509 -days 3650 -out certs/ca.crt  -keyout certs/ca.key -subj "/C=US/ST=California/L=San Francisco/O=ayada/OU=dev/CN=localhost"
@@ -220,7 +209,7 @@ type FilePair struct {
 
 type KeyPair struct {
 	Cert *x509.Certificate
-	Key  *rsa.PrivateKey
+	Key  crypto.Signer
 
 	FilePair
 }
@@ -86,7 +86,7 @@ func makeRootCA(path string, subject *pkix.Name) (*KeyPair, error) {
 		NotAfter:              time.Now().AddDate(10*365, 0, 0),
 		IsCA:                  true, // <- indicating this certificate is a CA certificate.
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
 		BasicConstraintsValid: true,
 	}
 	// generate a private key for the CA
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bingoohuang/ngg/mtls"
+	"github.com/spf13/cobra"
+)
+
+var caCmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Offline CA to issue, revoke and renew certs",
+	Long: `Offline CA keeps its files in the certs path (-C):
+ca.crt/ca.key for the root, <name>.crt/<name>.key for the issued certs,
+index.json for the issued serials and ca.crl for the revocation list.
+`,
+}
+
+var caInitOpts struct {
+	commonName string
+	keyAlgo    string
+	validity   time.Duration
+}
+
+var caInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create the CA root cert and key if they do not exist",
+	RunE: func(*cobra.Command, []string) error {
+		algo, err := mtls.ParseKeyAlgo(caInitOpts.keyAlgo)
+		if err != nil {
+			return err
+		}
+
+		a, err := mtls.InitAuthority(certsPath, caInitOpts.commonName, algo, caInitOpts.validity)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("CA %s ready ✅. CertFile: %s, KeyFile: %s, NotAfter: %s\n",
+			a.Cert.Subject.CommonName, a.CertFile, a.KeyFile, a.Cert.NotAfter.Format(time.RFC3339))
+		return nil
+	},
+}
+
+var issueOpts struct {
+	mtls.IssueOptions
+	keyAlgo string
+}
+
+var caIssueCmd = &cobra.Command{
+	Use:   "issue",
+	Short: "Issue a cert signed by the CA",
+	Example: `  mtls ca issue --cn server --dns localhost,d5k.co --ip 127.0.0.1 --key ecdsa-p256 --validity 2160h --server
+  mtls ca issue --cn client1 --key ed25519 --client`,
+	RunE: func(*cobra.Command, []string) error {
+		a, err := mtls.OpenAuthority(certsPath)
+		if err != nil {
+			return err
+		}
+
+		if issueOpts.KeyAlgo, err = mtls.ParseKeyAlgo(issueOpts.keyAlgo); err != nil {
+			return err
+		}
+
+		e, err := a.Issue(issueOpts.IssueOptions)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Issued %s ✅. Serial: %s, NotAfter: %s\n", e.Name, e.Serial, e.NotAfter.Format(time.RFC3339))
+		return nil
+	},
+}
+
+var revokeReason int
+
+var caRevokeCmd = &cobra.Command{
+	Use:   "revoke serial|name",
+	Short: "Revoke the cert into the CRL",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		a, err := mtls.OpenAuthority(certsPath)
+		if err != nil {
+			return err
+		}
+
+		e, err := a.Revoke(args[0], revokeReason)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Revoked %s ✅. Serial: %s, CRL: %s\n", e.Name, e.Serial, a.CRLFile())
+		return nil
+	},
+}
+
+var renewWithin time.Duration
+
+var caRenewCmd = &cobra.Command{
+	Use:   "renew",
+	Short: "Renew the certs which will expire soon",
+	RunE: func(*cobra.Command, []string) error {
+		a, err := mtls.OpenAuthority(certsPath)
+		if err != nil {
+			return err
+		}
+
+		renewed, err := a.Renew(renewWithin)
+		for _, e := range renewed {
+			fmt.Printf("Renewed %s ✅. Serial: %s, NotAfter: %s\n", e.Name, e.Serial, e.NotAfter.Format(time.RFC3339))
+		}
+		if err == nil && len(renewed) == 0 {
+			fmt.Printf("No certs will expire within %s\n", renewWithin)
+		}
+		return err
+	},
+}
+
+var crlNextUpdate time.Duration
+
+var caCrlCmd = &cobra.Command{
+	Use:   "crl",
+	Short: "Regenerate the CRL",
+	RunE: func(*cobra.Command, []string) error {
+		a, err := mtls.OpenAuthority(certsPath)
+		if err != nil {
+			return err
+		}
+
+		if _, err := a.GenerateCRL(crlNextUpdate); err != nil {
+			return err
+		}
+
+		fmt.Printf("CRL generated ✅. %s\n", a.CRLFile())
+		return nil
+	},
+}
+
+var caListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the issued certs in the index",
+	RunE: func(*cobra.Command, []string) error {
+		a, err := mtls.OpenAuthority(certsPath)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "SERIAL\tNAME\tCN\tKEY\tSTATUS\tNOT AFTER\tSANS")
+		for _, e := range a.SortedCerts() {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%v\n", e.Serial, e.Name, e.CommonName, e.KeyAlgo,
+				e.Status, e.NotAfter.Format(time.RFC3339), append(append([]string{}, e.DNSNames...), e.IPs...))
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	f := caInitCmd.Flags()
+	f.StringVar(&caInitOpts.commonName, "cn", "CA", "common name of the CA")
+	f.StringVar(&caInitOpts.keyAlgo, "key", "ecdsa-p256", "key algorithm: rsa2048, rsa4096, ecdsa-p256, ecdsa-p384, ed25519")
+	f.DurationVar(&caInitOpts.validity, "validity", 10*365*24*time.Hour, "validity of the CA cert")
+
+	f = caIssueCmd.Flags()
+	f.StringVar(&issueOpts.Name, "name", "", "base name of the cert/key files, default the common name")
+	f.StringVar(&issueOpts.CommonName, "cn", "", "common name")
+	f.StringSliceVar(&issueOpts.DNSNames, "dns", nil, "DNS SANs")
+	f.StringSliceVar(&issueOpts.IPs, "ip", nil, "IP SANs")
+	f.StringVar(&issueOpts.keyAlgo, "key", "ecdsa-p256", "key algorithm: rsa2048, rsa4096, ecdsa-p256, ecdsa-p384, ed25519")
+	f.DurationVar(&issueOpts.Validity, "validity", 365*24*time.Hour, "validity of the cert")
+	f.BoolVar(&issueOpts.Server, "server", false, "server auth usage (both server and client if neither is set)")
+	f.BoolVar(&issueOpts.Client, "client", false, "client auth usage (both server and client if neither is set)")
+	f.BoolVar(&issueOpts.Force, "force", false, "reissue the cert of an existing name, the previous one is superseded")
+	_ = caIssueCmd.MarkFlagRequired("cn")
+
+	caRevokeCmd.Flags().IntVar(&revokeReason, "reason", 0, "revocation reason code of RFC 5280, e.g. 1 keyCompromise, 4 superseded")
+	caRenewCmd.Flags().DurationVar(&renewWithin, "within", 30*24*time.Hour, "renew the certs which will expire within the duration")
+	caCrlCmd.Flags().DurationVar(&crlNextUpdate, "next-update", 7*24*time.Hour, "next update of the CRL")
+
+	caCmd.AddCommand(caInitCmd, caIssueCmd, caRevokeCmd, caRenewCmd, caCrlCmd, caListCmd)
+	rootCmd.AddCommand(caCmd)
+}
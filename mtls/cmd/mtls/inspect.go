@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bingoohuang/ngg/mtls"
+	"github.com/spf13/cobra"
+)
+
+var inspectOpts struct {
+	mtls.InspectOptions
+	json bool
+}
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect file|host:port...",
+	Short: "Inspect the certs chain of PEM bundles, PKCS#12 files or live endpoints with lint checks",
+	Example: `  mtls inspect certs/server.crt --ca certs/ca.crt
+  mtls inspect client.p12 --password 123456
+  mtls inspect baidu.com:443 d5k.co https://github.com --warn 720h --json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		results := make([]*mtls.Inspection, len(args))
+		var wg sync.WaitGroup
+		for i, arg := range args {
+			wg.Add(1)
+			go func(i int, arg string) {
+				defer wg.Done()
+				results[i] = mtls.Inspect(arg, inspectOpts.InspectOptions)
+			}(i, arg)
+		}
+		wg.Wait()
+
+		if inspectOpts.json {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(results); err != nil {
+				return err
+			}
+		} else {
+			for _, r := range results {
+				r.Print(os.Stdout)
+			}
+		}
+
+		failed := 0
+		for _, r := range results {
+			if r.HasIssue(mtls.IssueWarn) {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d inspected with issues", failed, len(results))
+		}
+		return nil
+	},
+}
+
+func init() {
+	f := inspectCmd.Flags()
+	f.StringVar(&inspectOpts.RootsFile, "ca", "", "roots PEM file to verify the chain, default the system roots")
+	f.StringVar(&inspectOpts.ServerName, "server-name", "", "hostname to check the leaf cert, default the endpoint host")
+	f.StringVar(&inspectOpts.Password, "password", "", "password of the PKCS#12 file")
+	f.DurationVar(&inspectOpts.ExpiryWarn, "warn", 30*24*time.Hour, "warn the certs which will expire within the duration")
+	f.DurationVar(&inspectOpts.Timeout, "timeout", 10*time.Second, "timeout to connect the endpoints")
+	f.BoolVar(&inspectOpts.json, "json", false, "output in JSON")
+	rootCmd.AddCommand(inspectCmd)
+}
@@ -0,0 +1,347 @@
+package mtls
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// InspectOptions defines the options of Inspect.
+type InspectOptions struct {
+	// RootsFile is the PEM file of the roots to verify the chain, empty to use the system pool.
+	RootsFile string
+	// ServerName is the hostname to check the leaf cert, default the host of the endpoint.
+	ServerName string
+	// Password is the password of the PKCS#12 file.
+	Password string
+	// ExpiryWarn warns the certs which will expire within the duration.
+	ExpiryWarn time.Duration
+	// Timeout is the timeout to connect the endpoints, default 10s.
+	Timeout time.Duration
+}
+
+// IssueLevel is the level of the lint issue.
+type IssueLevel string
+
+const (
+	IssueError IssueLevel = "error"
+	IssueWarn  IssueLevel = "warn"
+)
+
+// LintIssue is a problem found in the chain.
+type LintIssue struct {
+	Level IssueLevel `json:"level"`
+	// Cert is the index of the cert in the chain, -1 for the whole chain.
+	Cert    int    `json:"cert"`
+	Message string `json:"message"`
+}
+
+// CertSummary is the summary of a cert in the chain.
+type CertSummary struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	Serial             string    `json:"serial"`
+	NotBefore          time.Time `json:"notBefore"`
+	NotAfter           time.Time `json:"notAfter"`
+	DaysLeft           int       `json:"daysLeft"`
+	KeyAlgo            string    `json:"keyAlgo"`
+	KeyBits            int       `json:"keyBits"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	DNSNames           []string  `json:"dnsNames,omitempty"`
+	IPs                []string  `json:"ips,omitempty"`
+	IsCA               bool      `json:"isCA"`
+}
+
+// Inspection is the result of Inspect.
+type Inspection struct {
+	Source      string        `json:"source"`
+	ServerName  string        `json:"serverName,omitempty"`
+	Chain       []CertSummary `json:"chain"`
+	Verified    bool          `json:"verified"`
+	VerifyError string        `json:"verifyError,omitempty"`
+	Issues      []LintIssue   `json:"issues,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// HasIssue tells whether the inspection has any issue at the level or higher.
+func (i *Inspection) HasIssue(level IssueLevel) bool {
+	if i.Error != "" {
+		return true
+	}
+	for _, issue := range i.Issues {
+		if issue.Level == IssueError || issue.Level == level {
+			return true
+		}
+	}
+	return false
+}
+
+// Inspect inspects the certs chain from a PEM bundle, a PKCS#12 file or a live host:port endpoint.
+func Inspect(source string, opts InspectOptions) *Inspection {
+	i := &Inspection{Source: source, ServerName: opts.ServerName}
+	if err := i.inspect(opts); err != nil {
+		i.Error = err.Error()
+	}
+	return i
+}
+
+func (i *Inspection) inspect(opts InspectOptions) error {
+	var (
+		chain []*x509.Certificate
+		err   error
+	)
+	if _, statErr := os.Stat(i.Source); statErr == nil {
+		chain, err = LoadCertsFile(i.Source, opts.Password)
+	} else {
+		var host string
+		chain, host, err = FetchChain(i.Source, opts.ServerName, opts.Timeout)
+		if i.ServerName == "" {
+			i.ServerName = host
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	var roots *x509.CertPool
+	if opts.RootsFile != "" {
+		data, err := os.ReadFile(opts.RootsFile)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", opts.RootsFile, err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certs found in %s", opts.RootsFile)
+		}
+	}
+
+	now := time.Now()
+	for _, c := range chain {
+		i.Chain = append(i.Chain, summarize(c, now))
+	}
+
+	if err := VerifyChain(chain, roots, ""); err != nil {
+		i.VerifyError = err.Error()
+		i.Issues = append(i.Issues, LintIssue{Level: IssueError, Cert: -1, Message: "chain verification: " + err.Error()})
+	} else {
+		i.Verified = true
+	}
+
+	i.Issues = append(i.Issues, Lint(chain, i.ServerName, opts.ExpiryWarn)...)
+	return nil
+}
+
+// LoadCertsFile loads the certs from a PEM bundle, DER certs (like .cer) or a PKCS#12 file, the leaf first.
+func LoadCertsFile(file, password string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", file, err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(file))
+	isPKCS12, isPEM := ext == ".p12" || ext == ".pfx", bytes.Contains(data, []byte("-----BEGIN"))
+	if !isPKCS12 && !isPEM {
+		if certs, err := x509.ParseCertificates(data); err == nil && len(certs) > 0 {
+			return certs, nil
+		}
+	}
+
+	if isPKCS12 || !isPEM {
+		_, leaf, cas, err := pkcs12.DecodeChain(data, password)
+		if err != nil {
+			// PKCS#12 files with only certs (trust stores)
+			if cas, err2 := pkcs12.DecodeTrustStore(data, password); err2 == nil {
+				return cas, nil
+			}
+			return nil, fmt.Errorf("decode PKCS#12 %s: %w", file, err)
+		}
+		return append([]*x509.Certificate{leaf}, cas...), nil
+	}
+
+	certs, err := ParsePEMCerts(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certs found in %s", file)
+	}
+
+	return certs, nil
+}
+
+// FetchChain fetches the certs chain presented by the endpoint, returns the chain and the host name.
+// The endpoint can be host:port, host (port 443) or https URL.
+func FetchChain(endpoint, serverName string, timeout time.Duration) ([]*x509.Certificate, string, error) {
+	addr := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		addr = u.Host
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, addr = addr, net.JoinHostPort(addr, "443")
+	}
+	if serverName == "" {
+		serverName = host
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	// the verification is done later, to inspect the expired or untrusted chains too.
+	conf := &tls.Config{ServerName: serverName, InsecureSkipVerify: true}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, conf)
+	if err != nil {
+		return nil, host, fmt.Errorf("dial %s: %w", addr, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	return conn.ConnectionState().PeerCertificates, host, nil
+}
+
+// VerifyChain verifies the chain (leaf first) builds to the roots, nil roots for the system pool.
+func VerifyChain(chain []*x509.Certificate, roots *x509.CertPool, hostname string) error {
+	if len(chain) == 0 {
+		return errors.New("empty chain")
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       hostname,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	for _, c := range chain[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+
+	_, err := chain[0].Verify(opts)
+	return err
+}
+
+// Lint checks the chain for weak keys, weak signatures, missing SANs, hostname mismatch and expiry.
+func Lint(chain []*x509.Certificate, hostname string, expiryWarn time.Duration) []LintIssue {
+	var issues []LintIssue
+	add := func(level IssueLevel, idx int, format string, args ...any) {
+		issues = append(issues, LintIssue{Level: level, Cert: idx, Message: fmt.Sprintf(format, args...)})
+	}
+
+	now := time.Now()
+	for idx, c := range chain {
+		name := c.Subject.CommonName
+		algo, bits := publicKeyInfo(c)
+		switch {
+		case algo == "RSA" && bits < 2048:
+			add(IssueError, idx, "%s: weak RSA key of %d bits", name, bits)
+		case algo == "ECDSA" && bits < 256:
+			add(IssueError, idx, "%s: weak ECDSA key of %d bits", name, bits)
+		}
+
+		selfSigned := bytes.Equal(c.RawIssuer, c.RawSubject)
+		if !selfSigned { // the signature of the self-signed root is not used in the verification.
+			switch c.SignatureAlgorithm {
+			case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+				add(IssueError, idx, "%s: SHA-1 signature %s", name, c.SignatureAlgorithm)
+			case x509.MD5WithRSA, x509.MD2WithRSA:
+				add(IssueError, idx, "%s: MD5/MD2 signature %s", name, c.SignatureAlgorithm)
+			}
+		}
+
+		switch left := c.NotAfter.Sub(now); {
+		case left <= 0:
+			add(IssueError, idx, "%s: expired at %s", name, c.NotAfter.Format(time.RFC3339))
+		case now.Before(c.NotBefore):
+			add(IssueError, idx, "%s: not valid before %s", name, c.NotBefore.Format(time.RFC3339))
+		case left < expiryWarn:
+			add(IssueWarn, idx, "%s: will expire in %d days at %s", name, int(left.Hours()/24), c.NotAfter.Format(time.RFC3339))
+		}
+	}
+
+	if len(chain) == 0 || chain[0].IsCA {
+		return issues
+	}
+
+	leaf := chain[0]
+	if len(leaf.DNSNames) == 0 && len(leaf.IPAddresses) == 0 && len(leaf.URIs) == 0 && len(leaf.EmailAddresses) == 0 {
+		add(IssueError, 0, "%s: no subject alternative names", leaf.Subject.CommonName)
+	}
+	if hostname != "" {
+		if err := leaf.VerifyHostname(hostname); err != nil {
+			add(IssueError, 0, "hostname mismatch: %v", err)
+		}
+	}
+
+	return issues
+}
+
+func publicKeyInfo(c *x509.Certificate) (algo string, bits int) {
+	switch k := c.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", k.Curve.Params().BitSize
+	default:
+		return c.PublicKeyAlgorithm.String(), 0
+	}
+}
+
+func summarize(c *x509.Certificate, now time.Time) CertSummary {
+	algo, bits := publicKeyInfo(c)
+	s := CertSummary{
+		Subject:            c.Subject.String(),
+		Issuer:             c.Issuer.String(),
+		Serial:             c.SerialNumber.Text(16),
+		NotBefore:          c.NotBefore,
+		NotAfter:           c.NotAfter,
+		DaysLeft:           int(c.NotAfter.Sub(now).Hours() / 24),
+		KeyAlgo:            algo,
+		KeyBits:            bits,
+		SignatureAlgorithm: c.SignatureAlgorithm.String(),
+		DNSNames:           c.DNSNames,
+		IsCA:               c.IsCA,
+	}
+	for _, ip := range c.IPAddresses {
+		s.IPs = append(s.IPs, ip.String())
+	}
+	return s
+}
+
+// Print prints the inspection in the text format.
+func (i *Inspection) Print(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "==> %s\n", i.Source)
+	if i.Error != "" {
+		_, _ = fmt.Fprintf(w, "  ❌ %s\n", i.Error)
+		return
+	}
+
+	for idx, c := range i.Chain {
+		_, _ = fmt.Fprintf(w, "  [%d] Subject: %s\n", idx, c.Subject)
+		_, _ = fmt.Fprintf(w, "      Issuer:  %s\n", c.Issuer)
+		_, _ = fmt.Fprintf(w, "      Serial:  %s, Key: %s %d, Signature: %s, CA: %t\n",
+			c.Serial, c.KeyAlgo, c.KeyBits, c.SignatureAlgorithm, c.IsCA)
+		_, _ = fmt.Fprintf(w, "      Valid:   %s ~ %s (%d days left)\n",
+			c.NotBefore.Format(time.RFC3339), c.NotAfter.Format(time.RFC3339), c.DaysLeft)
+		if sans := append(append([]string{}, c.DNSNames...), c.IPs...); len(sans) > 0 {
+			_, _ = fmt.Fprintf(w, "      SANs:    %s\n", strings.Join(sans, ", "))
+		}
+	}
+
+	if i.Verified {
+		_, _ = fmt.Fprintln(w, "  ✅ chain verified")
+	}
+	for _, issue := range i.Issues {
+		_, _ = fmt.Fprintf(w, "  %s %s\n", If(issue.Level == IssueError, "❌", "⚠️"), issue.Message)
+	}
+}
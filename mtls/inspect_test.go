@@ -0,0 +1,112 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestInspect(t *testing.T) {
+	dir := t.TempDir()
+	a, err := InitAuthority(dir, "Test CA", KeyECDSAP256, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Issue(IssueOptions{CommonName: "server", DNSNames: []string{"localhost"}, IPs: []string{"127.0.0.1"}, Validity: 24 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Issue(IssueOptions{CommonName: "nosan", Validity: 24 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := InspectOptions{RootsFile: a.CertFile, ServerName: "localhost"}
+
+	i := Inspect(filepath.Join(dir, "server.crt"), opts)
+	if i.Error != "" || !i.Verified || len(i.Issues) != 0 {
+		t.Fatalf("unexpected inspection: %+v", i)
+	}
+
+	opts.ExpiryWarn = 48 * time.Hour
+	i = Inspect(filepath.Join(dir, "nosan.crt"), opts)
+	assertIssues(t, i, "no subject alternative names", "hostname mismatch", "will expire")
+	if !i.HasIssue(IssueWarn) {
+		t.Fatal("expect issues")
+	}
+
+	// PKCS#12 with the leaf and the CA.
+	kp, err := ReadCertificate(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pfx, err := pkcs12.Modern.Encode(kp.Key, kp.Cert, []*x509.Certificate{a.Cert}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p12File := filepath.Join(dir, "server.p12")
+	if err := os.WriteFile(p12File, pfx, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	i = Inspect(p12File, InspectOptions{RootsFile: a.CertFile, Password: "secret"})
+	if i.Error != "" || !i.Verified || len(i.Chain) != 2 {
+		t.Fatalf("unexpected inspection: %+v", i)
+	}
+
+	// DER encoded .cer
+	cerFile := filepath.Join(dir, "server.cer")
+	if err := os.WriteFile(cerFile, kp.Cert.Raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	i = Inspect(cerFile, InspectOptions{RootsFile: a.CertFile})
+	if i.Error != "" || !i.Verified || len(i.Chain) != 1 {
+		t.Fatalf("unexpected inspection: %+v", i)
+	}
+
+	// live endpoint
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_ = c.(*tls.Conn).Handshake()
+			_ = c.Close()
+		}
+	}()
+
+	i = Inspect(l.Addr().String(), InspectOptions{RootsFile: a.CertFile, ServerName: "d5k.co"})
+	if i.Error != "" || !i.Verified {
+		t.Fatalf("unexpected inspection: %+v", i)
+	}
+	assertIssues(t, i, "hostname mismatch")
+}
+
+func assertIssues(t *testing.T, i *Inspection, messages ...string) {
+	t.Helper()
+	for _, m := range messages {
+		found := false
+		for _, issue := range i.Issues {
+			if strings.Contains(issue.Message, m) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("expect issue %q in %+v", m, i.Issues)
+		}
+	}
+}
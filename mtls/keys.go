@@ -0,0 +1,122 @@
+package mtls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+// KeyAlgo is the algorithm of the private key.
+type KeyAlgo string
+
+const (
+	KeyRSA2048   KeyAlgo = "rsa2048"
+	KeyRSA4096   KeyAlgo = "rsa4096"
+	KeyECDSAP256 KeyAlgo = "ecdsa-p256"
+	KeyECDSAP384 KeyAlgo = "ecdsa-p384"
+	KeyEd25519   KeyAlgo = "ed25519"
+)
+
+// KeyAlgos lists all the supported key algorithms.
+var KeyAlgos = []KeyAlgo{KeyRSA2048, KeyRSA4096, KeyECDSAP256, KeyECDSAP384, KeyEd25519}
+
+// ParseKeyAlgo parses the key algorithm name case-insensitively, empty for ecdsa-p256.
+func ParseKeyAlgo(s string) (KeyAlgo, error) {
+	if s == "" {
+		return KeyECDSAP256, nil
+	}
+
+	for _, a := range KeyAlgos {
+		if strings.EqualFold(s, string(a)) {
+			return a, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown key algorithm %s, available: %v", s, KeyAlgos)
+}
+
+// GenerateKey generates a private key of the algorithm.
+func GenerateKey(algo KeyAlgo) (crypto.Signer, error) {
+	switch algo {
+	case KeyRSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case KeyRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case KeyECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unknown key algorithm %s", algo)
+	}
+}
+
+// KeyAlgoOf tells the algorithm of the public key.
+func KeyAlgoOf(pub crypto.PublicKey) KeyAlgo {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() > 2048 {
+			return KeyRSA4096
+		}
+		return KeyRSA2048
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P384() {
+			return KeyECDSAP384
+		}
+		return KeyECDSAP256
+	case ed25519.PublicKey:
+		return KeyEd25519
+	}
+
+	return ""
+}
+
+// EncodePrivateKeyPEM encodes the private key in PEM,
+// RSA keys are in PKCS1 (RSA PRIVATE KEY) like before, and the others are in PKCS8 (PRIVATE KEY).
+func EncodePrivateKeyPEM(key crypto.Signer) ([]byte, error) {
+	if k, ok := key.(*rsa.PrivateKey); ok {
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}), nil
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal private key: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func parsePrivateKey(privPemBlockBytes []byte) (crypto.Signer, error) {
+	// Note that we use PKCS8 to parse the private key here.
+	k1, err1 := x509.ParsePKCS8PrivateKey(privPemBlockBytes)
+	if err1 == nil {
+		if signer, ok := k1.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", k1)
+	}
+
+	k2, err2 := x509.ParsePKCS1PrivateKey(privPemBlockBytes)
+	if err2 == nil {
+		return k2, nil
+	}
+
+	k3, err3 := x509.ParseECPrivateKey(privPemBlockBytes)
+	if err3 == nil {
+		return k3, nil
+	}
+
+	return nil, fmt.Errorf("parse private key: %w", multierr.Combine(err1, err2, err3))
+}
@@ -0,0 +1,348 @@
+package mtls
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClientAuthPolicy is the policy to verify the client certs on the server side.
+type ClientAuthPolicy string
+
+const (
+	// ClientAuthNone does not request client certs.
+	ClientAuthNone ClientAuthPolicy = "none"
+	// ClientAuthOptional verifies the client cert if it is given.
+	ClientAuthOptional ClientAuthPolicy = "optional"
+	// ClientAuthRequire requires and verifies the client cert.
+	ClientAuthRequire ClientAuthPolicy = "require"
+)
+
+// ReloadOptions defines the files and policies of the Reloader.
+type ReloadOptions struct {
+	// CertFile and KeyFile are the cert presented to the peer, optional for clients.
+	CertFile string
+	KeyFile  string
+	// CAFile is the CA bundle to verify the peer certs, empty to use the system pool.
+	CAFile string
+
+	// ClientAuth is the server side policy, default require when CAFile is set, otherwise none.
+	ClientAuth ClientAuthPolicy
+	// AllowedNames allows only the peer certs whose CN or DNS/IP/URI SANs are in the list, empty to allow all.
+	AllowedNames []string
+
+	// Interval is the interval to check the files changing, default 10s.
+	Interval time.Duration
+}
+
+// Reloader builds tls.Config whose certificates and CA pool are reloaded automatically when the files change.
+type Reloader struct {
+	opts ReloadOptions
+
+	cert   atomic.Pointer[tls.Certificate]
+	pool   atomic.Pointer[x509.CertPool]
+	caCert atomic.Pointer[x509.Certificate]
+
+	mu       sync.Mutex
+	checksum []byte
+
+	reloads      atomic.Int64
+	reloadErrors atomic.Int64
+	lastReload   atomic.Int64
+}
+
+// NewReloader creates a Reloader and loads the files for the first time.
+func NewReloader(opts ReloadOptions) (*Reloader, error) {
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, errors.New("CertFile and KeyFile should be set together")
+	}
+	if opts.ClientAuth == "" {
+		opts.ClientAuth = If(opts.CAFile != "", ClientAuthRequire, ClientAuthNone)
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Second
+	}
+
+	r := &Reloader{opts: opts}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload reloads the files if their content changed, returns whether it reloaded.
+func (r *Reloader) Reload() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed, err := r.reload()
+	if err != nil {
+		r.reloadErrors.Add(1)
+	}
+
+	return changed, err
+}
+
+func (r *Reloader) reload() (bool, error) {
+	files := [][]byte{nil, nil, nil}
+	h := sha256.New()
+	for i, f := range []string{r.opts.CertFile, r.opts.KeyFile, r.opts.CAFile} {
+		if f == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return false, fmt.Errorf("read %s: %w", f, err)
+		}
+		files[i] = data
+		h.Write(data)
+	}
+
+	checksum := h.Sum(nil)
+	if bytes.Equal(checksum, r.checksum) {
+		return false, nil
+	}
+
+	if r.opts.CertFile != "" {
+		cert, err := tls.X509KeyPair(files[0], files[1])
+		if err != nil {
+			// the cert and key files may be in the middle of rotation, keep the old ones.
+			return false, fmt.Errorf("load key pair %s: %w", r.opts.CertFile, err)
+		}
+		if cert.Leaf == nil {
+			if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+				return false, fmt.Errorf("parse %s: %w", r.opts.CertFile, err)
+			}
+		}
+		r.cert.Store(&cert)
+	}
+
+	if r.opts.CAFile != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(files[2]) {
+			return false, fmt.Errorf("no certs found in %s", r.opts.CAFile)
+		}
+		r.pool.Store(pool)
+
+		if certs, err := ParsePEMCerts(files[2]); err == nil && len(certs) > 0 {
+			r.caCert.Store(certs[0])
+		}
+	}
+
+	r.checksum = checksum
+	r.reloads.Add(1)
+	r.lastReload.Store(time.Now().Unix())
+
+	return true, nil
+}
+
+// Run checks the files changing periodically until the ctx is done.
+func (r *Reloader) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if changed, err := r.Reload(); err != nil {
+				log.Printf("W! reload certs failed: %v", err)
+			} else if changed {
+				log.Printf("I! certs reloaded, %s", r.expiryText())
+			}
+		}
+	}
+}
+
+func (r *Reloader) expiryText() string {
+	if c := r.cert.Load(); c != nil {
+		return fmt.Sprintf("%s expires at %s", c.Leaf.Subject.CommonName, c.Leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	return "no cert"
+}
+
+// ServerConfig returns the tls.Config for servers.
+func (r *Reloader) ServerConfig() *tls.Config {
+	c := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return r.certificate() },
+	}
+
+	switch r.opts.ClientAuth {
+	case ClientAuthRequire:
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+	case ClientAuthOptional:
+		c.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		c.ClientAuth = tls.NoClientCert
+	}
+
+	// GetConfigForClient makes the reloaded CA pool effective for the new connections.
+	c.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		cc := c.Clone()
+		cc.GetConfigForClient = nil
+		cc.ClientCAs = r.pool.Load()
+		cc.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return nil
+			}
+			return r.checkAllowed(cs.PeerCertificates[0])
+		}
+		return cc, nil
+	}
+
+	return c
+}
+
+// ClientConfig returns the tls.Config for clients, the server cert is verified by the reloaded CA pool.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if c := r.cert.Load(); c != nil {
+				return c, nil
+			}
+			return &tls.Certificate{}, nil // no client cert
+		},
+		// the standard verification is replaced by VerifyConnection with the reloaded pool.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("no server certificates")
+			}
+
+			opts := x509.VerifyOptions{
+				Roots:         r.pool.Load(),
+				DNSName:       cs.ServerName,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, c := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(c)
+			}
+			if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+				return err
+			}
+
+			return r.checkAllowed(cs.PeerCertificates[0])
+		},
+	}
+}
+
+func (r *Reloader) certificate() (*tls.Certificate, error) {
+	if c := r.cert.Load(); c != nil {
+		return c, nil
+	}
+
+	return nil, errors.New("no certificate configured")
+}
+
+// checkAllowed checks the peer cert by the allow-list of CN and SANs.
+func (r *Reloader) checkAllowed(cert *x509.Certificate) error {
+	if len(r.opts.AllowedNames) == 0 {
+		return nil
+	}
+
+	if slices.Contains(r.opts.AllowedNames, cert.Subject.CommonName) {
+		return nil
+	}
+	for _, n := range cert.DNSNames {
+		if slices.Contains(r.opts.AllowedNames, n) {
+			return nil
+		}
+	}
+	for _, ip := range cert.IPAddresses {
+		if slices.Contains(r.opts.AllowedNames, ip.String()) {
+			return nil
+		}
+	}
+	for _, u := range cert.URIs {
+		if slices.Contains(r.opts.AllowedNames, u.String()) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("peer certificate %s is not allowed", cert.Subject.CommonName)
+}
+
+// WriteMetrics writes the expiry and reload metrics in the Prometheus text format.
+func (r *Reloader) WriteMetrics(w io.Writer) {
+	now := time.Now()
+	gauge := func(name, help string) {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+
+	gauge("mtls_cert_not_after_seconds", "The unix time when the certificate expires.")
+	if c := r.cert.Load(); c != nil {
+		_, _ = fmt.Fprintf(w, "mtls_cert_not_after_seconds{kind=\"cert\",file=%q,cn=%q} %d\n",
+			r.opts.CertFile, c.Leaf.Subject.CommonName, c.Leaf.NotAfter.Unix())
+	}
+	if c := r.caCert.Load(); c != nil {
+		_, _ = fmt.Fprintf(w, "mtls_cert_not_after_seconds{kind=\"ca\",file=%q,cn=%q} %d\n",
+			r.opts.CAFile, c.Subject.CommonName, c.NotAfter.Unix())
+	}
+
+	gauge("mtls_cert_expiry_seconds", "The seconds left before the certificate expires.")
+	if c := r.cert.Load(); c != nil {
+		_, _ = fmt.Fprintf(w, "mtls_cert_expiry_seconds{kind=\"cert\",file=%q} %.0f\n",
+			r.opts.CertFile, c.Leaf.NotAfter.Sub(now).Seconds())
+	}
+	if c := r.caCert.Load(); c != nil {
+		_, _ = fmt.Fprintf(w, "mtls_cert_expiry_seconds{kind=\"ca\",file=%q} %.0f\n",
+			r.opts.CAFile, c.NotAfter.Sub(now).Seconds())
+	}
+
+	_, _ = fmt.Fprintf(w, "# HELP mtls_reloads_total The number of reloads.\n# TYPE mtls_reloads_total counter\n")
+	_, _ = fmt.Fprintf(w, "mtls_reloads_total %d\n", r.reloads.Load())
+	_, _ = fmt.Fprintf(w, "# HELP mtls_reload_errors_total The number of failed reloads.\n# TYPE mtls_reload_errors_total counter\n")
+	_, _ = fmt.Fprintf(w, "mtls_reload_errors_total %d\n", r.reloadErrors.Load())
+	gauge("mtls_last_reload_seconds", "The unix time of the last successful reload.")
+	_, _ = fmt.Fprintf(w, "mtls_last_reload_seconds %d\n", r.lastReload.Load())
+}
+
+// MetricsHandler returns the http.Handler to expose the metrics.
+func (r *Reloader) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteMetrics(w)
+	})
+}
+
+// ParsePEMCerts parses all the certificates in the PEM data.
+func ParsePEMCerts(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,112 @@
+package mtls
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	a, err := InitAuthority(dir, "Test CA", KeyECDSAP256, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := func(name string) *IndexEntry {
+		e, err := a.Issue(IssueOptions{CommonName: name, DNSNames: []string{"localhost"}, Validity: time.Hour, Force: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	issue("server")
+	issue("client1")
+	issue("client2")
+
+	sr, err := NewReloader(ReloadOptions{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		CAFile:       filepath.Join(dir, "ca.crt"),
+		AllowedNames: []string{"client1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", sr.ServerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_ = c.(*tls.Conn).Handshake()
+			_, _ = c.Write([]byte("ok"))
+			_ = c.Close()
+		}
+	}()
+
+	dial := func(client string) (string, error) {
+		cr, err := NewReloader(ReloadOptions{
+			CertFile: filepath.Join(dir, client+".crt"),
+			KeyFile:  filepath.Join(dir, client+".key"),
+			CAFile:   filepath.Join(dir, "ca.crt"),
+		})
+		if err != nil {
+			return "", err
+		}
+
+		c, err := tls.DialWithDialer(&net.Dialer{Timeout: 3 * time.Second}, "tcp", l.Addr().String(), cr.ClientConfig("localhost"))
+		if err != nil {
+			return "", err
+		}
+		defer c.Close()
+
+		buf := make([]byte, 2)
+		if _, err := c.Read(buf); err != nil {
+			return "", err
+		}
+		return c.ConnectionState().PeerCertificates[0].SerialNumber.Text(16), nil
+	}
+
+	serial1, err := dial("client1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dial("client2"); err == nil {
+		t.Fatal("expect client2 rejected by the allow-list")
+	}
+
+	// rotate the server cert.
+	e := issue("server")
+	if changed, err := sr.Reload(); err != nil || !changed {
+		t.Fatalf("expect reloaded, got %v, %v", changed, err)
+	}
+	if changed, _ := sr.Reload(); changed {
+		t.Fatal("expect no reload when files unchanged")
+	}
+
+	serial2, err := dial("client1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serial2 == serial1 || serial2 != e.Serial {
+		t.Fatalf("expect the rotated cert %s, got %s (old %s)", e.Serial, serial2, serial1)
+	}
+
+	var buf bytes.Buffer
+	sr.WriteMetrics(&buf)
+	if !strings.Contains(buf.String(), `mtls_cert_expiry_seconds{kind="cert"`) ||
+		!strings.Contains(buf.String(), "mtls_reloads_total 2") {
+		t.Fatalf("unexpected metrics:\n%s", buf.String())
+	}
+}
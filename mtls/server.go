@@ -1,13 +1,12 @@
 package mtls
 
 import (
+	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
 	"path/filepath"
 )
 
@@ -29,30 +28,31 @@ func StartServer(serverPath, certsPath string, port, sslPort int) error {
 		}
 	}()
 
-	// load CA certificate file and add it to list of client CAs
-	caCertFile, err := os.ReadFile(filepath.Join(certsPath, "ca.crt"))
+	// load the server cert and CA certificate file which are reloaded when changed
+	reloader, err := NewReloader(ReloadOptions{
+		CertFile:   filepath.Join(certsPath, "server.crt"),
+		KeyFile:    filepath.Join(certsPath, "server.key"),
+		CAFile:     filepath.Join(certsPath, "ca.crt"),
+		ClientAuth: If(GetEnvBool("CLIENT_AUTH_OFF"), ClientAuthNone, ClientAuthRequire),
+	})
 	if err != nil {
-		return fmt.Errorf("reading CA certificate: %w", err)
+		return fmt.Errorf("loading certificates: %w", err)
 	}
-	caCertPool := x509.NewCertPool()
-	caCertPool.AppendCertsFromPEM(caCertFile)
+	go reloader.Run(context.Background())
+	handler.Handle("/metrics", reloader.MetricsHandler())
 
 	// Create the TLS Config with the CA pool and enable Client certificate validation
-	tlsConfig := &tls.Config{
-		ClientCAs:        caCertPool,
-		ClientAuth:       If(GetEnvBool("CLIENT_AUTH_OFF"), tls.NoClientCert, tls.RequireAndVerifyClientCert),
-		MinVersion:       tls.VersionTLS12,
-		CurvePreferences: []tls.CurveID{tls.CurveP521, tls.CurveP384, tls.CurveP256},
-		CipherSuites: []uint16{
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
-			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-		},
-		InsecureSkipVerify: GetEnvBool("INSECURE_SKIP_VERIFY"),
+	tlsConfig := reloader.ServerConfig()
+	tlsConfig.CurvePreferences = []tls.CurveID{tls.CurveP521, tls.CurveP384, tls.CurveP256}
+	tlsConfig.CipherSuites = []uint16{
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+		tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
 	}
+	tlsConfig.InsecureSkipVerify = GetEnvBool("INSECURE_SKIP_VERIFY")
 
 	// serve on port 8443 of local host
 	server := http.Server{
@@ -62,9 +62,7 @@ func StartServer(serverPath, certsPath string, port, sslPort int) error {
 	}
 
 	fmt.Printf("(HTTPS) Listen on :%d\n", sslPort)
-	if err := server.ListenAndServeTLS(
-		filepath.Join(certsPath, "server.crt"),
-		filepath.Join(certsPath, "server.key")); err != nil {
+	if err := server.ListenAndServeTLS("", ""); err != nil {
 		return fmt.Errorf(" listening to port: %w", err)
 	}
 
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
@@ -26,6 +27,12 @@ func main() {
 	version := flag.Bool("version", false, "show version, then exit")
 	follow := flag.Bool("follow", false, "follow tail")
 	reopen := flag.Bool("reopen", false, "reopen tail")
+	schema := flag.Bool("schema", false, "print the tables schema and tag cardinality report, then exit")
+	schemaWarn := flag.Int64("schema-warn", sqliter.DefaultCardinalityWarn, "tag cardinality warning threshold of the schema report")
+	listen := flag.String("listen", "", "listen address for influxdb compatible /write and /query api, e.g. :8086")
+	sqlToken := flag.String("sql-token", "", "enable sql query (q and where) of /query with header Authorization: Token <sql-token>")
+	var rollups rollupFlags
+	flag.Var(&rollups, "rollup", "rollup definition table:interval[:keep], e.g. cpu:1m:3months, can be repeated")
 	flag.Parse()
 
 	if *version {
@@ -33,26 +40,52 @@ func main() {
 		return
 	}
 
-	if *tailFile == "" {
-		log.Fatalf("tail argument required")
+	if *schema {
+		printSchema(*prefix, *schemaWarn)
+		return
 	}
 
-	// Create a tail
-	t, err := tail.TailFile(*tailFile, tail.Config{Follow: *follow, ReOpen: *reopen})
-	if err != nil {
-		panic(err)
+	if *tailFile == "" && *listen == "" {
+		log.Fatalf("tail or listen argument required")
 	}
 
 	plus, err := sqliter.New(
 		sqliter.WithDriverName("sqlite3"),
 		sqliter.WithPrefix(*prefix),
 		sqliter.WithDebug(*debug),
+		sqliter.WithRollups(rollups...),
 	)
 	if err != nil {
 		panic(err)
 	}
 	defer plus.Close()
 
+	if *listen != "" {
+		go func() {
+			log.Printf("listening on %s", *listen)
+			if err := http.ListenAndServe(*listen, sqliter.NewHTTPHandler(plus, sqliter.WithSQLToken(*sqlToken))); err != nil {
+				log.Fatalf("listen %s error: %v", *listen, err)
+			}
+		}()
+	}
+
+	// 创建一个信号通道
+	sigs := make(chan os.Signal, 1)
+	// 监听 SIGINT 信号，Ctrl+C 会触发这个信号
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	if *tailFile == "" {
+		sig := <-sigs
+		log.Printf("received singal %s", sig)
+		return
+	}
+
+	// Create a tail
+	t, err := tail.TailFile(*tailFile, tail.Config{Follow: *follow, ReOpen: *reopen})
+	if err != nil {
+		panic(err)
+	}
+
 	lineDone := make(chan struct{})
 
 	var table string
@@ -96,10 +129,6 @@ func main() {
 		lineDone <- struct{}{}
 	}()
 
-	// 创建一个信号通道
-	sigs := make(chan os.Signal, 1)
-	// 监听 SIGINT 信号，Ctrl+C 会触发这个信号
-	signal.Notify(sigs, syscall.SIGINT)
 	// 阻塞等待信号
 	select {
 	case sig := <-sigs:
@@ -120,3 +149,42 @@ func main() {
 		log.Printf("stop tail error: %v", err)
 	}
 }
+
+func printSchema(prefix string, warn int64) {
+	plus, err := sqliter.New(
+		sqliter.WithDriverName("sqlite3"),
+		sqliter.WithPrefix(prefix),
+	)
+	if err != nil {
+		log.Fatalf("create sqliter error: %v", err)
+	}
+	defer plus.Close()
+
+	schemas, err := plus.Schema(warn)
+	if err != nil {
+		log.Fatalf("schema error: %v", err)
+	}
+	if err := sqliter.PrintSchema(os.Stdout, schemas); err != nil {
+		log.Fatalf("print schema error: %v", err)
+	}
+}
+
+// rollupFlags 可重复的 -rollup 参数
+type rollupFlags []sqliter.Rollup
+
+func (f *rollupFlags) String() string {
+	var names []string
+	for _, r := range *f {
+		names = append(names, r.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (f *rollupFlags) Set(s string) error {
+	r, err := sqliter.ParseRollup(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, r)
+	return nil
+}
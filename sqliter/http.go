@@ -0,0 +1,258 @@
+package sqliter
+
+import (
+	"compress/gzip"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/ngg/sqliter/influx"
+	"github.com/bingoohuang/ngg/sqlrun"
+	"github.com/bingoohuang/ngg/ss"
+	"github.com/bingoohuang/ngg/tick"
+)
+
+// MaxWriteBodySize 写入请求体（解压后）的最大大小
+const MaxWriteBodySize = 64 << 20
+
+// HTTPConfig HTTP 处理器配置
+type HTTPConfig struct {
+	// SQLToken 非空时开启 /query 的 SQL 查询（q 参数）及自定义过滤条件（where 参数），
+	// 请求需要携带 Authorization: Token <SQLToken> 头，为空时这两个参数被拒绝
+	SQLToken string
+}
+
+// HTTPConfigFn HTTP 处理器配置函数
+type HTTPConfigFn func(c *HTTPConfig)
+
+// WithSQLToken 设置开启 SQL 查询的令牌
+func WithSQLToken(val string) HTTPConfigFn { return func(c *HTTPConfig) { c.SQLToken = val } }
+
+// ErrSQLDisabled SQL 查询未开启或者令牌不匹配
+var ErrSQLDisabled = errors.New("sql query is disabled or token mismatched")
+
+// NewHTTPHandler 创建兼容 InfluxDB 写入协议的 HTTP 处理器，便于 Telegraf 等代理直接推送
+//
+//	POST /write?precision=s         InfluxDB v1 行协议写入，精度 n/ns/u/us/ms/s/m/h
+//	POST /api/v2/write?precision=s  InfluxDB v2 行协议写入，精度 ns/us/ms/s
+//	GET  /ping                      健康检查
+//	GET  /query                     JSON 查询
+//	GET  /schema?warn=10000         表结构及 tag 基数报告
+//
+// 写入请求体支持 Content-Encoding: gzip，解压后超过 MaxWriteBodySize 时返回 413
+func NewHTTPHandler(q *Sqliter, fns ...HTTPConfigFn) http.Handler {
+	c := &HTTPConfig{}
+	for _, f := range fns {
+		f(c)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/write", func(w http.ResponseWriter, r *http.Request) {
+		q.serveWrite(w, r, false)
+	})
+	mux.HandleFunc("/api/v2/write", func(w http.ResponseWriter, r *http.Request) {
+		q.serveWrite(w, r, true)
+	})
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) {
+		q.serveQuery(w, r, c)
+	})
+	mux.HandleFunc("/schema", q.serveSchema)
+	return mux
+}
+
+func (q *Sqliter) serveWrite(w http.ResponseWriter, r *http.Request, v2 bool) {
+	writeError := func(status int, err error) {
+		if v2 {
+			writeJSON(w, status, map[string]string{"code": "invalid", "message": err.Error()})
+		} else {
+			writeJSON(w, status, map[string]string{"error": err.Error()})
+		}
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	precision, err := influx.ParsePrecision(r.URL.Query().Get("precision"))
+	if err != nil {
+		writeError(http.StatusBadRequest, err)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, MaxWriteBodySize)
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		gr, err := gzip.NewReader(body)
+		if err != nil {
+			writeError(http.StatusBadRequest, fmt.Errorf("gzip: %w", err))
+			return
+		}
+		defer gr.Close()
+		// 限制解压后的大小，超出时与请求体超限一样返回 413
+		body = http.MaxBytesReader(w, io.NopCloser(gr), MaxWriteBodySize)
+	}
+
+	points, err := influx.ParseLines(body, precision, time.Now())
+	if err != nil {
+		if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+			writeError(http.StatusRequestEntityTooLarge, err)
+		} else {
+			writeError(http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	for _, p := range points {
+		if err := q.WriteMetric(p); err != nil {
+			writeError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// QueryResponse /query 的 JSON 响应
+type QueryResponse struct {
+	Headers   []string   `json:"headers"`
+	Rows      [][]string `json:"rows"`
+	RowsCount int        `json:"rowsCount"`
+	Cost      string     `json:"cost"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// serveQuery 处理 JSON 查询，参数:
+//
+//	table    表名，必须
+//	from, to 时间范围 [from, to)，RFC3339 或者偏移，例如 -90d，跨分区查询
+//	columns  查询字段，逗号分隔
+//	where    额外过滤条件，需要开启 SQL 查询
+//	limit, offset, desc 分页及排序
+//	q, t     在时间 t (默认当前时间) 所在的分区上执行单条 select 语句 q，需要开启 SQL 查询
+func (q *Sqliter) serveQuery(w http.ResponseWriter, r *http.Request, c *HTTPConfig) {
+	result, err := q.query(r, c)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrSQLDisabled) {
+			status = http.StatusForbidden
+		}
+		writeJSON(w, status, QueryResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, QueryResponse{
+		Headers:   result.Headers,
+		Rows:      result.StringRows(),
+		RowsCount: result.RowsCount,
+		Cost:      result.CostTime.String(),
+	})
+}
+
+func (q *Sqliter) query(r *http.Request, c *HTTPConfig) (*sqlrun.Result, error) {
+	v := r.URL.Query()
+	table := v.Get("table")
+	if table == "" {
+		return nil, fmt.Errorf("table required")
+	}
+
+	if (v.Has("q") || v.Has("where")) && !c.sqlAllowed(r) {
+		return nil, ErrSQLDisabled
+	}
+
+	if sql := v.Get("q"); sql != "" {
+		if err := checkSingleSelect(sql); err != nil {
+			return nil, err
+		}
+		t := time.Now()
+		if tm := v.Get("t"); tm != "" {
+			var err error
+			if t, err = tick.ParseTime(tm); err != nil {
+				return nil, err
+			}
+		}
+		return q.Read(table, sql, t.Local(), nil)
+	}
+
+	rq := RangeQuery{Table: table, Where: v.Get("where"), Desc: v.Get("desc") == "true"}
+	var err error
+	if rq.From, err = parseQueryTime(v.Get("from"), "-1h"); err != nil {
+		return nil, err
+	}
+	if rq.To, err = parseQueryTime(v.Get("to"), "0"); err != nil {
+		return nil, err
+	}
+	if columns := v.Get("columns"); columns != "" {
+		rq.Columns = ss.Split(columns, ",")
+		for _, col := range rq.Columns {
+			if strings.Contains(col, `"`) {
+				return nil, fmt.Errorf("bad column %q", col)
+			}
+		}
+	}
+	if rq.Limit, err = ss.Parse[int](ss.Or(v.Get("limit"), "0")); err != nil {
+		return nil, fmt.Errorf("bad limit: %w", err)
+	}
+	if rq.Offset, err = ss.Parse[int](ss.Or(v.Get("offset"), "0")); err != nil {
+		return nil, fmt.Errorf("bad offset: %w", err)
+	}
+
+	return q.ReadRange(rq)
+}
+
+// sqlAllowed 判断请求是否允许执行 SQL 查询
+func (c *HTTPConfig) sqlAllowed(r *http.Request) bool {
+	if c.SQLToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Token ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(c.SQLToken)) == 1
+}
+
+// checkSingleSelect 检查 sql 是单条 select 语句（读库也是只读打开的）
+func checkSingleSelect(sql string) error {
+	if stmts := ss.SplitX(sql, ";"); len(stmts) != 1 {
+		return fmt.Errorf("only a single select statement is allowed")
+	}
+	if !strings.EqualFold(sqlrun.FirstWord(sql), "select") {
+		return fmt.Errorf("only select statement is allowed")
+	}
+	return nil
+}
+
+func (q *Sqliter) serveSchema(w http.ResponseWriter, r *http.Request) {
+	warn, err := ss.Parse[int64](ss.Or(r.URL.Query().Get("warn"), "0"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "bad warn: " + err.Error()})
+		return
+	}
+
+	schemas, err := q.Schema(warn)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, schemas)
+}
+
+// parseQueryTime 解析查询时间，转换为本地时区（与写入时的时间格式一致，便于按字符串比较）
+func parseQueryTime(s, defaultValue string) (time.Time, error) {
+	t, err := tick.ParseTime(ss.Or(s, defaultValue))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.Local(), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
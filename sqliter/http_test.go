@@ -0,0 +1,112 @@
+package sqliter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	prefix := t.TempDir() + "/http.t"
+	plus, err := New(WithPrefix(prefix), WithSeqKeysDBName("off"))
+	assert.Nil(t, err)
+
+	ts := httptest.NewServer(NewHTTPHandler(plus, WithSQLToken("secret")))
+	defer ts.Close()
+
+	now := time.Now().Truncate(time.Second)
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	for i := 0; i < 3; i++ {
+		_, _ = fmt.Fprintf(gw, "cpu,host=h1 usage=%d %d\n", i+1, now.Add(time.Duration(i-10)*time.Second).Unix())
+	}
+	assert.Nil(t, gw.Close())
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/write?db=telegraf&precision=s", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	rsp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, rsp.StatusCode)
+
+	rsp, err = http.Post(ts.URL+"/api/v2/write?precision=s", "text/plain", bytes.NewBufferString("cpu,host=h1 usage=bad"))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	var v2Err map[string]string
+	assert.Nil(t, json.NewDecoder(rsp.Body).Decode(&v2Err))
+	assert.Equal(t, "invalid", v2Err["code"])
+
+	// 关闭以刷新批量写入，再重新打开查询
+	assert.Nil(t, plus.Close())
+	plus, err = New(WithPrefix(prefix), WithSeqKeysDBName("off"))
+	assert.Nil(t, err)
+	defer plus.Close()
+	ts.Config.Handler = NewHTTPHandler(plus, WithSQLToken("secret"))
+
+	query := url.Values{"table": {"cpu"}, "from": {"-1m"}, "columns": {"timestamp,usage"}, "desc": {"true"}, "limit": {"2"}}
+	rsp, err = http.Get(ts.URL + "/query?" + query.Encode())
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	var qr QueryResponse
+	assert.Nil(t, json.NewDecoder(rsp.Body).Decode(&qr))
+	assert.Equal(t, []string{"timestamp", "usage"}, qr.Headers)
+	assert.Equal(t, 2, qr.RowsCount)
+	assert.Equal(t, "3", qr.Rows[0][1])
+
+	sqlQuery := func(token, sql string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/query?"+url.Values{"table": {"cpu"}, "q": {sql}}.Encode(), nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Token "+token)
+		}
+		rsp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		return rsp
+	}
+
+	rsp = sqlQuery("secret", "select count(*) from cpu")
+	assert.Nil(t, json.NewDecoder(rsp.Body).Decode(&qr))
+	assert.Equal(t, [][]string{{"3"}}, qr.Rows)
+
+	assert.Equal(t, http.StatusForbidden, sqlQuery("", "select count(*) from cpu").StatusCode)
+	assert.Equal(t, http.StatusForbidden, sqlQuery("bad", "select count(*) from cpu").StatusCode)
+	assert.Equal(t, http.StatusBadRequest, sqlQuery("secret", "delete from cpu").StatusCode)
+	assert.Equal(t, http.StatusBadRequest, sqlQuery("secret", "select 1; delete from cpu").StatusCode)
+
+	rsp, err = http.Get(ts.URL + "/query?" + url.Values{"table": {"cpu"}, "where": {"1=1"}}.Encode())
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, rsp.StatusCode)
+
+	rsp = sqlQuery("secret", "select count(*) from cpu")
+	assert.Nil(t, json.NewDecoder(rsp.Body).Decode(&qr))
+	assert.Equal(t, [][]string{{"3"}}, qr.Rows)
+}
+
+func TestHTTPWriteTooLarge(t *testing.T) {
+	plus, err := New(WithPrefix(t.TempDir()+"/http.t"), WithSeqKeysDBName("off"))
+	assert.Nil(t, err)
+	defer plus.Close()
+
+	ts := httptest.NewServer(NewHTTPHandler(plus))
+	defer ts.Close()
+
+	// 解压后超过大小限制
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	line := []byte(`cpu,host=h1 msg="` + strings.Repeat("x", 4000) + "\"\n")
+	_, _ = gw.Write(bytes.Repeat(line, MaxWriteBodySize/len(line)+1))
+	assert.Nil(t, gw.Close())
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/write", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	rsp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rsp.StatusCode)
+}
@@ -0,0 +1,208 @@
+package influx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseLineProtocol 解析InfluxDB的行协议字符串，时间戳单位为纳秒
+// 指标时间截断到秒，与 tail 导入已有数据的唯一索引去重保持一致，需要更高精度时使用 ParseLineProtocolPrecision
+func ParseLineProtocol(lp string) (*Point, error) {
+	p, err := ParseLineProtocolPrecision(lp, time.Nanosecond, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	p.MetricTime = time.Unix(p.MetricTime.Unix(), 0)
+	return p, nil
+}
+
+// ParseLineProtocolPrecision 按时间精度 precision 解析InfluxDB的行协议字符串
+// 格式: measurement[,tag=value...] field=value[,field=value...] [timestamp]
+// 时间戳缺失时，使用 now 作为指标时间
+func ParseLineProtocolPrecision(lp string, precision time.Duration, now time.Time) (*Point, error) {
+	lp = strings.TrimSpace(lp)
+	keyEnd := indexUnescaped(lp, ' ', false)
+	if keyEnd <= 0 {
+		return nil, fmt.Errorf("invalid line protocol format: missing fields")
+	}
+
+	rest := strings.TrimLeft(lp[keyEnd:], " ")
+	fieldsEnd := indexUnescaped(rest, ' ', true)
+	fieldStr, timestampStr := rest, ""
+	if fieldsEnd >= 0 {
+		fieldStr, timestampStr = rest[:fieldsEnd], strings.TrimSpace(rest[fieldsEnd:])
+	}
+
+	measurement, tags, err := parseTags(lp[:keyEnd])
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFields(fieldStr)
+	if err != nil {
+		return nil, err
+	}
+
+	t := now
+	if timestampStr != "" {
+		timestamp, err := parseTimestamp(timestampStr)
+		if err != nil {
+			return nil, err
+		}
+		t = time.Unix(0, timestamp*int64(precision))
+	}
+
+	return &Point{
+		MetricName:   measurement,
+		MetricTags:   tags,
+		MetricFields: fields,
+		Timestamp:    t.UnixNano(),
+		MetricTime:   t,
+	}, nil
+}
+
+// ParsePrecision 解析写入协议的时间精度参数
+// InfluxDB v1: n, ns, u, us, ms, s, m, h; InfluxDB v2: ns, us, ms, s; 为空时表示纳秒
+func ParsePrecision(s string) (time.Duration, error) {
+	switch s {
+	case "", "n", "ns":
+		return time.Nanosecond, nil
+	case "u", "us", "µs":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	}
+
+	return 0, fmt.Errorf("invalid precision: %s", s)
+}
+
+// ParseLines 逐行解析行协议，忽略空行和 # 开头的注释行
+func ParseLines(r io.Reader, precision time.Duration, now time.Time) ([]*Point, error) {
+	var points []*Point
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p, err := ParseLineProtocolPrecision(line, precision, now)
+		if err != nil {
+			// 读取出错时，最后的不完整行也会被扫描出来，优先返回读取错误
+			if serr := scanner.Err(); serr != nil {
+				return nil, serr
+			}
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		points = append(points, p)
+	}
+
+	return points, scanner.Err()
+}
+
+// indexUnescaped 查找未被反斜杠转义的字符 c 的位置，quoted 为真时跳过双引号字符串中的内容
+func indexUnescaped(s string, c byte, quoted bool) int {
+	inQuote := false
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '\\':
+			i++ // 跳过被转义的字符
+		case quoted && ch == '"':
+			inQuote = !inQuote
+		case !inQuote && ch == c:
+			return i
+		}
+	}
+	return -1
+}
+
+// splitUnescaped 按未被转义的字符 c 切分
+func splitUnescaped(s string, c byte, quoted bool) (parts []string) {
+	for {
+		i := indexUnescaped(s, c, quoted)
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
+
+var keyUnescaper = strings.NewReplacer(`\,`, ",", `\ `, " ", `\=`, "=", `\\`, `\`)
+
+// parseTags 解析标签字符串
+func parseTags(tagStr string) (string, map[string]string, error) {
+	tags := make(map[string]string)
+	parts := splitUnescaped(tagStr, ',', false)
+	for _, tag := range parts[1:] {
+		i := indexUnescaped(tag, '=', false)
+		if i <= 0 {
+			return "", nil, fmt.Errorf("invalid tag format: %s", tag)
+		}
+		tags[keyUnescaper.Replace(tag[:i])] = keyUnescaper.Replace(tag[i+1:])
+	}
+	return keyUnescaper.Replace(parts[0]), tags, nil
+}
+
+// parseFields 解析字段字符串
+func parseFields(fieldStr string) (map[string]any, error) {
+	fields := make(map[string]any)
+	for _, field := range splitUnescaped(fieldStr, ',', true) {
+		i := indexUnescaped(field, '=', false)
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid field format: %s", field)
+		}
+		value, err := parseFieldValue(field[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		fields[keyUnescaper.Replace(field[:i])] = value
+	}
+	return fields, nil
+}
+
+var stringUnescaper = strings.NewReplacer(`\"`, `"`, `\\`, `\`)
+
+// parseFieldValue 解析字段值
+func parseFieldValue(valueStr string) (any, error) {
+	switch valueStr {
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+
+	switch {
+	case len(valueStr) >= 2 && valueStr[0] == '"' && valueStr[len(valueStr)-1] == '"':
+		// 双引号包围的是字符串
+		return stringUnescaper.Replace(valueStr[1 : len(valueStr)-1]), nil
+	case strings.HasSuffix(valueStr, "i"):
+		// 如果值以 'i' 结尾，表示它是一个整数
+		return strconv.ParseInt(valueStr[:len(valueStr)-1], 10, 64)
+	case strings.HasSuffix(valueStr, "u"):
+		// 如果值以 'u' 结尾，表示它是一个无符号整数
+		return strconv.ParseUint(valueStr[:len(valueStr)-1], 10, 64)
+	}
+
+	// 默认尝试解析为浮点数
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field value: %s", valueStr)
+	}
+	return value, nil
+}
+
+// parseTimestamp 解析时间戳
+func parseTimestamp(timestampStr string) (int64, error) {
+	return strconv.ParseInt(timestampStr, 10, 64)
+}
@@ -1,6 +1,7 @@
 package influx
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -26,3 +27,28 @@ func TestParse(t *testing.T) {
 	assert.Nil(t, err)
 	t.Logf("point: %+v", p)
 }
+
+func TestParseLineProtocolPrecision(t *testing.T) {
+	now := time.Unix(1723795322, 0)
+	p, err := ParseLineProtocolPrecision(`disk\ io,path=/data\,1,host=h\=1 msg="a \"quoted\", spaced",used=10u,ok=t,free=3i 1723795322`, time.Second, now)
+	assert.Nil(t, err)
+	assert.Equal(t, "disk io", p.Name())
+	assert.Equal(t, map[string]string{"path": "/data,1", "host": "h=1"}, p.Tags())
+	assert.Equal(t, map[string]any{"msg": `a "quoted", spaced`, "used": uint64(10), "ok": true, "free": int64(3)}, p.Fields())
+	assert.True(t, now.Equal(p.Time()))
+
+	p, err = ParseLineProtocolPrecision("cpu usage=1.5", time.Millisecond, now)
+	assert.Nil(t, err)
+	assert.Equal(t, now, p.Time())
+
+	points, err := ParseLines(strings.NewReader("# comment\ncpu usage=1 1000\n\nmem used=2i 2000\n"), time.Millisecond, now)
+	assert.Nil(t, err)
+	assert.Len(t, points, 2)
+	assert.Equal(t, time.Unix(2, 0), points[1].Time())
+
+	_, err = ParseLines(strings.NewReader("cpu usage=1\ncpu\n"), time.Second, now)
+	assert.ErrorContains(t, err, "line 2")
+
+	_, err = ParsePrecision("d")
+	assert.NotNil(t, err)
+}
@@ -28,7 +28,8 @@ func (q *Sqliter) WriteMetric(metric influx.Metric) error {
 		columns: columns,
 	}
 
-	return nil
+	// 增量汇总到降采样表
+	return q.rollup(metric)
 }
 
 // createColumnsFromMetric 从指标 metric 中生成列信息
@@ -0,0 +1,206 @@
+package sqliter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bingoohuang/ngg/sqlrun"
+)
+
+// RangeQuery 跨时间分区查询条件
+type RangeQuery struct {
+	// Table 表名称
+	Table string
+	// From 起始时间（包含）
+	From time.Time
+	// To 结束时间（不包含）
+	To time.Time
+
+	// Columns 查询字段，例如 "timestamp", "host", "usage", 为空时查询全部字段
+	Columns []string
+	// Where 额外的过滤条件，例如 "host = ?"，不包括 where 关键字
+	Where string
+	// Args Where 条件中的绑定参数
+	Args []any
+
+	// Desc 是否按时间倒序
+	Desc bool
+	// Limit 最大返回行数，0 表示不限制
+	Limit int
+	// Offset 跳过行数
+	Offset int
+}
+
+// ErrNoTimestampColumn 查询字段中没有 timestamp 字段，无法进行归并排序
+var ErrNoTimestampColumn = errors.New("timestamp column required")
+
+// rangeConcurrency ReadRange 同时查询的最大分区数
+const rangeConcurrency = 4
+
+// ReadRange 在时间范围 [From, To) 覆盖的所有分区库文件上并发查询，按 timestamp 归并排序后返回
+// 结果集中 Rows 格式是 [][]string
+func (q *Sqliter) ReadRange(rq RangeQuery) (*sqlrun.Result, error) {
+	if err := q.ValidateTable(rq.Table); err != nil {
+		return nil, err
+	}
+	if !rq.From.Before(rq.To) {
+		return nil, fmt.Errorf("bad time range [%s, %s)", rq.From, rq.To)
+	}
+
+	dividedBys, err := q.RangeDividedBys(rq.Table, rq.From, rq.To)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	query, args := rq.partitionQuery()
+
+	results := make([]*sqlrun.Result, len(dividedBys))
+	errs := make([]error, len(dividedBys))
+	// 限制同时查询的分区数，避免时间范围很大时打开过多的库文件
+	sem := make(chan struct{}, rangeConcurrency)
+	var wg sync.WaitGroup
+	for i, dividedBy := range dividedBys {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, dividedBy string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			db, err := q.getReadDB(rq.Table, dividedBy)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i], errs[i] = db.db.Query(nil, query, args...)
+		}(i, dividedBy)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	r, err := mergeRangeResults(results, rq.Desc, rq.Offset, rq.Limit)
+	if err != nil {
+		return nil, err
+	}
+	r.CostTime = time.Since(start)
+	return r, nil
+}
+
+// RangeDividedBys 列出表 table 在时间范围 [from, to) 内，磁盘上存在的分区库的时间划分，按时间划分排序
+func (q *Sqliter) RangeDividedBys(table string, from, to time.Time) ([]string, error) {
+	tables, err := q.ListDiskTables()
+	if err != nil {
+		return nil, err
+	}
+
+	// 按天步进，收集时间范围覆盖的所有时间划分（按周划分时，跨年的周不能简单按周步进）
+	wanted := map[string]bool{}
+	for t := from; t.Before(to); t = t.AddDate(0, 0, 1) {
+		wanted[q.DividedString(t)] = true
+	}
+	wanted[q.DividedString(to.Add(-time.Nanosecond))] = true
+
+	var dividedBys []string
+	for _, f := range tables[table] {
+		if wanted[f.DividedBy] {
+			dividedBys = append(dividedBys, f.DividedBy)
+		}
+	}
+
+	return dividedBys, nil
+}
+
+// partitionQuery 生成单个分区库上的查询 SQL，limit 下推为 offset + limit
+func (rq RangeQuery) partitionQuery() (string, []any) {
+	columns := "*"
+	if len(rq.Columns) > 0 {
+		columns = strings.Join(QuoteSlice(rq.Columns), ",")
+	}
+
+	query := fmt.Sprintf(`select %s from %q where "timestamp" >= ? and "timestamp" < ?`, columns, rq.Table)
+	// 与写入时一致，直接绑定 time.Time
+	args := []any{rq.From, rq.To}
+	if rq.Where != "" {
+		query += " and (" + rq.Where + ")"
+		args = append(args, rq.Args...)
+	}
+
+	query += ` order by "timestamp"`
+	if rq.Desc {
+		query += " desc"
+	}
+	if rq.Limit > 0 {
+		query += fmt.Sprintf(" limit %d", rq.Offset+rq.Limit)
+	}
+
+	return query, args
+}
+
+// mergeRangeResults 将各分区上已按 timestamp 排序的结果集归并排序，并应用 offset/limit
+func mergeRangeResults(results []*sqlrun.Result, desc bool, offset, limit int) (*sqlrun.Result, error) {
+	merged := &sqlrun.Result{IsQuery: true}
+
+	var parts [][][]string
+	tsIndex := -1
+	for _, r := range results {
+		if r == nil || r.RowsCount == 0 {
+			continue
+		}
+		if merged.Headers == nil {
+			merged.Headers = r.Headers
+			if tsIndex = slices.Index(r.Headers, "timestamp"); tsIndex < 0 {
+				return nil, ErrNoTimestampColumn
+			}
+		} else if !slices.Equal(merged.Headers, r.Headers) {
+			// 不同分区库的表结构可能不同(新增了字段)，这里要求查询字段一致
+			return nil, fmt.Errorf("headers mismatch among partitions: %v and %v, specify the columns", merged.Headers, r.Headers)
+		}
+		parts = append(parts, r.StringRows())
+	}
+
+	var rows [][]string
+	for {
+		picked := -1
+		for i, part := range parts {
+			if len(part) == 0 {
+				continue
+			}
+			if picked < 0 {
+				picked = i
+				continue
+			}
+			ts, pickedTs := part[0][tsIndex], parts[picked][0][tsIndex]
+			if desc && ts > pickedTs || !desc && ts < pickedTs {
+				picked = i
+			}
+		}
+		if picked < 0 {
+			break
+		}
+
+		row := parts[picked][0]
+		parts[picked] = parts[picked][1:]
+		if offset > 0 {
+			offset--
+			continue
+		}
+		rows = append(rows, row)
+		if limit > 0 && len(rows) >= limit {
+			break
+		}
+	}
+
+	if rows == nil {
+		rows = [][]string{}
+	}
+	merged.Rows = rows
+	merged.RowsCount = len(rows)
+	return merged, nil
+}
@@ -0,0 +1,69 @@
+package sqliter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/ngg/sqliter/influx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRange(t *testing.T) {
+	prefix := t.TempDir() + "/range.t"
+	plus, err := New(WithPrefix(prefix), WithSeqKeysDBName("off"), WithDividedBy(DividedByMonth))
+	assert.Nil(t, err)
+
+	// 3个月，每天一个点
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	for i := 0; i < 91; i++ {
+		tm := from.AddDate(0, 0, i)
+		p := influx.NewPoint("cpu", map[string]string{"host": "h1"}, map[string]any{"usage": i}, tm)
+		assert.Nil(t, plus.WriteMetric(p))
+	}
+	assert.Nil(t, plus.Close())
+
+	plus, err = New(WithPrefix(prefix), WithSeqKeysDBName("off"), WithDividedBy(DividedByMonth))
+	assert.Nil(t, err)
+	defer plus.Close()
+
+	dividedBys, err := plus.RangeDividedBys("cpu", from.AddDate(0, 0, 20), from.AddDate(0, 0, 70))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"month.202406", "month.202407", "month.202408"}, dividedBys)
+
+	r, err := plus.ReadRange(RangeQuery{
+		Table:   "cpu",
+		From:    from.AddDate(0, 0, 20),
+		To:      from.AddDate(0, 0, 70),
+		Columns: []string{"timestamp", "usage"},
+		Offset:  5,
+		Limit:   30,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 30, r.RowsCount)
+	rows := r.StringRows()
+	assert.Equal(t, "25", rows[0][1])
+	assert.Equal(t, "54", rows[29][1]) // 跨越了 6月、7月 两个分区
+
+	r, err = plus.ReadRange(RangeQuery{
+		Table:   "cpu",
+		From:    from,
+		To:      from.AddDate(1, 0, 0),
+		Columns: []string{"timestamp", "usage"},
+		Where:   `"usage" % 10 = ?`,
+		Args:    []any{0},
+		Desc:    true,
+		Limit:   3,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"90"}, {"80"}, {"70"}}, usages(r.StringRows()))
+
+	_, err = plus.ReadRange(RangeQuery{Table: "cpu", From: from, To: from.AddDate(0, 1, 0), Columns: []string{"usage"}})
+	assert.ErrorIs(t, err, ErrNoTimestampColumn)
+}
+
+func usages(rows [][]string) (result [][]string) {
+	for _, row := range rows {
+		result = append(result, row[1:])
+	}
+	return result
+}
@@ -1,6 +1,7 @@
 package sqliter
 
 import (
+	"os"
 	"runtime"
 	"time"
 
@@ -50,7 +51,11 @@ func (q *Sqliter) getReadDB(table, dividedBy string) (*readTable, error) {
 		return nil, ErrNotFound
 	}
 
-	dsn := q.Prefix + dbFile + "?" + q.ReadDsnOptions
+	// 库文件不存在时不创建，只读方式打开，避免查询语句修改数据
+	if _, err := os.Stat(q.Prefix + dbFile); err != nil {
+		return nil, ErrNotFound
+	}
+	dsn := "file:" + q.Prefix + dbFile + "?mode=ro&" + q.ReadDsnOptions
 	debugDB, err := NewDebugDB(q.Prefix+dbFile, dsn, max(4, runtime.NumCPU()), q.Config, ModeRead)
 	if err != nil {
 		return nil, err
@@ -14,10 +14,7 @@ import (
 )
 
 // recycleLoop 回收循环，以指定的间隔循环
-func (q *Sqliter) recycleLoop() {
-	ctx, cancel := context.WithCancel(context.Background())
-	q.recycleCancel = cancel
-
+func (q *Sqliter) recycleLoop(ctx context.Context) {
 	if q.RecycleCron != "" {
 		parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.DowOptional | cron.Descriptor)
 
@@ -162,9 +159,10 @@ func (q *Sqliter) tickRecycleByDays(t time.Time, keepTime TimeSpan) (totalRecycl
 		return
 	}
 
-	cutoffDivided := q.DividedBy.CutoffDays(t, keepTime)
 	var recycled []*DbFile
-	for _, infos := range tables {
+	for table, infos := range tables {
+		// 汇总表按各自的保留时间回收
+		cutoffDivided := q.DividedBy.CutoffDays(t, q.tableKeep(table, keepTime))
 		for _, info := range infos {
 			if info.DividedBy < cutoffDivided {
 				recycled = append(recycled, info)
@@ -303,10 +301,12 @@ func (q *Sqliter) statWriteDbs() (dbStats []DbStat) {
 // Close 关闭 sqliter 所有操作，包括关闭库文件、退出回收协程等
 func (q *Sqliter) Close() error {
 	q.recycleCancel()
+
+	// 先写出未完结的汇总，再关闭写库
+	err := q.flushRollups()
 	q.closeWriteDbs()
 	q.closeReadDbs()
 
-	var err error
 	if q.SeqKeysDB != nil {
 		err = multierr.Append(err, q.SeqKeysDB.Close())
 	}
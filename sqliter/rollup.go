@@ -0,0 +1,301 @@
+package sqliter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bingoohuang/ngg/sqliter/influx"
+	pie "github.com/elliotchance/pie/v2"
+	"github.com/samber/lo"
+)
+
+// Rollup 降采样（汇总）定义，将源表的打点数据按时间间隔汇总到单独的汇总表
+// 汇总表按 tag 组合，对每个数值字段 f 生成 f_avg, f_min, f_max, f_last 字段，以及 count 点数字段
+type Rollup struct {
+	// Table 源表名，例如 cpu
+	Table string
+	// Interval 汇总时间间隔，例如 1m, 1h
+	Interval time.Duration
+	// Keep 汇总表保留时间，通常比原始打点数据的 TimeSeriesKeep 长，为空时与 TimeSeriesKeep 一致
+	Keep TimeSpan
+}
+
+// Name 汇总表名，例如 cpu_1m, cpu_1h
+func (r Rollup) Name() string {
+	switch {
+	case r.Interval%time.Hour == 0:
+		return fmt.Sprintf("%s_%dh", r.Table, r.Interval/time.Hour)
+	case r.Interval%time.Minute == 0:
+		return fmt.Sprintf("%s_%dm", r.Table, r.Interval/time.Minute)
+	default:
+		return fmt.Sprintf("%s_%ds", r.Table, r.Interval/time.Second)
+	}
+}
+
+// ParseRollup 解析汇总定义，格式: 表名:间隔[:保留时间]，例如 cpu:1m:3months, cpu:1h:12months
+func ParseRollup(s string) (Rollup, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+		return Rollup{}, fmt.Errorf("bad rollup %q, expect table:interval[:keep]", s)
+	}
+
+	interval, err := time.ParseDuration(parts[1])
+	if err != nil || interval < time.Second {
+		return Rollup{}, fmt.Errorf("bad rollup interval %q", parts[1])
+	}
+
+	r := Rollup{Table: parts[0], Interval: interval}
+	if len(parts) == 3 {
+		if r.Keep, err = ParseTimeSpan(parts[2]); err != nil {
+			return Rollup{}, fmt.Errorf("bad rollup keep %q: %w", parts[2], err)
+		}
+	}
+
+	return r, nil
+}
+
+// rollupState 增量汇总的状态，由写入协程在 WriteMetric 中更新
+type rollupState struct {
+	lock sync.Mutex
+	// aggs 源表名 => 汇总器
+	aggs map[string][]*rollupAgg
+}
+
+func newRollupState(rollups []Rollup) *rollupState {
+	s := &rollupState{aggs: map[string][]*rollupAgg{}}
+	for _, r := range rollups {
+		s.aggs[r.Table] = append(s.aggs[r.Table], &rollupAgg{
+			Rollup: r,
+			name:   r.Name(),
+			series: map[string]*rollupSeries{},
+		})
+	}
+	return s
+}
+
+// rollupAgg 单个汇总定义的聚合器
+type rollupAgg struct {
+	Rollup
+	name string
+
+	// series tag 组合 => 该组合的未完结时间桶
+	series map[string]*rollupSeries
+}
+
+// rollupSeries 单个 tag 组合的汇总状态，各组合独立推进，某个主机上报延迟不影响其它主机
+type rollupSeries struct {
+	// buckets 时间桶起点 => 未完结的时间桶
+	buckets map[int64]*rollupBucket
+	// watermark 已见到的最大时间桶起点，早于 watermark - Interval 的桶视为完结
+	watermark time.Time
+}
+
+type rollupBucket struct {
+	start  time.Time
+	tags   map[string]string
+	count  int64
+	fields map[string]*fieldAgg
+}
+
+type fieldAgg struct {
+	sum, min, max, last float64
+	n                   int64
+}
+
+// add 将指标 m 汇总到时间桶中，返回已经完结的汇总点
+// 迟到的数据仍然汇总，写出时与汇总表中已有的同一时间桶合并
+func (a *rollupAgg) add(m influx.Metric) (done []influx.Metric) {
+	start := m.Time().Truncate(a.Interval)
+	key := seriesKey(m.Tags())
+	s := a.series[key]
+	if s == nil {
+		s = &rollupSeries{buckets: map[int64]*rollupBucket{}}
+		a.series[key] = s
+	}
+
+	b := s.buckets[start.UnixNano()]
+	if b == nil {
+		b = &rollupBucket{start: start, tags: m.Tags(), fields: map[string]*fieldAgg{}}
+		s.buckets[start.UnixNano()] = b
+	}
+
+	b.count++
+	for k, v := range m.Fields() {
+		f, ok := toFloat64(v)
+		if !ok {
+			continue
+		}
+		fa := b.fields[k]
+		if fa == nil {
+			fa = &fieldAgg{min: f, max: f}
+			b.fields[k] = fa
+		}
+		fa.sum += f
+		fa.n++
+		fa.min = min(fa.min, f)
+		fa.max = max(fa.max, f)
+		fa.last = f
+	}
+
+	if start.After(s.watermark) {
+		s.watermark = start
+		// 允许一个间隔的乱序，更早的桶完结写出
+		done = s.flush(a.name, start.Add(-a.Interval))
+	}
+	return done
+}
+
+// flush 写出全部 tag 组合未完结的时间桶
+func (a *rollupAgg) flush() (done []influx.Metric) {
+	for _, s := range a.series {
+		done = append(done, s.flush(a.name, time.Time{})...)
+	}
+	return done
+}
+
+// flush 写出起点早于 before 的时间桶, before 为零值时写出全部
+func (s *rollupSeries) flush(name string, before time.Time) (done []influx.Metric) {
+	for k, b := range s.buckets {
+		if before.IsZero() || b.start.Before(before) {
+			done = append(done, b.metric(name))
+			delete(s.buckets, k)
+		}
+	}
+	return done
+}
+
+func (b *rollupBucket) metric(name string) influx.Metric {
+	fields := map[string]any{"count": b.count}
+	for k, f := range b.fields {
+		fields[k+"_avg"] = f.sum / float64(f.n)
+		fields[k+"_min"] = f.min
+		fields[k+"_max"] = f.max
+		fields[k+"_last"] = f.last
+	}
+	return influx.NewPoint(name, b.tags, fields, b.start)
+}
+
+func seriesKey(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k + "=" + tags[k] + ",")
+	}
+	return sb.String()
+}
+
+func toFloat64(v any) (float64, bool) {
+	switch x := ToDBFieldValue(v).(type) {
+	case int64:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+// rollup 将指标汇总到源表对应的汇总器，并写出完结的汇总点
+func (q *Sqliter) rollup(metric influx.Metric) error {
+	if q.rollups == nil {
+		return nil
+	}
+
+	q.rollups.lock.Lock()
+	var done []influx.Metric
+	for _, a := range q.rollups.aggs[metric.Name()] {
+		done = append(done, a.add(metric)...)
+	}
+	q.rollups.lock.Unlock()
+
+	return q.writeRollups(done)
+}
+
+// flushRollups 写出全部未完结的时间桶，在关闭时调用
+func (q *Sqliter) flushRollups() error {
+	if q.rollups == nil {
+		return nil
+	}
+
+	q.rollups.lock.Lock()
+	var done []influx.Metric
+	for _, aggs := range q.rollups.aggs {
+		for _, a := range aggs {
+			done = append(done, a.flush()...)
+		}
+	}
+	q.rollups.lock.Unlock()
+
+	return q.writeRollups(done)
+}
+
+func (q *Sqliter) writeRollups(done []influx.Metric) error {
+	for _, m := range done {
+		// 汇总表有 timestamp + tags 的唯一索引，重复写出同一个时间桶时与已有汇总合并，见 rollupOnConflictGen
+		if err := q.WriteMetric(m); err != nil {
+			return fmt.Errorf("write rollup %s: %w", m.Name(), err)
+		}
+	}
+	return nil
+}
+
+// isRollupTable 判断 table 是否为汇总表
+func (c *Config) isRollupTable(table string) bool {
+	for _, r := range c.Rollups {
+		if r.Name() == table {
+			return true
+		}
+	}
+	return false
+}
+
+// rollupOnConflictGen 生成汇总表的 on conflict 子语句函数
+// 同一时间桶可能分多次写出（迟到数据、关闭时写出未完结的桶后重启继续汇总），
+// 因此与已有汇总合并而不是覆盖: count 相加，min/max 取极值，avg 按点数加权，last 取新值
+func rollupOnConflictGen(tags map[string]bool) func(columns []string) string {
+	sortedColumns := uniqueIndexSort(lo.Keys(tags))
+
+	q := " on conflict(" + strings.Join(pie.Map(sortedColumns, strconv.Quote), ",") + ") do update set "
+	return func(columns []string) string {
+		sets := pie.Of(columns).
+			Filter(func(s string) bool {
+				return !tags[s]
+			}).
+			Map(func(s string) string {
+				switch {
+				case s == "count":
+					return `"count"="count"+excluded."count"`
+				case strings.HasSuffix(s, "_min"):
+					return fmt.Sprintf("%q=min(coalesce(%q,excluded.%q),excluded.%q)", s, s, s, s)
+				case strings.HasSuffix(s, "_max"):
+					return fmt.Sprintf("%q=max(coalesce(%q,excluded.%q),excluded.%q)", s, s, s, s)
+				case strings.HasSuffix(s, "_avg"):
+					return fmt.Sprintf(`%q=(1.0*coalesce(%q,excluded.%q)*"count"+excluded.%q*excluded."count")/("count"+excluded."count")`, s, s, s, s)
+				default:
+					return fmt.Sprintf("%q=excluded.%q", s, s)
+				}
+			}).
+			Result
+		return q + strings.Join(sets, ",")
+	}
+}
+
+// tableKeep 返回表的保留时间，汇总表使用其汇总定义的保留时间
+func (q *Sqliter) tableKeep(table string, keep TimeSpan) TimeSpan {
+	for _, r := range q.Rollups {
+		if r.Name() == table && r.Keep.Value > 0 {
+			return r.Keep
+		}
+	}
+	return keep
+}
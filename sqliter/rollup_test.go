@@ -0,0 +1,93 @@
+package sqliter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/ngg/sqliter/influx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRollup(t *testing.T) {
+	r, err := ParseRollup("cpu:1h:12months")
+	assert.Nil(t, err)
+	assert.Equal(t, Rollup{Table: "cpu", Interval: time.Hour, Keep: UnitMonth.Of(12)}, r)
+	assert.Equal(t, "cpu_1h", r.Name())
+
+	r, err = ParseRollup("cpu:90s")
+	assert.Nil(t, err)
+	assert.Equal(t, "cpu_90s", r.Name())
+
+	_, err = ParseRollup("cpu")
+	assert.NotNil(t, err)
+}
+
+func TestRollup(t *testing.T) {
+	prefix := t.TempDir() + "/rollup.t"
+	rollup := Rollup{Table: "cpu", Interval: time.Minute, Keep: UnitMonth.Of(12)}
+	plus, err := New(WithPrefix(prefix), WithSeqKeysDBName("off"), WithRollups(rollup))
+	assert.Nil(t, err)
+
+	// 3分钟，每10秒一个点，第1分钟的 usage 是 0,1,...,5
+	from := time.Date(2024, 8, 10, 10, 0, 0, 0, time.Local)
+	for i := 0; i < 18; i++ {
+		p := influx.NewPoint("cpu", map[string]string{"host": "h1"}, map[string]any{"usage": i}, from.Add(time.Duration(i)*10*time.Second))
+		assert.Nil(t, plus.WriteMetric(p))
+	}
+	assert.Nil(t, plus.Close())
+
+	plus, err = New(WithPrefix(prefix), WithSeqKeysDBName("off"))
+	assert.Nil(t, err)
+	defer plus.Close()
+
+	r, err := plus.Read("cpu_1m", `select "count", usage_avg, usage_min, usage_max, usage_last from cpu_1m order by "timestamp"`, from, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"6", "2.5", "0", "5", "5"},
+		{"6", "8.5", "6", "11", "11"},
+		{"6", "14.5", "12", "17", "17"},
+	}, r.StringRows())
+
+	assert.Equal(t, UnitMonth.Of(12), (&Sqliter{Config: &Config{Rollups: []Rollup{rollup}}}).tableKeep("cpu_1m", UnitMonth.Of(1)))
+}
+
+func TestRollupMerge(t *testing.T) {
+	prefix := t.TempDir() + "/rollup.t"
+	rollup := Rollup{Table: "cpu", Interval: time.Minute}
+	write := func(host string, from time.Time, values ...int) {
+		plus, err := New(WithPrefix(prefix), WithSeqKeysDBName("off"), WithRollups(rollup))
+		assert.Nil(t, err)
+		for i, v := range values {
+			p := influx.NewPoint("cpu", map[string]string{"host": host}, map[string]any{"usage": v}, from.Add(time.Duration(i)*10*time.Second))
+			assert.Nil(t, plus.WriteMetric(p))
+		}
+		assert.Nil(t, plus.Close())
+	}
+
+	from := time.Date(2024, 8, 10, 10, 0, 0, 0, time.Local)
+	// 第1分钟的数据分两次运行写入，关闭时写出的未完结桶在重启后合并
+	write("h1", from, 1, 2, 3)
+	write("h1", from.Add(30*time.Second), 4, 5, 6)
+
+	plus, err := New(WithPrefix(prefix), WithSeqKeysDBName("off"), WithRollups(rollup))
+	assert.Nil(t, err)
+	// h2 上报延迟，不受 h1 时间推进的影响
+	for i := 0; i < 18; i++ {
+		p := influx.NewPoint("cpu", map[string]string{"host": "h1"}, map[string]any{"usage": 100}, from.Add(time.Minute+time.Duration(i)*10*time.Second))
+		assert.Nil(t, plus.WriteMetric(p))
+	}
+	p := influx.NewPoint("cpu", map[string]string{"host": "h2"}, map[string]any{"usage": 7}, from)
+	assert.Nil(t, plus.WriteMetric(p))
+	assert.Nil(t, plus.Close())
+
+	plus, err = New(WithPrefix(prefix), WithSeqKeysDBName("off"))
+	assert.Nil(t, err)
+	defer plus.Close()
+
+	r, err := plus.Read("cpu_1m", `select host, "count", usage_avg, usage_min, usage_max from cpu_1m order by "timestamp", host limit 2`, from, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"h1", "6", "3.5", "1", "6"},
+		{"h2", "1", "7", "7", "7"},
+	}, r.StringRows())
+}
@@ -0,0 +1,192 @@
+package sqliter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bingoohuang/ngg/ss"
+)
+
+// DefaultCardinalityWarn 默认的 tag 基数告警阈值
+const DefaultCardinalityWarn = 10000
+
+// TableSchema 表（指标）的结构及统计
+type TableSchema struct {
+	// Table 表名
+	Table string `json:"table"`
+	// Columns 字段列表，以最新分区的表结构为准
+	Columns []ColumnSchema `json:"columns"`
+	// Partitions 各时间分区的行数及大小
+	Partitions []PartitionStat `json:"partitions"`
+	// Warnings 告警信息，例如 tag 基数过高
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ColumnSchema 字段结构
+type ColumnSchema struct {
+	// Name 字段名
+	Name string `json:"name"`
+	// Type sqlite 声明类型，例如 NUMERIC, TEXT
+	Type string `json:"type"`
+	// Tag 是否索引字段(tag)
+	Tag bool `json:"tag"`
+	// Cardinality tag 的不同取值数量（最新分区）
+	Cardinality int64 `json:"cardinality,omitempty"`
+	// Samples tag 的取值样例，已经从序号库还原为原始字符串
+	Samples []string `json:"samples,omitempty"`
+}
+
+// PartitionStat 时间分区统计
+type PartitionStat struct {
+	// DividedBy 时间划分, e.g. month.202407
+	DividedBy string `json:"dividedBy"`
+	// Rows 行数
+	Rows int64 `json:"rows"`
+	// Size 文件大小（包括 -wal, -shm 等关联文件）
+	Size int64 `json:"size"`
+}
+
+// Schema 列出磁盘上全部表的字段、tag/field 类型、各分区行数以及 tag 基数
+// cardinalityWarn tag 基数告警阈值，<= 0 时使用 DefaultCardinalityWarn
+func (q *Sqliter) Schema(cardinalityWarn int64) ([]TableSchema, error) {
+	if cardinalityWarn <= 0 {
+		cardinalityWarn = DefaultCardinalityWarn
+	}
+
+	tables, err := q.ListDiskTables()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	schemas := make([]TableSchema, 0, len(names))
+	for _, table := range names {
+		s, err := q.tableSchema(table, tables[table], cardinalityWarn)
+		if err != nil {
+			return nil, fmt.Errorf("schema of %s: %w", table, err)
+		}
+		schemas = append(schemas, *s)
+	}
+
+	return schemas, nil
+}
+
+func (q *Sqliter) tableSchema(table string, dbFiles []*DbFile, cardinalityWarn int64) (*TableSchema, error) {
+	s := &TableSchema{Table: table}
+	var latest *DebugDB
+	for _, f := range dbFiles {
+		db, err := q.getReadDB(table, f.DividedBy)
+		if err != nil {
+			return nil, err
+		}
+
+		rows, err := queryInt(db.db, fmt.Sprintf("select count(*) from %q", table))
+		if err != nil {
+			return nil, err
+		}
+		s.Partitions = append(s.Partitions, PartitionStat{DividedBy: f.DividedBy, Rows: rows, Size: f.TotalSize()})
+		latest = db.db
+	}
+	if latest == nil {
+		return s, nil
+	}
+
+	type tableInfo struct {
+		Name string `name:"name"`
+		Type string `name:"type"`
+	}
+	r, err := latest.Query(tableInfo{}, fmt.Sprintf("select name, type from pragma_table_info('%s')", table))
+	if err != nil {
+		return nil, err
+	}
+	ti, err := ParseTableIndexInfo(latest, table)
+	if err != nil {
+		return nil, err
+	}
+	tags := ss.ToSet(ti.Tags)
+
+	for _, c := range r.Rows.([]tableInfo) {
+		// timestamp 也有单独索引，但不是 tag
+		col := ColumnSchema{Name: c.Name, Type: c.Type, Tag: tags[c.Name] && c.Name != "timestamp"}
+		if col.Tag {
+			if err := q.tagStat(latest, table, &col); err != nil {
+				return nil, err
+			}
+			if col.Cardinality > cardinalityWarn {
+				s.Warnings = append(s.Warnings, fmt.Sprintf("tag %s cardinality %d exceeds %d",
+					col.Name, col.Cardinality, cardinalityWarn))
+			}
+		}
+		s.Columns = append(s.Columns, col)
+	}
+
+	return s, nil
+}
+
+// tagStat 统计 tag 基数以及取值样例
+func (q *Sqliter) tagStat(db *DebugDB, table string, col *ColumnSchema) (err error) {
+	col.Cardinality, err = queryInt(db, fmt.Sprintf("select count(distinct %q) from %q", col.Name, table))
+	if err != nil {
+		return err
+	}
+
+	r, err := db.Query(nil, fmt.Sprintf("select distinct %q from %q limit 5", col.Name, table))
+	if err != nil {
+		return err
+	}
+	for _, row := range r.StringRows() {
+		sample := row[0]
+		// tag 取值经过序号库转换为数字存储时，还原为原始字符串
+		if q.SeqKeysDB != nil && strings.EqualFold(col.Type, "NUMERIC") {
+			if key, err := q.SeqKeysDB.Find(sample); err == nil {
+				sample = key
+			}
+		}
+		col.Samples = append(col.Samples, sample)
+	}
+	return nil
+}
+
+func queryInt(db *DebugDB, query string) (int64, error) {
+	r, err := db.Query(nil, query)
+	if err != nil {
+		return 0, err
+	}
+	if rows := r.StringRows(); len(rows) > 0 && len(rows[0]) > 0 {
+		return ss.Parse[int64](rows[0][0])
+	}
+	return 0, nil
+}
+
+// PrintSchema 以表格形式打印 Schema 的结果
+func PrintSchema(w io.Writer, schemas []TableSchema) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range schemas {
+		_, _ = fmt.Fprintf(tw, "# %s\n", s.Table)
+		_, _ = fmt.Fprintln(tw, "COLUMN\tTYPE\tKIND\tCARDINALITY\tSAMPLES")
+		for _, c := range s.Columns {
+			kind, cardinality := "field", "-"
+			if c.Tag {
+				kind, cardinality = "tag", fmt.Sprintf("%d", c.Cardinality)
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Name, c.Type, kind, cardinality, strings.Join(c.Samples, ","))
+		}
+		_, _ = fmt.Fprintln(tw, "PARTITION\tROWS\tSIZE")
+		for _, p := range s.Partitions {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\n", p.DividedBy, p.Rows, ss.IBytes(uint64(p.Size)))
+		}
+		for _, warn := range s.Warnings {
+			_, _ = fmt.Fprintf(tw, "W! %s\n", warn)
+		}
+		_, _ = fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,54 @@
+package sqliter
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/ngg/sqliter/influx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchema(t *testing.T) {
+	prefix := t.TempDir() + "/schema.t"
+	plus, err := New(WithPrefix(prefix))
+	assert.Nil(t, err)
+
+	from := time.Date(2024, 7, 30, 0, 0, 0, 0, time.Local)
+	for i := 0; i < 6; i++ {
+		p := influx.NewPoint("cpu", map[string]string{"host": fmt.Sprintf("h%d", i%3)},
+			map[string]any{"usage": i}, from.AddDate(0, 0, i))
+		assert.Nil(t, plus.WriteMetric(p))
+	}
+	assert.Nil(t, plus.Close())
+
+	plus, err = New(WithPrefix(prefix))
+	assert.Nil(t, err)
+	defer plus.Close()
+
+	schemas, err := plus.Schema(2)
+	assert.Nil(t, err)
+	assert.Len(t, schemas, 1)
+
+	s := schemas[0]
+	assert.Equal(t, "cpu", s.Table)
+	assert.Equal(t, []string{"month.202407", "month.202408"}, []string{s.Partitions[0].DividedBy, s.Partitions[1].DividedBy})
+	assert.Equal(t, []int64{2, 4}, []int64{s.Partitions[0].Rows, s.Partitions[1].Rows})
+
+	columns := map[string]ColumnSchema{}
+	for _, c := range s.Columns {
+		columns[c.Name] = c
+	}
+	assert.False(t, columns["timestamp"].Tag)
+	assert.False(t, columns["usage"].Tag)
+	host := columns["host"]
+	assert.True(t, host.Tag)
+	assert.Equal(t, int64(3), host.Cardinality)
+	assert.ElementsMatch(t, []string{"h0", "h1", "h2"}, host.Samples)
+	assert.Equal(t, []string{"tag host cardinality 3 exceeds 2"}, s.Warnings)
+
+	var buf bytes.Buffer
+	assert.Nil(t, PrintSchema(&buf, schemas))
+	assert.Contains(t, buf.String(), "W! tag host cardinality 3 exceeds 2")
+}
@@ -21,7 +21,13 @@ func New(fns ...ConfigFn) (*Sqliter, error) {
 		readDbs:  make(map[string]*readTable),
 		writeDbs: make(map[string]*writeTable),
 	}
-	go plus.recycleLoop()
+	if len(config.Rollups) > 0 {
+		plus.rollups = newRollupState(config.Rollups)
+	}
+	// 在启动协程前创建好取消函数，避免 New 后立即 Close 时 recycleCancel 为 nil
+	ctx, cancel := context.WithCancel(context.Background())
+	plus.recycleCancel = cancel
+	go plus.recycleLoop(ctx)
 
 	return plus, nil
 }
@@ -83,6 +89,9 @@ type Config struct {
 	RecycleInterval time.Duration
 	// DividedBy 按时间分库模式
 	DividedBy
+
+	// Rollups 降采样汇总定义，例如 cpu 按 1m, 1h 汇总到 cpu_1m, cpu_1h 表
+	Rollups []Rollup
 }
 
 const (
@@ -210,6 +219,9 @@ type Sqliter struct {
 
 	// recycleCancel 用于取消回收循环协程
 	recycleCancel context.CancelFunc
+
+	// rollups 降采样汇总状态，没有汇总定义时为 nil
+	rollups *rollupState
 }
 
 type ConfigFn func(*Config)
@@ -224,3 +236,4 @@ func WithAllowDBErrors(val []string) ConfigFn { return func(c *Config) { c.Allow
 func WithSeqKeysDBName(val string) ConfigFn   { return func(c *Config) { c.SeqKeysDBName = val } }
 func WithSeqKeysDB(val *BoltSeq) ConfigFn     { return func(c *Config) { c.SeqKeysDB = val } }
 func WithDividedBy(val DividedBy) ConfigFn    { return func(c *Config) { c.DividedBy = val } }
+func WithRollups(val ...Rollup) ConfigFn      { return func(c *Config) { c.Rollups = val } }
@@ -166,6 +166,9 @@ func (d *writeTable) makeSureTableCreated(metric influx.Metric, seq *BoltSeq, ta
 	d.Tags = ss.ToSet(ti.Tags)
 	d.Fields = ss.ToSet(pie.Filter(headers, func(s string) bool { return !d.Tags[s] }))
 	d.onConflictFn = OnConflictGen(d.Tags)
+	if d.isRollupTable(d.Table) {
+		d.onConflictFn = rollupOnConflictGen(d.Tags)
+	}
 }
 
 func (d *writeTable) prepareQuery(query string, columns int, onConflict string, metric influx.Metric) (*Prepared, error) {
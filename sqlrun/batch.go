@@ -0,0 +1,372 @@
+package sqlrun
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bingoohuang/ngg/ss"
+)
+
+// Dialect 数据库方言，用于生成批量插入/更新 SQL
+type Dialect int
+
+const (
+	// DialectSQLite SQLite: ? 占位符, ON CONFLICT 更新
+	DialectSQLite Dialect = iota
+	// DialectMySQL MySQL: ? 占位符, ON DUPLICATE KEY UPDATE 更新
+	DialectMySQL
+	// DialectPostgres PostgreSQL: $n 占位符, ON CONFLICT 更新
+	DialectPostgres
+	// DialectOracle Oracle: :n 占位符, INSERT ALL 插入, MERGE 更新
+	DialectOracle
+	// DialectDM 达梦: ? 占位符, INSERT ALL 插入, MERGE 更新
+	DialectDM
+)
+
+// ParseDialect 根据驱动名称识别数据库方言，例如 sqlite3, mysql, pgx, postgres, godror, oracle, dm
+func ParseDialect(driverName string) (Dialect, error) {
+	switch name := strings.ToLower(driverName); {
+	case strings.HasPrefix(name, "sqlite"):
+		return DialectSQLite, nil
+	case name == "mysql":
+		return DialectMySQL, nil
+	case name == "postgres" || name == "pgx" || name == "pq":
+		return DialectPostgres, nil
+	case name == "oracle" || name == "godror" || name == "oci8":
+		return DialectOracle, nil
+	case name == "dm":
+		return DialectDM, nil
+	}
+
+	return 0, fmt.Errorf("unknown dialect of driver %s", driverName)
+}
+
+// MaxParams 单条语句最大绑定参数个数
+func (d Dialect) MaxParams() int {
+	switch d {
+	case DialectSQLite:
+		// SQLite 3.32.0 之后是 32766，之前是 999
+		return 32766
+	default:
+		return 65535
+	}
+}
+
+// placeholder 第 n 个（从1开始）绑定参数的占位符
+func (d Dialect) placeholder(n int) string {
+	switch d {
+	case DialectPostgres:
+		return "$" + strconv.Itoa(n)
+	case DialectOracle:
+		return ":" + strconv.Itoa(n)
+	default:
+		return "?"
+	}
+}
+
+// quote 引用标识符，Oracle/达梦引用后大小写敏感，因此不引用
+func (d Dialect) quote(name string) string {
+	switch d {
+	case DialectMySQL:
+		return "`" + name + "`"
+	case DialectSQLite, DialectPostgres:
+		return strconv.Quote(name)
+	default:
+		return name
+	}
+}
+
+// DefaultBatchChunkSize 默认每条语句的最大行数
+const DefaultBatchChunkSize = 1000
+
+// BatchOptions 批量插入/更新选项
+type BatchOptions struct {
+	// Dialect 数据库方言
+	Dialect Dialect
+	// Table 表名
+	Table string
+	// Columns 插入的列，为空时取自结构体字段（name/db 标签，未打标签时为字段名的蛇形形式）或者 map 的键（按字母排序，各行的键需要相同）
+	Columns []string
+	// Keys 冲突判断的唯一键列，非空时执行 upsert
+	Keys []string
+	// UpdateColumns 冲突时更新的列，为空时更新 Columns 中除 Keys 外的全部列
+	UpdateColumns []string
+	// ChunkSize 每条语句的最大行数，默认 DefaultBatchChunkSize，同时受到 Dialect.MaxParams 的限制
+	ChunkSize int
+}
+
+// ChunkResult 单个分块的执行结果
+type ChunkResult struct {
+	// Rows 分块中的行数
+	Rows int
+	// RowsAffected 影响行数，MySQL 的 upsert 中，更新的行计为 2
+	RowsAffected int64
+}
+
+// BatchResult 批量执行结果
+type BatchResult struct {
+	Chunks       []ChunkResult
+	RowsAffected int64
+}
+
+// ContextExecDB 支持 context 的执行接口，*sql.DB, *sql.Tx, *sql.Conn 都实现了该接口
+type ContextExecDB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// BatchInsert 按方言生成多行 INSERT（或者 upsert）语句，按分块批量执行
+// rows 可以是结构体切片、结构体指针切片或者 []map[string]any；结构体中嵌套的非匿名结构体字段不参与插入
+// 出错时停止执行，返回已经执行的分块结果以及错误
+func BatchInsert(ctx context.Context, db ContextExecDB, opts BatchOptions, rows any) (*BatchResult, error) {
+	columns, values, err := batchValues(opts.Columns, rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns to insert into %s", opts.Table)
+	}
+	opts.Columns = columns
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultBatchChunkSize
+	}
+	chunkSize = max(1, min(chunkSize, opts.Dialect.MaxParams()/len(columns)))
+
+	result := &BatchResult{}
+	for start := 0; start < len(values); start += chunkSize {
+		chunk := values[start:min(start+chunkSize, len(values))]
+		query := opts.batchSQL(len(chunk))
+		args := make([]any, 0, len(chunk)*len(columns))
+		for _, row := range chunk {
+			args = append(args, row...)
+		}
+
+		r, err := db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return result, fmt.Errorf("batch insert rows [%d, %d) into %s: %w", start, start+len(chunk), opts.Table, err)
+		}
+
+		affected, _ := r.RowsAffected()
+		result.Chunks = append(result.Chunks, ChunkResult{Rows: len(chunk), RowsAffected: affected})
+		result.RowsAffected += affected
+	}
+
+	return result, nil
+}
+
+// batchValues 从 rows 中提取列以及每行的值
+func batchValues(columns []string, rows any) ([]string, [][]any, error) {
+	if maps, ok := rows.([]map[string]any); ok {
+		if len(columns) == 0 && len(maps) > 0 {
+			for k := range maps[0] {
+				columns = append(columns, k)
+			}
+			sort.Strings(columns)
+
+			// 未指定列时，各行的键必须与第一行相同，避免缺少的键被静默插入 NULL
+			for i, m := range maps[1:] {
+				if len(m) != len(columns) {
+					return nil, nil, fmt.Errorf("row %d keys mismatch with row 0 columns %v", i+1, columns)
+				}
+				for _, col := range columns {
+					if _, ok := m[col]; !ok {
+						return nil, nil, fmt.Errorf("row %d keys mismatch with row 0 columns %v", i+1, columns)
+					}
+				}
+			}
+		}
+
+		values := make([][]any, len(maps))
+		for i, m := range maps {
+			values[i] = make([]any, len(columns))
+			for j, col := range columns {
+				values[i][j] = m[col]
+			}
+		}
+		return columns, values, nil
+	}
+
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice || derefType(v.Type().Elem()).Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("rows should be struct slice or []map[string]any, got %T", rows)
+	}
+
+	var paths []*fieldPath
+	all := structFieldPaths(derefType(v.Type().Elem()))
+	if len(columns) == 0 {
+		for i, p := range all {
+			if strings.Contains(p.Key, ".") {
+				continue
+			}
+			paths = append(paths, &all[i])
+			// 未打标签的字段，列名为字段名的蛇形形式，例如 UserName 对应 user_name
+			if p.Tagged {
+				columns = append(columns, p.Key)
+			} else {
+				columns = append(columns, ss.ToSnake(p.Key))
+			}
+		}
+	} else {
+		for _, col := range columns {
+			p := all.find(col)
+			if p == nil {
+				return nil, nil, fmt.Errorf("column %s not found in %s", col, v.Type().Elem())
+			}
+			paths = append(paths, p)
+		}
+	}
+
+	values := make([][]any, v.Len())
+	for i := range values {
+		elem := v.Index(i)
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		values[i] = make([]any, len(paths))
+		for j, p := range paths {
+			if f, err := elem.FieldByIndexErr(p.Index); err == nil {
+				values[i][j] = f.Interface()
+			}
+		}
+	}
+
+	return columns, values, nil
+}
+
+// batchSQL 生成 rows 行的批量插入（或者 upsert）语句
+func (o BatchOptions) batchSQL(rows int) string {
+	d := o.Dialect
+	quoted := make([]string, len(o.Columns))
+	for i, c := range o.Columns {
+		quoted[i] = d.quote(c)
+	}
+	columnList := strings.Join(quoted, ",")
+	table := d.quote(o.Table)
+
+	n := 0
+	rowBinds := func() string {
+		binds := make([]string, len(o.Columns))
+		for i := range binds {
+			n++
+			binds[i] = d.placeholder(n)
+		}
+		return strings.Join(binds, ",")
+	}
+
+	if d == DialectOracle || d == DialectDM {
+		if len(o.Keys) > 0 {
+			return o.mergeSQL(rows, rowBinds)
+		}
+
+		var sb strings.Builder
+		sb.WriteString("INSERT ALL")
+		for i := 0; i < rows; i++ {
+			sb.WriteString(" INTO " + table + " (" + columnList + ") VALUES (" + rowBinds() + ")")
+		}
+		sb.WriteString(" SELECT 1 FROM DUAL")
+		return sb.String()
+	}
+
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO " + table + " (" + columnList + ") VALUES ")
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("(" + rowBinds() + ")")
+	}
+
+	if len(o.Keys) == 0 {
+		return sb.String()
+	}
+
+	updates := o.updateColumns()
+	switch d {
+	case DialectMySQL:
+		if len(updates) == 0 { // 没有需要更新的列时，更新唯一键为自身，相当于忽略
+			updates = o.Keys[:1]
+		}
+		sets := make([]string, len(updates))
+		for i, c := range updates {
+			sets[i] = d.quote(c) + "=VALUES(" + d.quote(c) + ")"
+		}
+		sb.WriteString(" ON DUPLICATE KEY UPDATE " + strings.Join(sets, ","))
+	default:
+		keys := make([]string, len(o.Keys))
+		for i, k := range o.Keys {
+			keys[i] = d.quote(k)
+		}
+		sb.WriteString(" ON CONFLICT (" + strings.Join(keys, ",") + ")")
+		if len(updates) == 0 {
+			sb.WriteString(" DO NOTHING")
+			break
+		}
+		sets := make([]string, len(updates))
+		for i, c := range updates {
+			sets[i] = d.quote(c) + "=EXCLUDED." + d.quote(c)
+		}
+		sb.WriteString(" DO UPDATE SET " + strings.Join(sets, ","))
+	}
+
+	return sb.String()
+}
+
+// mergeSQL 生成 Oracle/达梦 的 MERGE 语句
+func (o BatchOptions) mergeSQL(rows int, rowBinds func() string) string {
+	var sb strings.Builder
+	sb.WriteString("MERGE INTO " + o.Table + " t USING (")
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			sb.WriteString(" UNION ALL ")
+		}
+		binds := strings.Split(rowBinds(), ",")
+		for j, c := range o.Columns {
+			binds[j] += " " + c
+		}
+		sb.WriteString("SELECT " + strings.Join(binds, ",") + " FROM DUAL")
+	}
+	sb.WriteString(") s ON (")
+	for i, k := range o.Keys {
+		if i > 0 {
+			sb.WriteString(" AND ")
+		}
+		sb.WriteString("t." + k + "=s." + k)
+	}
+	sb.WriteString(")")
+
+	if updates := o.updateColumns(); len(updates) > 0 {
+		sets := make([]string, len(updates))
+		for i, c := range updates {
+			sets[i] = "t." + c + "=s." + c
+		}
+		sb.WriteString(" WHEN MATCHED THEN UPDATE SET " + strings.Join(sets, ","))
+	}
+
+	values := make([]string, len(o.Columns))
+	for i, c := range o.Columns {
+		values[i] = "s." + c
+	}
+	sb.WriteString(" WHEN NOT MATCHED THEN INSERT (" + strings.Join(o.Columns, ",") + ") VALUES (" + strings.Join(values, ",") + ")")
+	return sb.String()
+}
+
+func (o BatchOptions) updateColumns() []string {
+	if len(o.UpdateColumns) > 0 {
+		return o.UpdateColumns
+	}
+
+	var updates []string
+	for _, c := range o.Columns {
+		if !slices.Contains(o.Keys, c) {
+			updates = append(updates, c)
+		}
+	}
+	return updates
+}
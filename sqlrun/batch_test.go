@@ -0,0 +1,119 @@
+package sqlrun_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/bingoohuang/ngg/sqlrun"
+	"github.com/stretchr/testify/assert"
+)
+
+type product struct {
+	ID    int64   `db:"id"`
+	Name  string  `db:"name"`
+	Price float64 `db:"price"`
+	Skip  string  `db:"-"`
+}
+
+func TestBatchInsert(t *testing.T) {
+	d, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	defer d.Close()
+
+	_, err = d.Exec(`create table product(id integer primary key, name text, price real)`)
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	var rows []product
+	for i := 1; i <= 5; i++ {
+		rows = append(rows, product{ID: int64(i), Name: fmt.Sprintf("p%d", i), Price: float64(i)})
+	}
+	opts := sqlrun.BatchOptions{Dialect: sqlrun.DialectSQLite, Table: "product", ChunkSize: 2}
+	r, err := sqlrun.BatchInsert(ctx, d, opts, rows)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), r.RowsAffected)
+	assert.Equal(t, []sqlrun.ChunkResult{{Rows: 2, RowsAffected: 2}, {Rows: 2, RowsAffected: 2}, {Rows: 1, RowsAffected: 1}}, r.Chunks)
+
+	// 主键冲突
+	_, err = sqlrun.BatchInsert(ctx, d, opts, []*product{{ID: 1, Name: "dup"}})
+	assert.NotNil(t, err)
+
+	// upsert: 只更新 price
+	opts.Keys = []string{"id"}
+	opts.UpdateColumns = []string{"price"}
+	r, err = sqlrun.BatchInsert(ctx, d, opts, []map[string]any{
+		{"id": 1, "name": "new1", "price": 10.5},
+		{"id": 6, "name": "p6", "price": 6},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), r.RowsAffected)
+
+	var got []product
+	assert.Nil(t, sqlrun.QueryEach(ctx, d, "select * from product where id in (1, 6) order by id", func(p product) error {
+		got = append(got, p)
+		return nil
+	}))
+	assert.Equal(t, []product{{ID: 1, Name: "p1", Price: 10.5}, {ID: 6, Name: "p6", Price: 6}}, got)
+
+	// 没有更新列时忽略冲突
+	opts.Columns = []string{"id"}
+	opts.UpdateColumns = nil
+	r, err = sqlrun.BatchInsert(ctx, d, opts, rows[:2])
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), r.RowsAffected)
+}
+
+type recordDB struct {
+	queries []string
+}
+
+func (r *recordDB) ExecContext(_ context.Context, query string, _ ...any) (sql.Result, error) {
+	r.queries = append(r.queries, query)
+	return driverResult(1), nil
+}
+
+type driverResult int64
+
+func (r driverResult) LastInsertId() (int64, error) { return 0, nil }
+func (r driverResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func TestBatchInsertDialects(t *testing.T) {
+	rows := []map[string]any{{"id": 1, "name": "a"}, {"id": 2, "name": "b"}}
+	cases := []struct {
+		dialect sqlrun.Dialect
+		keys    []string
+		want    string
+	}{
+		{sqlrun.DialectMySQL, nil, "INSERT INTO `tb` (`id`,`name`) VALUES (?,?),(?,?)"},
+		{sqlrun.DialectMySQL, []string{"id"}, "INSERT INTO `tb` (`id`,`name`) VALUES (?,?),(?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)"},
+		{sqlrun.DialectPostgres, []string{"id"}, `INSERT INTO "tb" ("id","name") VALUES ($1,$2),($3,$4) ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name"`},
+		{sqlrun.DialectOracle, nil, "INSERT ALL INTO tb (id,name) VALUES (:1,:2) INTO tb (id,name) VALUES (:3,:4) SELECT 1 FROM DUAL"},
+		{sqlrun.DialectDM, []string{"id"}, "MERGE INTO tb t USING (SELECT ? id,? name FROM DUAL UNION ALL SELECT ? id,? name FROM DUAL) s ON (t.id=s.id)" +
+			" WHEN MATCHED THEN UPDATE SET t.name=s.name WHEN NOT MATCHED THEN INSERT (id,name) VALUES (s.id,s.name)"},
+	}
+
+	for _, c := range cases {
+		db := &recordDB{}
+		_, err := sqlrun.BatchInsert(context.Background(), db, sqlrun.BatchOptions{Dialect: c.dialect, Table: "tb", Keys: c.keys}, rows)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{c.want}, db.queries)
+	}
+
+	db := &recordDB{}
+	type user struct {
+		ID       int64 `db:"id"`
+		UserName string
+	}
+	_, err := sqlrun.BatchInsert(context.Background(), db, sqlrun.BatchOptions{Dialect: sqlrun.DialectPostgres, Table: "tb"}, []user{{ID: 1, UserName: "a"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`INSERT INTO "tb" ("id","user_name") VALUES ($1,$2)`}, db.queries)
+
+	_, err = sqlrun.BatchInsert(context.Background(), db, sqlrun.BatchOptions{Table: "tb"}, []map[string]any{{"id": 1}, {"id": 2, "name": "b"}})
+	assert.NotNil(t, err)
+
+	d, err := sqlrun.ParseDialect("pgx")
+	assert.Nil(t, err)
+	assert.Equal(t, sqlrun.DialectPostgres, d)
+}
@@ -0,0 +1,36 @@
+//go:build go1.23
+
+package sqlrun
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+var errStopIter = errors.New("stop iteration")
+
+// QueryIter 与 QueryEach 一样流式执行查询，以 Go 1.23 的迭代器形式返回结果
+//
+//	for row, err := range sqlrun.QueryIter[User](ctx, db, "select * from user where age > :age", map[string]any{"age": 18}) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// 出错时，最后一次迭代返回 T 的零值及错误；提前 break 时，释放查询资源
+func QueryIter[T any](ctx context.Context, db ContextDB, query string, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		err := QueryEach(ctx, db, query, func(row T) error {
+			if !yield(row, nil) {
+				return errStopIter
+			}
+			return nil
+		}, args...)
+		if err != nil && !errors.Is(err, errStopIter) {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+//go:build go1.23
+
+package sqlrun_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bingoohuang/ngg/sqlrun"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryIter(t *testing.T) {
+	d := openUsers(t)
+
+	var names []string
+	for u, err := range sqlrun.QueryIter[user](context.Background(), d, "select id, name from users order by id") {
+		assert.Nil(t, err)
+		names = append(names, u.Name)
+		if len(names) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"bingoo", "huang"}, names)
+
+	for _, err := range sqlrun.QueryIter[user](context.Background(), d, "select * from no_such_table") {
+		assert.NotNil(t, err)
+	}
+}
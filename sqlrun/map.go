@@ -54,6 +54,17 @@ func (m *MapMapping) RowsData() any { return m.rowsData }
 
 // Scan scans the rows one by one.
 func (m *MapMapping) Scan(rowNum int) error {
+	values, err := m.scanRow()
+	if err != nil {
+		return err
+	}
+
+	m.rowsData = append(m.rowsData, values)
+	return nil
+}
+
+// scanRow scans the current row to string values.
+func (m *MapMapping) scanRow() ([]string, error) {
 	holders := make([]sql.NullString, m.columnSize)
 	pointers := make([]any, m.columnSize)
 
@@ -63,7 +74,7 @@ func (m *MapMapping) Scan(rowNum int) error {
 
 	err := m.rows.Scan(pointers...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	values := make([]string, m.columnSize)
@@ -76,6 +87,5 @@ func (m *MapMapping) Scan(rowNum int) error {
 		}
 	}
 
-	m.rowsData = append(m.rowsData, values)
-	return nil
+	return values, nil
 }
@@ -15,16 +15,23 @@ type selectItem interface {
 }
 
 type structItem struct {
-	field *reflect.StructField
+	field *fieldPath
 	root  reflect.Value
 }
 
 func (s *structItem) Type() reflect.Type         { return s.field.Type }
 func (s *structItem) SetRoot(root reflect.Value) { s.root = root }
 func (s *structItem) SetField(val reflect.Value) {
-	s.root.FieldByIndex(s.field.Index).Set(val.Convert(s.field.Type))
+	fieldByIndexAlloc(s.root, s.field.Index).Set(val.Convert(s.field.Type))
 }
 
+// discardItem 用于没有对应结构体字段的查询列，扫描后丢弃
+type discardItem struct{}
+
+func (discardItem) Type() reflect.Type     { return nil }
+func (discardItem) SetRoot(reflect.Value)  {}
+func (discardItem) SetField(reflect.Value) {}
+
 // mapping defines the interface for SQL query processing.
 type mapping interface {
 	Scan(rowNum int) error
@@ -76,26 +83,111 @@ func (m *structPreparer) newStructFields(columns []string) selectItemSlice {
 
 // newStructField creates a new struct field.
 func (m *structPreparer) newStructField(col string) selectItem {
-	fv, ok := m.StructType.FieldByNameFunc(func(field string) bool {
-		return m.matchesField2Col(field, col)
-	})
+	if fp := m.fields.find(col); fp != nil {
+		return &structItem{field: fp}
+	}
+
+	return discardItem{}
+}
+
+// fieldPath 结构体(包括嵌套结构体)中的一个可映射字段
+type fieldPath struct {
+	// Key 映射的列名，嵌套结构体字段以 . 连接，例如 address.city
+	Key string
+	// Tagged 是否通过 db/name 标签指定了列名
+	Tagged bool
+	// Index 字段的索引路径
+	Index []int
+	Type  reflect.Type
+}
+
+type fieldPaths []fieldPath
+
+// structFieldPaths 解析结构体的可映射字段
+// 列名取自 name 标签，其次是 db 标签，再次是字段名；db 标签为 - 时忽略该字段
+// 匿名嵌入的结构体字段平铺，其它嵌套结构体字段以 "前缀." 的形式映射，例如 db:"address" 的 City 字段映射列 address.city
+func structFieldPaths(t reflect.Type) fieldPaths {
+	var paths fieldPaths
+	walkStructFields(t, "", nil, &paths)
+	return paths
+}
+
+// fieldTag 取得字段映射的列名标签，name:"-" 与未设置 name 标签相同
+func fieldTag(f reflect.StructField) (tag string, skip bool) {
+	if name := f.Tag.Get("name"); name != "" && name != "-" {
+		return name, false
+	}
+	db, _, _ := strings.Cut(f.Tag.Get("db"), ",")
+	return db, db == "-"
+}
+
+func walkStructFields(t reflect.Type, prefix string, index []int, paths *fieldPaths) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, skip := fieldTag(f)
+		if skip || !f.IsExported() && !f.Anonymous {
+			continue
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+		if base := derefType(f.Type); base.Kind() == reflect.Struct && base != timeType && !implSQLScanner(f.Type) {
+			nestedPrefix := prefix
+			if !f.Anonymous || tag != "" {
+				nestedPrefix += ss.Or(tag, f.Name) + "."
+			}
+			walkStructFields(base, nestedPrefix, fieldIndex, paths)
+			continue
+		}
+
+		if f.IsExported() {
+			*paths = append(*paths, fieldPath{Key: prefix + ss.Or(tag, f.Name), Tagged: tag != "", Index: fieldIndex, Type: f.Type})
+		}
+	}
+}
 
-	if ok {
-		return &structItem{field: &fv}
+// find 查找列 col 对应的字段，先按列名精确（忽略大小写）匹配，再将列名转换为驼峰后匹配未打标签的字段
+func (paths fieldPaths) find(col string) *fieldPath {
+	for i, p := range paths {
+		if strings.EqualFold(p.Key, col) {
+			return &paths[i]
+		}
+	}
+
+	parts := strings.Split(col, ".")
+	for i, part := range parts {
+		parts[i] = ss.ToCamel(part)
+	}
+	camel := strings.Join(parts, ".")
+	for i, p := range paths {
+		if !p.Tagged && strings.EqualFold(p.Key, camel) {
+			return &paths[i]
+		}
 	}
 
 	return nil
 }
 
-func (m *structPreparer) matchesField2Col(field, col string) bool {
-	f, _ := m.StructType.FieldByName(field)
-	if v := f.Tag.Get("name"); v != "" && v != "-" {
-		return v == col
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
+	return t
+}
 
-	eq := strings.EqualFold
-
-	return eq(field, col) || eq(field, ss.ToCamel(col))
+// fieldByIndexAlloc 与 reflect.Value.FieldByIndex 类似，但是会为路径上的 nil 结构体指针分配内存
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 {
+			for v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+	return v
 }
 
 // ContainsFold tell if a contains b in case-insensitively.
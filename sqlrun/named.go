@@ -0,0 +1,135 @@
+package sqlrun
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NamedArgs 指定方言的命名参数，作为 QueryEach/QueryIter 的唯一参数时，按方言 Dialect 的占位符绑定
+type NamedArgs struct {
+	Dialect Dialect
+	// Arg map[string]any 或者结构体（及其指针）
+	Arg any
+}
+
+// BindNamed 将查询中的命名参数 :name 替换为方言 d 的占位符（? 或者 PostgreSQL 的 $n，Oracle 的 :n），并从 arg 中按名字取得绑定参数
+// arg 可以是 map[string]any，或者结构体（及其指针），结构体按 name/db 标签或字段名取值，嵌套结构体字段使用 :address.city 的形式
+// 单引号字符串、双引号标识符中的内容，以及 PostgreSQL 的类型转换 ::type 不作处理
+func BindNamed(d Dialect, query string, arg any) (string, []any, error) {
+	lookup, err := namedLookup(arg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var sb strings.Builder
+	var args []any
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			// PostgreSQL 类型转换 ::type
+			sb.WriteString("::")
+			i++
+			continue
+		case c == ':' && i+1 < len(query) && isNameStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && isNameChar(query[j]) {
+				j++
+			}
+			name := strings.TrimRight(query[i+1:j], ".")
+			v, ok := lookup(name)
+			if !ok {
+				return "", nil, fmt.Errorf("named parameter :%s not found", name)
+			}
+			args = append(args, v)
+			sb.WriteString(d.placeholder(len(args)))
+			i += len(name)
+			continue
+		}
+		sb.WriteByte(c)
+	}
+
+	return sb.String(), args, nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || '0' <= c && c <= '9' || c == '.'
+}
+
+func namedLookup(arg any) (func(name string) (any, bool), error) {
+	if m, ok := arg.(map[string]any); ok {
+		return func(name string) (any, bool) {
+			v, ok := m[name]
+			return v, ok
+		}, nil
+	}
+
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("named arg should be map[string]any or struct, got %T", arg)
+	}
+
+	paths := structFieldPaths(v.Type())
+	return func(name string) (any, bool) {
+		p := paths.find(name)
+		if p == nil {
+			return nil, false
+		}
+
+		f, err := v.FieldByIndexErr(p.Index)
+		if err != nil { // 路径上有 nil 指针
+			return nil, true
+		}
+		return f.Interface(), true
+	}, nil
+}
+
+var (
+	driverValuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	sqlNamedArgType  = reflect.TypeOf(sql.NamedArg{})
+)
+
+// isNamedArg 判断 arg 是否是命名参数的容器，即 map[string]any 或者普通结构体（不是 time.Time、sql.NamedArg 以及 driver.Valuer 之类的值类型）
+func isNamedArg(arg any) bool {
+	if _, ok := arg.(map[string]any); ok {
+		return true
+	}
+
+	t := reflect.TypeOf(arg)
+	if t == nil || t.Implements(driverValuerType) {
+		return false
+	}
+	t = derefType(t)
+	return t.Kind() == reflect.Struct && t != timeType && t != sqlNamedArgType && !implSQLScanner(t)
+}
+
+// bindArgs 当唯一的参数是命名参数容器时，绑定查询中的命名参数
+// 直接传入 map 或者结构体时使用 ? 占位符，其它方言需要使用 NamedArgs
+func bindArgs(query string, args []any) (string, []any, error) {
+	if len(args) == 1 {
+		if n, ok := args[0].(NamedArgs); ok {
+			return BindNamed(n.Dialect, query, n.Arg)
+		}
+		if isNamedArg(args[0]) {
+			return BindNamed(DialectSQLite, query, args[0])
+		}
+	}
+	return query, args, nil
+}
@@ -2,7 +2,10 @@ package sqlrun
 
 import (
 	"database/sql"
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -44,14 +47,32 @@ func (n *nullAny) Scan(value any) error {
 		}
 
 		n.Val = reflect.ValueOf(sn.String).Convert(n.Type)
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		sn := &sql.NullInt32{}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		sn := &sql.NullInt64{}
 		if err := sn.Scan(value); err != nil {
 			return err
 		}
 
-		n.Val = reflect.ValueOf(sn.Int32).Convert(n.Type)
+		n.Val = reflect.New(n.Type).Elem()
+		if n.Val.OverflowInt(sn.Int64) {
+			return fmt.Errorf("converting %v to a %s: value out of range", value, n.Type)
+		}
+		n.Val.SetInt(sn.Int64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		sn := &sql.NullString{}
+		if err := sn.Scan(value); err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(sn.String, "-") {
+			return fmt.Errorf("converting %v to a %s: value out of range", value, n.Type)
+		}
+		u, err := strconv.ParseUint(sn.String, 10, n.Type.Bits())
+		if err != nil {
+			return fmt.Errorf("converting %v to a %s: %w", value, n.Type, err)
+		}
+		n.Val = reflect.New(n.Type).Elem()
+		n.Val.SetUint(u)
 	case reflect.Float32, reflect.Float64:
 		sn := &sql.NullFloat64{}
 		if err := sn.Scan(value); err != nil {
@@ -0,0 +1,106 @@
+package sqlrun
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// ContextDB 支持 context 的查询接口，*sql.DB, *sql.Tx, *sql.Conn 都实现了该接口
+type ContextDB interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// QueryEach 流式执行查询，逐行回调 fn，不在内存中保留全部结果集，适合导出大量数据
+// T 可以是:
+//
+//	[]string        NULL 值为空字符串
+//	map[string]any  列名 => 值，[]byte 转换为 string
+//	结构体或者结构体指针，按 db/name 标签或者字段名映射，支持嵌套结构体
+//
+// args 只有一个并且是 map[string]any 或者结构体时，按命名参数 :name 绑定，见 BindNamed
+// fn 返回错误时，停止查询并返回该错误; ctx 取消时，返回 ctx 的错误
+func QueryEach[T any](ctx context.Context, db ContextDB, query string, fn func(row T) error, args ...any) error {
+	query, args, err := bindArgs(query, args)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	scan, err := newRowScanner[T](rows, columns)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		row, err := scan()
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// newRowScanner 根据 T 的类型创建行扫描函数
+func newRowScanner[T any](rows *sql.Rows, columns []string) (func() (T, error), error) {
+	var zero T
+	t := reflect.TypeOf(&zero).Elem()
+
+	switch {
+	case t == reflect.TypeOf([]string(nil)):
+		m := newStrPreparer("").Prepare(rows, columns).(*MapMapping)
+		return func() (T, error) {
+			values, err := m.scanRow()
+			return any(values).(T), err
+		}, nil
+	case t == reflect.TypeOf(map[string]any(nil)):
+		return func() (T, error) {
+			values := make([]any, len(columns))
+			pointers := make([]any, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			if err := rows.Scan(pointers...); err != nil {
+				return zero, err
+			}
+
+			row := make(map[string]any, len(columns))
+			for i, col := range columns {
+				if b, ok := values[i].([]byte); ok {
+					values[i] = string(b)
+				}
+				row[col] = values[i]
+			}
+			return any(row).(T), nil
+		}, nil
+	case derefType(t).Kind() == reflect.Struct:
+		m := newStructPreparer(reflect.New(derefType(t)).Interface()).Prepare(rows, columns).(*structMapping)
+		return func() (T, error) {
+			elem, err := m.scanRow()
+			if err != nil {
+				return zero, err
+			}
+			if t.Kind() == reflect.Ptr {
+				return elem.Addr().Interface().(T), nil
+			}
+			return elem.Interface().(T), nil
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported row type %s", t)
+}
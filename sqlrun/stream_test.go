@@ -0,0 +1,162 @@
+package sqlrun_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/bingoohuang/ngg/sqlrun"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+type address struct {
+	City string `db:"city"`
+	Zip  string
+}
+
+type base struct {
+	ID int64 `db:"id"`
+}
+
+type user struct {
+	base
+	Name    string   `db:"name"`
+	Age     int      `db:"age"`
+	Address address  `db:"address"`
+	Work    *address `db:"work"`
+	Ignored string   `db:"-"`
+}
+
+func openUsers(t *testing.T) *sql.DB {
+	d, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	t.Cleanup(func() { d.Close() })
+
+	_, err = d.Exec(`create table users(id integer primary key, name text, age int, city text, zip text, work_city text)`)
+	assert.Nil(t, err)
+	for _, u := range []user{
+		{base: base{ID: 1}, Name: "bingoo", Age: 18, Address: address{City: "beijing", Zip: "100000"}, Work: &address{City: "shanghai"}},
+		{base: base{ID: 2}, Name: "huang", Age: 30, Address: address{City: "nanjing", Zip: "210000"}},
+		{base: base{ID: 3}, Name: "kitty", Age: 40},
+	} {
+		work := ""
+		if u.Work != nil {
+			work = u.Work.City
+		}
+		_, err := sqlrun.Query(d, "insert into users(id, name, age, city, zip, work_city) values (?, ?, ?, ?, ?, ?)",
+			u.ID, u.Name, u.Age, u.Address.City, u.Address.Zip, work)
+		assert.Nil(t, err)
+	}
+	return d
+}
+
+func TestQueryEach(t *testing.T) {
+	d := openUsers(t)
+	ctx := context.Background()
+	q := `select id, name, age, city as "address.city", zip as "address.zip", work_city as "work.city", 'x' as unknown
+		from users where age >= :age and name != ':name' order by id`
+
+	var users []user
+	err := sqlrun.QueryEach(ctx, d, q, func(u user) error {
+		users = append(users, u)
+		return nil
+	}, map[string]any{"age": 20})
+	assert.Nil(t, err)
+	assert.Equal(t, []user{
+		{base: base{ID: 2}, Name: "huang", Age: 30, Address: address{City: "nanjing", Zip: "210000"}, Work: &address{}},
+		{base: base{ID: 3}, Name: "kitty", Age: 40, Work: &address{}},
+	}, users)
+
+	var names [][]string
+	err = sqlrun.QueryEach(ctx, d, "select name, age from users where id <= :id order by id", func(row []string) error {
+		names = append(names, row)
+		return nil
+	}, user{base: base{ID: 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"bingoo", "18"}, {"huang", "30"}}, names)
+
+	var first *user
+	stop := errors.New("stop")
+	err = sqlrun.QueryEach(ctx, d, q, func(u *user) error {
+		first = u
+		return stop
+	}, map[string]any{"age": 0})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, "shanghai", first.Work.City)
+
+	var rows []map[string]any
+	err = sqlrun.QueryEach(ctx, d, "select id, name from users where id = ?", func(row map[string]any) error {
+		rows = append(rows, row)
+		return nil
+	}, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]any{{"id": int64(3), "name": "kitty"}}, rows)
+
+	// sql.NamedArg 交给驱动绑定，不作为命名参数的容器
+	rows = nil
+	err = sqlrun.QueryEach(ctx, d, "select id, name from users where id = :id", func(row map[string]any) error {
+		rows = append(rows, row)
+		return nil
+	}, sql.Named("id", 2))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]any{{"id": int64(2), "name": "huang"}}, rows)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = sqlrun.QueryEach(canceled, d, "select id from users", func([]string) error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestQueryEachLargeInt(t *testing.T) {
+	d := openUsers(t)
+	ctx := context.Background()
+	_, err := d.Exec(`insert into users(id, name, age) values (5000000000, 'large', 1)`)
+	assert.Nil(t, err)
+
+	var u user
+	err = sqlrun.QueryEach(ctx, d, "select id, name from users where id = :id", func(v user) error {
+		u = v
+		return nil
+	}, map[string]any{"id": 5000000000})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5000000000), u.ID)
+
+	type counter struct {
+		ID  uint64 `db:"id"`
+		Age int8   `db:"age"`
+	}
+	var c counter
+	err = sqlrun.QueryEach(ctx, d, "select id, age from users where id = 5000000000", func(v counter) error {
+		c = v
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, counter{ID: 5000000000, Age: 1}, c)
+
+	err = sqlrun.QueryEach(ctx, d, "select id as age from users where id = 5000000000", func(counter) error { return nil })
+	assert.ErrorContains(t, err, "out of range")
+	err = sqlrun.QueryEach(ctx, d, "select -1 as id", func(counter) error { return nil })
+	assert.ErrorContains(t, err, "out of range")
+}
+
+func TestBindNamed(t *testing.T) {
+	query := `select '{:x}', a::text from t where a = :a and b in (:b, :c.city) and d = ":d"`
+	arg := map[string]any{"a": 1, "b": "x", "c.city": "bj"}
+	q, args, err := sqlrun.BindNamed(sqlrun.DialectMySQL, query, arg)
+	assert.Nil(t, err)
+	assert.Equal(t, `select '{:x}', a::text from t where a = ? and b in (?, ?) and d = ":d"`, q)
+	assert.Equal(t, []any{1, "x", "bj"}, args)
+
+	q, _, err = sqlrun.BindNamed(sqlrun.DialectPostgres, query, arg)
+	assert.Nil(t, err)
+	assert.Equal(t, `select '{:x}', a::text from t where a = $1 and b in ($2, $3) and d = ":d"`, q)
+
+	q, _, err = sqlrun.BindNamed(sqlrun.DialectOracle, "select :a, :a from dual", arg)
+	assert.Nil(t, err)
+	assert.Equal(t, "select :1, :2 from dual", q)
+
+	_, _, err = sqlrun.BindNamed(sqlrun.DialectSQLite, "select :missing", map[string]any{})
+	assert.NotNil(t, err)
+}
@@ -18,12 +18,14 @@ func newStructPreparer(v any) *structPreparer {
 
 	return &structPreparer{
 		StructType: t,
+		fields:     structFieldPaths(t),
 	}
 }
 
 // structPreparer is the structure to create struct mapping.
 type structPreparer struct {
 	StructType reflect.Type
+	fields     fieldPaths
 }
 
 // Prepare prepares to scan query rows.
@@ -46,11 +48,22 @@ type structMapping struct {
 
 // Scan scans the query result to fetch the rows one by one.
 func (s *structMapping) Scan(rowNum int) error {
+	elem, err := s.scanRow()
+	if err != nil {
+		return err
+	}
+
+	s.rowsData = reflect.Append(s.rowsData, elem)
+	return nil
+}
+
+// scanRow scans the current row to a new struct value.
+func (s *structMapping) scanRow() (reflect.Value, error) {
 	pointers, structPtr := s.mapFields.ResetDestinations(s.structPreparer)
 
 	err := s.rows.Scan(pointers...)
 	if err != nil {
-		return err
+		return reflect.Value{}, err
 	}
 
 	for i, field := range s.mapFields {
@@ -61,10 +74,7 @@ func (s *structMapping) Scan(rowNum int) error {
 		}
 	}
 
-	elem := structPtr.Elem()
-	s.rowsData = reflect.Append(s.rowsData, elem)
-
-	return nil
+	return structPtr.Elem(), nil
 }
 
 // RowsData returns the mapped rows data.
@@ -77,8 +87,8 @@ func (mapFields selectItemSlice) ResetDestinations(mapper *structPreparer) ([]an
 	for i, fv := range mapFields {
 		fv.SetRoot(structPtr.Elem())
 
-		if implSQLScanner(fv.Type()) {
-			pointers[i] = reflect.New(fv.Type()).Interface()
+		if t := fv.Type(); t != nil && implSQLScanner(t) {
+			pointers[i] = reflect.New(t).Interface()
 		} else {
 			pointers[i] = &nullAny{Type: fv.Type()}
 		}
@@ -0,0 +1,75 @@
+package tick
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/ngg/ss"
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule describes a job's duty cycle.
+type Schedule interface {
+	// Next returns the next activation time, later than the given time.
+	// The zero time is returned when no time can be found.
+	Next(time.Time) time.Time
+}
+
+// EverySchedule is a schedule of fixed interval, like @every 5m.
+type EverySchedule struct {
+	Every time.Duration
+}
+
+// Next returns the time after the interval.
+func (s EverySchedule) Next(t time.Time) time.Time { return t.Add(s.Every) }
+
+// cronParser accepts 5 or 6 fields (the second is optional) and the descriptors like @daily.
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour |
+	cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ParseCron parses the cron expression into a schedule. The supported formats:
+//
+//	0 30 8 * * MON-FRI       6 fields: second minute hour day-of-month month day-of-week
+//	30 8 * * 1-5             5 fields: minute hour day-of-month month day-of-week, second is 0
+//	CRON_TZ=Asia/Shanghai 30 8 * * *   with time zone prefix, TZ= is also accepted
+//	@yearly @monthly @weekly @daily @midnight @hourly
+//	@every 1h30m             fixed interval, units d and w are also supported
+//
+// The expressions are parsed by github.com/robfig/cron/v3, day-of-week is 0-6 or SUN-SAT.
+func ParseCron(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	var loc *time.Location
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		tz, rest, _ := strings.Cut(spec, " ")
+		_, name, _ := strings.Cut(tz, "=")
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("bad time zone %s: %w", name, err)
+		}
+		loc, spec = l, strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, _, err := ss.ParseDur(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil {
+			return nil, fmt.Errorf("bad @every duration %s: %w", spec, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("non-positive @every duration %s", spec)
+		}
+		return EverySchedule{Every: d}, nil
+	}
+
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("bad cron expression %q: %w", spec, err)
+	}
+
+	if loc != nil {
+		schedule.(*cron.SpecSchedule).Location = loc
+	}
+
+	return schedule, nil
+}
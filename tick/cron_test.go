@@ -0,0 +1,103 @@
+package tick_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/ngg/tick"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	assert.Nil(t, err)
+
+	from := time.Date(2024, 1, 31, 10, 20, 30, 500, loc) // Wednesday
+	cases := []struct {
+		spec string
+		next string
+	}{
+		{"* * * * * *", "2024-01-31 10:20:31"},
+		{"*/15 * * * * *", "2024-01-31 10:20:45"},
+		{"30 8 * * *", "2024-02-01 08:30:00"},
+		{"0 0 9-17/4 * * MON-FRI", "2024-01-31 13:00:00"},
+		{"0 0 0 29 feb ?", "2024-02-29 00:00:00"},
+		{"0 0 1 1,15 * 6", "2024-02-01 01:00:00"}, // day of month or Saturday
+		{"@daily", "2024-02-01 00:00:00"},
+		{"@monthly", "2024-02-01 00:00:00"},
+		{"@yearly", "2025-01-01 00:00:00"},
+		{"CRON_TZ=UTC 0 0 * * *", "2024-02-01 08:00:00"},
+		{"@every 1h", "2024-01-31 11:20:30"},
+	}
+
+	for _, c := range cases {
+		s, err := tick.ParseCron(c.spec)
+		assert.Nil(t, err, c.spec)
+		assert.Equal(t, c.next, s.Next(from).Format(time.DateTime), c.spec)
+	}
+
+	for _, spec := range []string{"", "* * *", "60 * * * *", "* * * * 13", "5-1 * * * *", "*/0 * * * *", "@every x", "TZ=Bad/Zone * * * * *"} {
+		_, err := tick.ParseCron(spec)
+		assert.NotNil(t, err, spec)
+	}
+
+	s, _ := tick.ParseCron("0 0 0 30 2 *")
+	assert.True(t, s.Next(from).IsZero())
+}
+
+func countRuns(t *testing.T, policy tick.MissedPolicy) int32 {
+	var runs int32
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	err := tick.Cron(ctx, "@every 50ms", func(ctx context.Context) error {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			time.Sleep(260 * time.Millisecond) // misses about 5 runs
+		}
+		return nil
+	}, tick.WithMissedPolicy(policy))
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	return atomic.LoadInt32(&runs)
+}
+
+func TestCronMissedPolicy(t *testing.T) {
+	skip := countRuns(t, tick.MissedSkip)
+	once := countRuns(t, tick.MissedRunOnce)
+	catchUp := countRuns(t, tick.MissedCatchUp)
+
+	assert.True(t, skip < once, "skip %d, once %d", skip, once)
+	assert.True(t, once < catchUp, "once %d, catch up %d", once, catchUp)
+}
+
+func TestScheduler(t *testing.T) {
+	var s tick.Scheduler
+	var a, b, errs int32
+
+	_, err := s.Add("@every 20ms", func(ctx context.Context) error {
+		atomic.AddInt32(&a, 1)
+		panic("boom")
+	}, tick.WithErrorHandler(func(spec string, err error) { atomic.AddInt32(&errs, 1) }))
+	assert.Nil(t, err)
+
+	_, err = s.Add("bad", nil)
+	assert.NotNil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = s.Add("@every 20ms", func(ctx context.Context) error {
+			atomic.AddInt32(&b, 1)
+			return nil
+		})
+	}()
+
+	assert.Equal(t, context.DeadlineExceeded, s.Run(ctx))
+	assert.True(t, atomic.LoadInt32(&a) >= 3)
+	assert.Equal(t, atomic.LoadInt32(&a), atomic.LoadInt32(&errs))
+	assert.True(t, atomic.LoadInt32(&b) >= 1)
+}
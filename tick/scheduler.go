@@ -0,0 +1,193 @@
+package tick
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// MissedPolicy decides what to do with the runs missed,
+// because the previous run lasted too long, or the process (system) was paused.
+type MissedPolicy int
+
+const (
+	// MissedSkip skips the missed runs, and waits for the next scheduled time from now.
+	MissedSkip MissedPolicy = iota
+	// MissedCatchUp runs all the missed runs one after another, then continues the schedule.
+	MissedCatchUp
+	// MissedRunOnce runs once immediately for all the missed runs, then continues the schedule.
+	MissedRunOnce
+)
+
+// CronJob is a job scheduled by the cron expression.
+type CronJob struct {
+	Spec     string
+	schedule Schedule
+	f        func(ctx context.Context) error
+
+	policy   MissedPolicy
+	jitter   time.Duration
+	location *time.Location
+	onError  func(spec string, err error)
+}
+
+// CronOption is the option of CronJob.
+type CronOption func(*CronJob)
+
+// WithMissedPolicy sets the policy of missed runs, default MissedSkip.
+func WithMissedPolicy(p MissedPolicy) CronOption {
+	return func(j *CronJob) { j.policy = p }
+}
+
+// WithCronJitter adds a random delay up to jitter before each run.
+func WithCronJitter(jitter time.Duration) CronOption {
+	return func(j *CronJob) { j.jitter = jitter }
+}
+
+// WithLocation sets the time zone of the expression without CRON_TZ prefix, default time.Local.
+func WithLocation(loc *time.Location) CronOption {
+	return func(j *CronJob) { j.location = loc }
+}
+
+// WithErrorHandler sets the handler of job errors (and panics), default logging the error.
+func WithErrorHandler(f func(spec string, err error)) CronOption {
+	return func(j *CronJob) { j.onError = f }
+}
+
+// NewCronJob creates a CronJob with the cron expression, see ParseCron for the formats.
+func NewCronJob(spec string, f func(ctx context.Context) error, options ...CronOption) (*CronJob, error) {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &CronJob{Spec: spec, schedule: schedule, f: f, location: time.Local}
+	for _, option := range options {
+		option(j)
+	}
+
+	if j.onError == nil {
+		j.onError = func(spec string, err error) {
+			log.Printf("E! cron job %s failed: %v", spec, err)
+		}
+	}
+
+	return j, nil
+}
+
+// Run runs the job on schedule until the context is done.
+// The job never overlaps with itself, the runs missed are handled by the MissedPolicy.
+func (j *CronJob) Run(ctx context.Context) error {
+	next := j.schedule.Next(time.Now().In(j.location))
+
+	for !next.IsZero() {
+		if err := Sleep(ctx, Jitter(time.Until(next), j.jitter)); err != nil {
+			return err
+		}
+
+		j.exec(ctx)
+		next = j.next(next, time.Now().In(j.location))
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// next returns the next run time after the run scheduled at the time finished at now.
+func (j *CronJob) next(scheduled, now time.Time) time.Time {
+	next := j.schedule.Next(scheduled)
+	if !next.Before(now) {
+		return next
+	}
+
+	switch j.policy {
+	case MissedCatchUp:
+		return next
+	case MissedRunOnce:
+		return now
+	default:
+		return j.schedule.Next(now)
+	}
+}
+
+func (j *CronJob) exec(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			j.onError(j.Spec, fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	if err := j.f(ctx); err != nil {
+		j.onError(j.Spec, err)
+	}
+}
+
+// Cron runs f on the cron schedule until the context is done, see ParseCron for the formats.
+func Cron(ctx context.Context, spec string, f func(ctx context.Context) error, options ...CronOption) error {
+	j, err := NewCronJob(spec, f, options...)
+	if err != nil {
+		return err
+	}
+
+	return j.Run(ctx)
+}
+
+// Scheduler runs multiple cron jobs.
+type Scheduler struct {
+	mu   sync.Mutex
+	jobs []*CronJob
+	ctx  context.Context
+	wg   sync.WaitGroup
+}
+
+// Add adds a cron job, which starts immediately if the scheduler is running.
+func (s *Scheduler) Add(spec string, f func(ctx context.Context) error, options ...CronOption) (*CronJob, error) {
+	j, err := NewCronJob(spec, f, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs = append(s.jobs, j)
+	if s.ctx != nil {
+		s.start(j)
+	}
+
+	return j, nil
+}
+
+func (s *Scheduler) start(j *CronJob) {
+	s.wg.Add(1)
+	go func(ctx context.Context) {
+		defer s.wg.Done()
+		_ = j.Run(ctx)
+	}(s.ctx)
+}
+
+// Run runs all the jobs until the context is done, and waits for the running jobs to finish.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("scheduler is already running")
+	}
+
+	s.ctx = ctx
+	for _, j := range s.jobs {
+		s.start(j)
+	}
+	s.mu.Unlock()
+
+	<-ctx.Done()
+	s.wg.Wait()
+
+	s.mu.Lock()
+	s.ctx = nil
+	s.mu.Unlock()
+
+	return ctx.Err()
+}
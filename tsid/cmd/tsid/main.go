@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/ngg/tsid"
+)
+
+var (
+	kind           = flag.String("kind", "", "id kind, one of tsid, ulid, uuid, snowflake, empty for detecting by the format")
+	nodeBits       = flag.Int("node-bits", 0, "node bits of tsid")
+	epoch          = flag.Int64("epoch", tsid.Epoch, "epoch millis of tsid")
+	snowflakeEpoch = flag.Int64("snowflake-epoch", tsid.SnowflakeEpoch, "epoch millis of snowflake")
+	gen            = flag.Int("gen", 0, "generate `n` ids of -kind instead of decoding")
+	node           = flag.Int64("node", 0, "node id when generating tsid or snowflake")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [id...]\n\nDecodes ids (from args or stdin lines) into timestamp, node and counter.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *gen > 0 {
+		if err := generate(*gen); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	opts := tsid.DecodeOptions{Kind: *kind, NodeBits: int32(*nodeBits), Epoch: *epoch, SnowflakeEpoch: *snowflakeEpoch}
+	failed := false
+	decode := func(id string) {
+		if id = strings.TrimSpace(id); id == "" {
+			return
+		}
+		ds, err := tsid.Decode(id, opts)
+		if err != nil {
+			log.Printf("E! %v", err)
+			failed = true
+			return
+		}
+		for _, d := range ds {
+			printDecoded(id, d)
+		}
+	}
+
+	if flag.NArg() > 0 {
+		for _, id := range flag.Args() {
+			decode(id)
+		}
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			decode(scanner.Text())
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func printDecoded(id string, d tsid.Decoded) {
+	var fields []string
+	fields = append(fields, "kind: "+d.Kind)
+	if d.Kind == "uuid" {
+		fields = append(fields, fmt.Sprintf("version: %d", d.Version))
+	}
+	if !d.Time.IsZero() {
+		fields = append(fields, "time: "+d.Time.Format(time.RFC3339Nano))
+	}
+	switch d.Kind {
+	case "tsid", "snowflake":
+		fields = append(fields, fmt.Sprintf("node: %d", d.Node), fmt.Sprintf("counter: %d", d.Counter))
+	case "uuid":
+		if d.Version == 7 {
+			fields = append(fields, fmt.Sprintf("counter: %d", d.Counter))
+		}
+	case "ulid":
+		fields = append(fields, "random: "+d.Random)
+	}
+	fmt.Printf("%s\t%s\n", id, strings.Join(fields, ", "))
+}
+
+func generate(n int) error {
+	var next func() (string, error)
+	switch *kind {
+	case "", "tsid":
+		f, err := tsid.NewBuilder().WithNodeBits(int32(*nodeBits)).WithNode(int32(*node)).WithEpoch(*epoch).New()
+		if err != nil {
+			return err
+		}
+		next = func() (string, error) {
+			t, err := f.Generate()
+			if err != nil {
+				return "", err
+			}
+			return t.ToString(), nil
+		}
+	case "ulid":
+		g := tsid.NewULIDGenerator(nil, nil)
+		next = func() (string, error) {
+			u, err := g.Generate()
+			return u.String(), err
+		}
+	case "uuid":
+		g := tsid.NewUUIDv7Generator(nil, nil)
+		next = func() (string, error) {
+			u, err := g.Generate()
+			return u.String(), err
+		}
+	case "snowflake":
+		g, err := tsid.NewSnowflakeGenerator(*node, *snowflakeEpoch, nil)
+		if err != nil {
+			return err
+		}
+		next = func() (string, error) {
+			id, err := g.Generate()
+			return fmt.Sprintf("%d", id), err
+		}
+	default:
+		return fmt.Errorf("unknown id kind: %s", *kind)
+	}
+
+	for i := 0; i < n; i++ {
+		id, err := next()
+		if err != nil {
+			return err
+		}
+		fmt.Println(id)
+	}
+	return nil
+}
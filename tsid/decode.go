@@ -0,0 +1,148 @@
+package tsid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decoded is the decoded components of an ID
+type Decoded struct {
+	// Kind is one of tsid, ulid, uuid, snowflake
+	Kind string
+	ID   string
+	// Time is the time component, zero for UUIDs other than version 7
+	Time    time.Time
+	Node    int64
+	Counter int64
+	// Random is the random component in hex of ULID
+	Random string
+	// Version is the version of UUID
+	Version int
+}
+
+// DecodeOptions is the options of Decode
+type DecodeOptions struct {
+	// Kind is one of tsid, ulid, uuid, snowflake, empty means detecting by the format
+	Kind string
+	// NodeBits is the node bits of TSID, default 0
+	NodeBits int32
+	// Epoch is the epoch of TSID, default Epoch
+	Epoch int64
+	// SnowflakeEpoch is the epoch of snowflake, default SnowflakeEpoch
+	SnowflakeEpoch int64
+}
+
+// Decode decodes the ID into timestamp, node and counter. When the kind is not specified, it is detected by the format:
+//
+//	36 chars with hyphens or 32 hex chars: UUID
+//	26 chars: ULID
+//	13 chars: TSID string
+//	digits: both TSID number and snowflake, since they can not be distinguished
+func Decode(s string, opts DecodeOptions) ([]Decoded, error) {
+	s = strings.TrimSpace(s)
+	kind := strings.ToLower(opts.Kind)
+	if kind == "" {
+		switch {
+		case len(s) == 36 || len(s) == 32 && isHex(s):
+			kind = "uuid"
+		case len(s) == ULIDChars:
+			kind = "ulid"
+		case len(s) == int(Chars) && IsValidRuneArray([]rune(s)):
+			kind = "tsid"
+		case isDigits(s):
+			kind = "tsid,snowflake"
+		default:
+			return nil, fmt.Errorf("unknown id format: %s", s)
+		}
+	}
+
+	var result []Decoded
+	for _, k := range strings.Split(kind, ",") {
+		d, err := decodeKind(k, s, opts)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+func decodeKind(kind, s string, opts DecodeOptions) (Decoded, error) {
+	switch kind {
+	case "uuid":
+		u, err := ParseUUID(s)
+		if err != nil {
+			return Decoded{}, err
+		}
+		d := Decoded{Kind: kind, ID: u.String(), Version: u.Version()}
+		if d.Version == 7 {
+			d.Time = u.Time()
+			d.Counter = u.Counter()
+		}
+		return d, nil
+	case "ulid":
+		u, err := ParseULID(s)
+		if err != nil {
+			return Decoded{}, err
+		}
+		return Decoded{Kind: kind, ID: u.String(), Time: u.Time(), Random: u.Entropy()}, nil
+	case "snowflake":
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return Decoded{}, fmt.Errorf("invalid snowflake %s: %w", s, err)
+		}
+		sf := ParseSnowflake(id, opts.SnowflakeEpoch)
+		return Decoded{Kind: kind, ID: s, Time: sf.Time, Node: sf.Node, Counter: sf.Sequence}, nil
+	case "tsid":
+		if opts.NodeBits < 0 || opts.NodeBits > 20 {
+			return Decoded{}, fmt.Errorf("node bits out of range [0, 20]: %d", opts.NodeBits)
+		}
+		var t *Tsid
+		if isDigits(s) {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return Decoded{}, fmt.Errorf("invalid tsid %s: %w", s, err)
+			}
+			t = FromNumber(n)
+		} else if IsValidRuneArray([]rune(s)) {
+			t = FromString(s)
+		} else {
+			return Decoded{}, fmt.Errorf("invalid tsid %s", s)
+		}
+
+		epoch := opts.Epoch
+		if epoch == 0 {
+			epoch = Epoch
+		}
+		counterBits := Bits - opts.NodeBits
+		return Decoded{
+			Kind:    kind,
+			ID:      t.ToString(),
+			Time:    time.UnixMilli(t.GetUnixMillisEpoch(epoch)),
+			Node:    t.GetRandom() >> counterBits,
+			Counter: t.GetRandom() & (1<<counterBits - 1),
+		}, nil
+	}
+
+	return Decoded{}, fmt.Errorf("unknown id kind: %s", kind)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package tsid
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -39,9 +40,36 @@ type Clock interface {
 	UnixMilli() int64
 }
 
+// SystemClock is the default Clock which reads the current system time
+type SystemClock struct{}
+
+func (SystemClock) UnixMilli() int64 { return time.Now().UnixMilli() }
+
+// ErrClockBackward is returned by Generate when the clock moved backwards
+// and the ClockBackwardPolicy does not allow to continue
+var ErrClockBackward = errors.New("tsid: clock moved backwards")
+
+// ClockBackwardPolicy decides what Generate does when the clock goes backwards
+type ClockBackwardPolicy int
+
+const (
+	// ClockBackwardBorrow keeps the last time and borrows from the counter,
+	// the time component can be ahead of the clock until it catches up. This is the default.
+	ClockBackwardBorrow ClockBackwardPolicy = iota
+	// ClockBackwardWait sleeps until the clock catches up, or fails with ErrClockBackward
+	// when the clock is behind more than the max wait
+	ClockBackwardWait
+	// ClockBackwardFail fails with ErrClockBackward immediately
+	ClockBackwardFail
+)
+
+// DefaultClockBackwardMaxWait is the default max wait of ClockBackwardWait
+const DefaultClockBackwardMaxWait = time.Second
+
 // Factory is a singleton that
 // should be used to generate random Tsid
 type Factory struct {
+	mu          sync.Mutex
 	node        int32
 	nodeBits    int32
 	nodeMask    int32
@@ -49,10 +77,15 @@ type Factory struct {
 	counterBits int32
 	counterMask int32
 	lastTime    int64
+	lastClock   int64
 	customEpoch int64
 	clock       Clock
 	random      Random
 	randomBytes int32
+
+	clockBackward        ClockBackwardPolicy
+	clockBackwardMaxWait time.Duration
+	allocator            NodeAllocator
 }
 
 func newFactory(builder *Builder) (*Factory, error) {
@@ -61,6 +94,9 @@ func newFactory(builder *Builder) (*Factory, error) {
 		customEpoch: builder.GetEpoch(),
 		clock:       builder.GetClock(),
 		random:      builder.GetRandom(),
+
+		clockBackward:        builder.clockBackward,
+		clockBackwardMaxWait: builder.GetClockBackwardMaxWait(),
 	}
 
 	// get node bits
@@ -84,11 +120,19 @@ func newFactory(builder *Builder) (*Factory, error) {
 		log.Print(err.Error())
 		return nil, errors.New("failed to initialize tsid factory")
 	}
+	if builder.allocator != nil {
+		if node, err = builder.allocator.Acquire(context.Background(), fact.nodeMask); err != nil {
+			return nil, fmt.Errorf("failed to acquire tsid node: %w", err)
+		}
+		fact.allocator = builder.allocator
+	}
 	fact.node = node & fact.nodeMask
 
 	fact.lastTime = fact.clock.UnixMilli()
+	fact.lastClock = fact.lastTime
 	counter, err := fact.getRandomValue()
 	if err != nil {
+		fact.Close()
 		return nil, err
 	}
 
@@ -96,8 +140,29 @@ func newFactory(builder *Builder) (*Factory, error) {
 	return fact, nil
 }
 
-// Generate will return a tsid with random number
+// Node returns the node id of the factory, which may be acquired from the NodeAllocator
+func (factory *Factory) Node() int32 { return factory.node }
+
+// Close releases the node id acquired from the NodeAllocator, if any
+func (factory *Factory) Close() error {
+	if factory.allocator == nil {
+		return nil
+	}
+	return factory.allocator.Release(context.Background())
+}
+
+// Generate will return a tsid with random number.
+// It fails with ErrNodeLeaseLost once the node lease from the NodeAllocator is lost or expired.
 func (factory *Factory) Generate() (*Tsid, error) {
+	if factory.allocator != nil {
+		if err := factory.allocator.Valid(); err != nil {
+			return nil, err
+		}
+	}
+
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+
 	tim, err := factory.getTime()
 	if err != nil {
 		return nil, err
@@ -113,6 +178,15 @@ func (factory *Factory) Generate() (*Tsid, error) {
 
 func (factory *Factory) getTime() (int64, error) {
 	milli := factory.clock.UnixMilli()
+	if milli < factory.lastClock {
+		var err error
+		if milli, err = factory.clockMovedBackwards(milli); err != nil {
+			return 0, err
+		}
+	} else {
+		factory.lastClock = milli
+	}
+
 	if milli <= factory.lastTime {
 		factory.counter++
 		carry := uint32(factory.counter) >> factory.counterBits
@@ -130,6 +204,29 @@ func (factory *Factory) getTime() (int64, error) {
 	return milli - factory.customEpoch, nil
 }
 
+// clockMovedBackwards applies the ClockBackwardPolicy when the clock reads milli,
+// which is behind the last clock reading.
+func (factory *Factory) clockMovedBackwards(milli int64) (int64, error) {
+	switch factory.clockBackward {
+	case ClockBackwardFail:
+		return 0, fmt.Errorf("%w: %dms", ErrClockBackward, factory.lastClock-milli)
+	case ClockBackwardWait:
+		maxWait := factory.clockBackwardMaxWait.Milliseconds()
+		for waited := int64(0); milli < factory.lastClock; milli = factory.clock.UnixMilli() {
+			behind := factory.lastClock - milli
+			if waited+behind > maxWait {
+				return 0, fmt.Errorf("%w: %dms, exceeds max wait %s", ErrClockBackward, behind, factory.clockBackwardMaxWait)
+			}
+			time.Sleep(time.Duration(behind) * time.Millisecond)
+			waited += behind
+		}
+		factory.lastClock = milli
+	}
+
+	// ClockBackwardBorrow, the last time will be used and the counter will be incremented
+	return milli, nil
+}
+
 func (factory *Factory) getRandomValue() (int32, error) {
 	switch factory.random.(type) {
 	case *ByteRandom:
@@ -165,11 +262,15 @@ func (factory *Factory) getRandomValue() (int32, error) {
 }
 
 type Builder struct {
-	node     int32
-	nodeBits int32
-	epoch    int64
-	clock    Clock
-	random   Random
+	node      int32
+	nodeBits  int32
+	epoch     int64
+	clock     Clock
+	random    Random
+	allocator NodeAllocator
+
+	clockBackward        ClockBackwardPolicy
+	clockBackwardMaxWait time.Duration
 }
 
 // NewBuilder should be used to get instance of factory
@@ -200,6 +301,25 @@ func (b *Builder) WithRandom(random Random) *Builder {
 	return b
 }
 
+// WithNodeAllocator leases the node id from the allocator instead of WithNode,
+// the node id is released by Factory.Close.
+func (b *Builder) WithNodeAllocator(allocator NodeAllocator) *Builder {
+	b.allocator = allocator
+	return b
+}
+
+// WithClockBackward sets the policy when the clock moves backwards. Default is ClockBackwardBorrow.
+func (b *Builder) WithClockBackward(policy ClockBackwardPolicy) *Builder {
+	b.clockBackward = policy
+	return b
+}
+
+// WithClockBackwardMaxWait sets the max wait of ClockBackwardWait.
+func (b *Builder) WithClockBackwardMaxWait(maxWait time.Duration) *Builder {
+	b.clockBackwardMaxWait = maxWait
+	return b
+}
+
 // GetNode returns the provided node id. Default is zero.
 func (b *Builder) GetNode() (int32, error) {
 	if b.nodeBits <= 0 {
@@ -228,11 +348,19 @@ func (b *Builder) GetNodeBits() (int32, error) {
 
 func (b *Builder) GetClock() Clock {
 	if b.clock == nil {
-		b.clock = time.Now().UTC()
+		b.clock = SystemClock{}
 	}
 	return b.clock
 }
 
+// GetClockBackwardMaxWait returns the max wait of ClockBackwardWait. Default is DefaultClockBackwardMaxWait.
+func (b *Builder) GetClockBackwardMaxWait() time.Duration {
+	if b.clockBackwardMaxWait <= 0 {
+		b.clockBackwardMaxWait = DefaultClockBackwardMaxWait
+	}
+	return b.clockBackwardMaxWait
+}
+
 func (b *Builder) GetRandom() Random {
 	if b.random == nil {
 		randomSupplier := NewMathRandomSupplier()
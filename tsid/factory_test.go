@@ -225,6 +225,62 @@ func Test_Generate(t *testing.T) {
 	})
 }
 
+func Test_ClockBackward(t *testing.T) {
+	epoch := time.Now().UnixMilli()
+	intRandom := tsid.NewIntRandom(tsid.IntSupplierFunc(func() (int32, error) {
+		return 0, nil
+	}))
+
+	t.Run("fail policy should return ErrClockBackward", func(t *testing.T) {
+		clock := &MockClock{millis: []int64{epoch, epoch + 1, epoch - 5, epoch + 2}}
+		instance, _ := tsid.NewBuilder().
+			WithClock(clock).
+			WithRandom(intRandom).
+			WithClockBackward(tsid.ClockBackwardFail).
+			New()
+
+		_, err := instance.Generate()
+		assert.Nil(t, err)
+		_, err = instance.Generate()
+		assert.ErrorIs(t, err, tsid.ErrClockBackward)
+		_, err = instance.Generate()
+		assert.Nil(t, err)
+	})
+
+	t.Run("wait policy should wait until clock catches up", func(t *testing.T) {
+		clock := &MockClock{millis: []int64{epoch, epoch + 10, epoch + 5, epoch + 8, epoch + 10}}
+		instance, _ := tsid.NewBuilder().
+			WithClock(clock).
+			WithRandom(intRandom).
+			WithClockBackward(tsid.ClockBackwardWait).
+			New()
+
+		tsid1, err := instance.Generate()
+		assert.Nil(t, err)
+		start := time.Now()
+		tsid2, err := instance.Generate()
+		assert.Nil(t, err)
+		assert.True(t, time.Since(start) >= 7*time.Millisecond)
+		assert.Equal(t, tsid1.GetUnixMillis(), tsid2.GetUnixMillis())
+		assert.Equal(t, tsid1.GetRandom()+1, tsid2.GetRandom())
+	})
+
+	t.Run("wait policy should fail when clock is behind more than max wait", func(t *testing.T) {
+		clock := &MockClock{millis: []int64{epoch, epoch + 10, epoch - 100}}
+		instance, _ := tsid.NewBuilder().
+			WithClock(clock).
+			WithRandom(intRandom).
+			WithClockBackward(tsid.ClockBackwardWait).
+			WithClockBackwardMaxWait(50 * time.Millisecond).
+			New()
+
+		_, err := instance.Generate()
+		assert.Nil(t, err)
+		_, err = instance.Generate()
+		assert.ErrorIs(t, err, tsid.ErrClockBackward)
+	})
+}
+
 type MockClock struct {
 	index  int
 	millis []int64
@@ -0,0 +1,134 @@
+package tsid_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/ngg/tsid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ULID(t *testing.T) {
+	u, err := tsid.ParseULID("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1469922850259), u.UnixMilli())
+	assert.Equal(t, "01ARZ3NDEKTSV4RRFFQ69G5FAV", u.String())
+
+	lower, err := tsid.ParseULID("01arz3ndektsv4rrffq69g5fav")
+	assert.Nil(t, err)
+	assert.Equal(t, u, lower)
+
+	_, err = tsid.ParseULID("81ARZ3NDEKTSV4RRFFQ69G5FAV")
+	assert.NotNil(t, err)
+
+	epoch := time.Now().UnixMilli()
+	clock := &MockClock{millis: []int64{epoch, epoch, epoch - 1, epoch + 1}}
+	ones := tsid.NewByteRandom(tsid.ByteSupplierFunc(func(length int32) ([]byte, error) {
+		b := make([]byte, length)
+		for i := range b {
+			b[i] = 0xff
+		}
+		b[length-1] = 0xfe
+		return b, nil
+	}))
+	g := tsid.NewULIDGenerator(clock, ones)
+	u1, err := g.Generate()
+	assert.Nil(t, err)
+	assert.Equal(t, epoch, u1.UnixMilli())
+	u2, err := g.Generate()
+	assert.Nil(t, err)
+	assert.True(t, u1.String() < u2.String())
+	assert.Equal(t, "ffffffffffffffffffff", u2.Entropy())
+
+	_, err = g.Generate()
+	assert.ErrorIs(t, err, tsid.ErrULIDOverflow)
+
+	u4, err := g.Generate()
+	assert.Nil(t, err)
+	assert.Equal(t, epoch+1, u4.UnixMilli())
+}
+
+func Test_UUIDv7(t *testing.T) {
+	u, err := tsid.ParseUUID("017F22E2-79B0-7CC3-98C4-DC0C0C07398F")
+	assert.Nil(t, err)
+	assert.Equal(t, 7, u.Version())
+	assert.Equal(t, int64(0x17F22E279B0), u.UnixMilli())
+	assert.Equal(t, "017f22e2-79b0-7cc3-98c4-dc0c0c07398f", u.String())
+
+	epoch := time.Now().UnixMilli()
+	millis := []int64{epoch}
+	for i := 0; i < 4096; i++ {
+		millis = append(millis, epoch)
+	}
+	g := tsid.NewUUIDv7Generator(&MockClock{millis: millis}, nil)
+
+	var prev string
+	for i := range millis {
+		u, err := g.Generate()
+		assert.Nil(t, err)
+		assert.Equal(t, 7, u.Version())
+		assert.Equal(t, byte(0x80), u[8]&0xc0)
+		assert.True(t, prev < u.String())
+		prev = u.String()
+
+		if i == len(millis)-1 { // the counter overflowed and borrowed the next millisecond
+			assert.Equal(t, epoch+1, u.UnixMilli())
+		}
+	}
+}
+
+func Test_Snowflake(t *testing.T) {
+	epoch := time.Now().UnixMilli()
+	clock := &MockClock{millis: []int64{epoch, epoch, epoch + 1, epoch}}
+	g, err := tsid.NewSnowflakeGenerator(5, 0, clock)
+	assert.Nil(t, err)
+
+	id1, _ := g.Generate()
+	id2, _ := g.Generate()
+	id3, _ := g.Generate()
+	_, err = g.Generate()
+	assert.ErrorIs(t, err, tsid.ErrClockBackward)
+
+	sf := tsid.ParseSnowflake(id2, 0)
+	assert.Equal(t, epoch, sf.Time.UnixMilli())
+	assert.Equal(t, int64(5), sf.Node)
+	assert.Equal(t, int64(1), sf.Sequence)
+	assert.Equal(t, id1+1, id2)
+	assert.Equal(t, int64(0), tsid.ParseSnowflake(id3, 0).Sequence)
+
+	_, err = tsid.NewSnowflakeGenerator(1024, 0, nil)
+	assert.NotNil(t, err)
+
+	// a well known twitter id
+	sf = tsid.ParseSnowflake(1541815603606036480, 0)
+	assert.Equal(t, int64(1656432460105), sf.Time.UnixMilli())
+	assert.Equal(t, int64(378), sf.Node)
+}
+
+func Test_Decode(t *testing.T) {
+	f, _ := tsid.NewBuilder().WithNodeBits(10).WithNode(500).New()
+	id, _ := f.Generate()
+
+	ds, err := tsid.Decode(id.ToString(), tsid.DecodeOptions{NodeBits: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(ds))
+	assert.Equal(t, "tsid", ds[0].Kind)
+	assert.Equal(t, int64(500), ds[0].Node)
+	assert.Equal(t, id.GetUnixMillis(), ds[0].Time.UnixMilli())
+
+	ds, err = tsid.Decode("1541815603606036480", tsid.DecodeOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tsid", "snowflake"}, []string{ds[0].Kind, ds[1].Kind})
+
+	ds, err = tsid.Decode("017f22e279b07cc398c4dc0c0c07398f", tsid.DecodeOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "uuid", ds[0].Kind)
+	assert.Equal(t, int64(0xcc3), ds[0].Counter)
+
+	ds, err = tsid.Decode("01ARZ3NDEKTSV4RRFFQ69G5FAV", tsid.DecodeOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "ulid", ds[0].Kind)
+
+	_, err = tsid.Decode("not-an-id", tsid.DecodeOptions{})
+	assert.NotNil(t, err)
+}
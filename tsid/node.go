@@ -0,0 +1,404 @@
+package tsid
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNoFreeNode is returned by NodeAllocator.Acquire when all node ids are in use
+var ErrNoFreeNode = errors.New("tsid: no free node id")
+
+// ErrNodeLeaseLost is returned by NodeAllocator.Valid and Factory.Generate
+// when the node lease is lost, expired or released, since the node id may be used by others
+var ErrNodeLeaseLost = errors.New("tsid: node lease lost")
+
+// NodeAllocator leases a unique node id for a Factory,
+// so that many replicas can generate TSIDs without configuring the node id by hand.
+type NodeAllocator interface {
+	// Acquire leases a node id in the range [0, maxNode]
+	Acquire(ctx context.Context, maxNode int32) (int32, error)
+	// Release returns the leased node id
+	Release(ctx context.Context) error
+	// Valid returns ErrNodeLeaseLost if the node id is not held anymore
+	Valid() error
+}
+
+// DefaultNodeLeaseTTL is the default TTL of the node lease in SQLNodeAllocator and RedisNodeAllocator
+const DefaultNodeLeaseTTL = 30 * time.Second
+
+// nodeOwner returns a unique owner token of the node lease, like hostname:pid:random
+func nodeOwner() string {
+	hostname, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hostname + ":" + strconv.Itoa(os.Getpid()) + ":" + hex.EncodeToString(b)
+}
+
+// lease renews the node lease periodically until stopped, and tracks whether it is still held
+type lease struct {
+	stop chan struct{}
+	done chan struct{}
+
+	mu sync.Mutex
+	// expiry is the time the lease expires if not renewed, counted from the start of the last acquire or renew request
+	expiry time.Time
+	// lost is set when the renew finds the lease taken over by others
+	lost bool
+}
+
+// keepLease calls renew every ttl/3, renew returns false when the lease is lost.
+// acquired is the time before the acquire request was sent.
+func keepLease(node int32, ttl time.Duration, acquired time.Time, renew func(ctx context.Context) (bool, error)) *lease {
+	l := &lease{stop: make(chan struct{}), done: make(chan struct{}), expiry: acquired.Add(ttl)}
+	go func() {
+		defer close(l.done)
+
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+				start := time.Now()
+				ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+				ok, err := renew(ctx)
+				cancel()
+				switch {
+				case err != nil:
+					// retry on the next tick, Valid fails once the lease expires
+					log.Printf("W! renew tsid node %d lease failed: %v", node, err)
+				case !ok:
+					log.Printf("E! tsid node %d lease lost", node)
+					l.mu.Lock()
+					l.lost = true
+					l.mu.Unlock()
+					return
+				default:
+					l.mu.Lock()
+					l.expiry = start.Add(ttl)
+					l.mu.Unlock()
+				}
+			}
+		}
+	}()
+	return l
+}
+
+// valid returns ErrNodeLeaseLost if the lease is lost or expired
+func (l *lease) valid() error {
+	if l == nil {
+		return fmt.Errorf("%w: not acquired", ErrNodeLeaseLost)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lost {
+		return ErrNodeLeaseLost
+	}
+	if now := time.Now(); !now.Before(l.expiry) {
+		return fmt.Errorf("%w: expired at %s", ErrNodeLeaseLost, l.expiry.Format(time.RFC3339Nano))
+	}
+	return nil
+}
+
+func (l *lease) close() {
+	if l != nil {
+		close(l.stop)
+		<-l.done
+	}
+}
+
+// SQLNodeAllocator leases the node id from a table in RDBMS, the table should be created like:
+//
+//	create table tsid_node (
+//		node    int          primary key,
+//		owner   varchar(128) not null,
+//		expired bigint       not null -- unix millis
+//	)
+//
+// The lease is renewed every TTL/3, and can be taken over by others after it expires.
+type SQLNodeAllocator struct {
+	DB *sql.DB
+	// Table is the table name, default tsid_node
+	Table string
+	// TTL is the lease TTL, default DefaultNodeLeaseTTL
+	TTL time.Duration
+	// Placeholder returns the n-th (1-based) bind placeholder, default ?, e.g. $1 for PostgreSQL
+	Placeholder func(n int) string
+
+	mu    sync.Mutex
+	node  int32
+	owner string
+	lease *lease
+}
+
+func (a *SQLNodeAllocator) table() string {
+	if a.Table == "" {
+		return "tsid_node"
+	}
+	return a.Table
+}
+
+func (a *SQLNodeAllocator) ttl() time.Duration {
+	if a.TTL <= 0 {
+		return DefaultNodeLeaseTTL
+	}
+	return a.TTL
+}
+
+func (a *SQLNodeAllocator) bind(query string, n int) string {
+	args := make([]any, n)
+	for i := range args {
+		if a.Placeholder == nil {
+			args[i] = "?"
+		} else {
+			args[i] = a.Placeholder(i + 1)
+		}
+	}
+	return fmt.Sprintf(query, args...)
+}
+
+func (a *SQLNodeAllocator) Acquire(ctx context.Context, maxNode int32) (int32, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.lease != nil {
+		return 0, fmt.Errorf("tsid node %d already acquired", a.node)
+	}
+
+	owner := nodeOwner()
+	insert := a.bind("insert into "+a.table()+" (node, owner, expired) values (%s, %s, %s)", 3)
+	takeover := a.bind("update "+a.table()+" set owner = %s, expired = %s where node = %s and expired < %s", 4)
+	for node := int32(0); node <= maxNode; node++ {
+		now := time.Now()
+		expired := now.Add(a.ttl()).UnixMilli()
+		if _, err := a.DB.ExecContext(ctx, insert, node, owner, expired); err == nil {
+			return a.acquired(node, owner, now), nil
+		}
+
+		// the node exists, take it over if the lease expired
+		r, err := a.DB.ExecContext(ctx, takeover, owner, expired, node, now.UnixMilli())
+		if err != nil {
+			return 0, err
+		}
+		if n, _ := r.RowsAffected(); n == 1 {
+			return a.acquired(node, owner, now), nil
+		}
+	}
+
+	return 0, ErrNoFreeNode
+}
+
+func (a *SQLNodeAllocator) acquired(node int32, owner string, now time.Time) int32 {
+	a.node, a.owner = node, owner
+	renew := a.bind("update "+a.table()+" set expired = %s where node = %s and owner = %s", 3)
+	a.lease = keepLease(node, a.ttl(), now, func(ctx context.Context) (bool, error) {
+		r, err := a.DB.ExecContext(ctx, renew, time.Now().Add(a.ttl()).UnixMilli(), node, owner)
+		if err != nil {
+			return false, err
+		}
+		n, err := r.RowsAffected()
+		return n == 1, err
+	})
+	return node
+}
+
+func (a *SQLNodeAllocator) Release(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.lease == nil {
+		return nil
+	}
+	a.lease.close()
+	a.lease = nil
+
+	query := a.bind("delete from "+a.table()+" where node = %s and owner = %s", 2)
+	_, err := a.DB.ExecContext(ctx, query, a.node, a.owner)
+	return err
+}
+
+func (a *SQLNodeAllocator) Valid() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.lease.valid()
+}
+
+// RedisClient is the minimal redis client used by RedisNodeAllocator,
+// it can be implemented by a thin wrapper of go-redis, e.g.
+//
+//	func (c wrapper) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+//		return c.Client.SetNX(ctx, key, value, ttl).Result()
+//	}
+//
+//	func (c wrapper) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+//		return c.Client.Eval(ctx, script, keys, args...).Result()
+//	}
+type RedisClient interface {
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+const (
+	redisRenewScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+	redisDelScript   = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+)
+
+// RedisNodeAllocator leases the node id by SET NX key {Prefix}{node} in redis,
+// the key expires after TTL and is renewed every TTL/3.
+type RedisNodeAllocator struct {
+	Client RedisClient
+	// Prefix is the key prefix, default tsid:node:
+	Prefix string
+	// TTL is the lease TTL, default DefaultNodeLeaseTTL
+	TTL time.Duration
+
+	mu    sync.Mutex
+	key   string
+	owner string
+	lease *lease
+}
+
+func (a *RedisNodeAllocator) ttl() time.Duration {
+	if a.TTL <= 0 {
+		return DefaultNodeLeaseTTL
+	}
+	return a.TTL
+}
+
+func (a *RedisNodeAllocator) Acquire(ctx context.Context, maxNode int32) (int32, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.lease != nil {
+		return 0, fmt.Errorf("tsid node %s already acquired", a.key)
+	}
+
+	prefix := a.Prefix
+	if prefix == "" {
+		prefix = "tsid:node:"
+	}
+
+	owner := nodeOwner()
+	for node := int32(0); node <= maxNode; node++ {
+		key := prefix + strconv.Itoa(int(node))
+		now := time.Now()
+		ok, err := a.Client.SetNX(ctx, key, owner, a.ttl())
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+
+		a.key, a.owner = key, owner
+		ttl := a.ttl().Milliseconds()
+		a.lease = keepLease(node, a.ttl(), now, func(ctx context.Context) (bool, error) {
+			r, err := a.Client.Eval(ctx, redisRenewScript, []string{key}, owner, ttl)
+			return r == int64(1), err
+		})
+		return node, nil
+	}
+
+	return 0, ErrNoFreeNode
+}
+
+func (a *RedisNodeAllocator) Release(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.lease == nil {
+		return nil
+	}
+	a.lease.close()
+	a.lease = nil
+
+	_, err := a.Client.Eval(ctx, redisDelScript, []string{a.key}, a.owner)
+	return err
+}
+
+func (a *RedisNodeAllocator) Valid() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.lease.valid()
+}
+
+// FileNodeAllocator leases the node id by locking the file {Dir}/tsid-node-{node}.lock,
+// which is suitable for multiple processes on the same host.
+// The lock is released automatically by the OS when the process exits.
+type FileNodeAllocator struct {
+	// Dir is the directory of the lock files, default os.TempDir()
+	Dir string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+func (a *FileNodeAllocator) Acquire(_ context.Context, maxNode int32) (int32, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file != nil {
+		return 0, fmt.Errorf("tsid node file %s already locked", a.file.Name())
+	}
+
+	dir := a.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return 0, err
+	}
+
+	for node := int32(0); node <= maxNode; node++ {
+		name := filepath.Join(dir, fmt.Sprintf("tsid-node-%d.lock", node))
+		f, err := tryLockFile(name)
+		if err != nil {
+			return 0, err
+		}
+		if f != nil {
+			a.file = f
+			return node, nil
+		}
+	}
+
+	return 0, ErrNoFreeNode
+}
+
+func (a *FileNodeAllocator) Release(context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+	err := unlockFile(a.file)
+	a.file = nil
+	return err
+}
+
+// Valid returns nil while the file is locked, the lock is held until released or the process exits
+func (a *FileNodeAllocator) Valid() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return fmt.Errorf("%w: not acquired", ErrNodeLeaseLost)
+	}
+	return nil
+}
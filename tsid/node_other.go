@@ -0,0 +1,29 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package tsid
+
+import (
+	"errors"
+	"os"
+	"strconv"
+)
+
+// tryLockFile creates the file exclusively, returns nil file when it exists.
+// Unlike flock, the file is left behind if the process crashes, and should be removed by hand.
+func tryLockFile(name string) (*os.File, error) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	_, _ = f.WriteString(strconv.Itoa(os.Getpid()))
+	return f, nil
+}
+
+func unlockFile(f *os.File) error {
+	f.Close()
+	return os.Remove(f.Name())
+}
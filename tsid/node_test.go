@@ -0,0 +1,139 @@
+package tsid_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/ngg/tsid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FileNodeAllocator(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	a1 := &tsid.FileNodeAllocator{Dir: dir}
+	a2 := &tsid.FileNodeAllocator{Dir: dir}
+	a3 := &tsid.FileNodeAllocator{Dir: dir}
+
+	n1, err := a1.Acquire(ctx, 1)
+	assert.Nil(t, err)
+	n2, err := a2.Acquire(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{0, 1}, []int32{n1, n2})
+
+	_, err = a3.Acquire(ctx, 1)
+	assert.ErrorIs(t, err, tsid.ErrNoFreeNode)
+
+	assert.Nil(t, a1.Release(ctx))
+	n3, err := a3.Acquire(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(0), n3)
+
+	assert.Nil(t, a2.Release(ctx))
+	assert.Nil(t, a3.Release(ctx))
+}
+
+func Test_WithNodeAllocator(t *testing.T) {
+	client := &mockRedis{values: map[string]string{}}
+	a1 := &tsid.RedisNodeAllocator{Client: client, TTL: 30 * time.Millisecond}
+	a2 := &tsid.RedisNodeAllocator{Client: client, TTL: 30 * time.Millisecond}
+
+	f1, err := tsid.NewBuilder().WithNodeBits(1).WithNodeAllocator(a1).New()
+	assert.Nil(t, err)
+	f2, err := tsid.NewBuilder().WithNodeBits(1).WithNodeAllocator(a2).New()
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{0, 1}, []int32{f1.Node(), f2.Node()})
+
+	_, err = tsid.NewBuilder().WithNodeBits(1).WithNodeAllocator(&tsid.RedisNodeAllocator{Client: client}).New()
+	assert.ErrorIs(t, err, tsid.ErrNoFreeNode)
+
+	// lease is renewed
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, client.renewed() > 0)
+
+	assert.Nil(t, f1.Close())
+	assert.Nil(t, f2.Close())
+	assert.Empty(t, client.values)
+}
+
+func Test_NodeLeaseLost(t *testing.T) {
+	client := &mockRedis{values: map[string]string{}}
+	f1, err := tsid.NewBuilder().WithNodeBits(1).WithNodeAllocator(&tsid.RedisNodeAllocator{Client: client, TTL: 30 * time.Millisecond}).New()
+	assert.Nil(t, err)
+	defer f1.Close()
+	f2, err := tsid.NewBuilder().WithNodeBits(1).WithNodeAllocator(&tsid.RedisNodeAllocator{Client: client, TTL: 30 * time.Millisecond}).New()
+	assert.Nil(t, err)
+	defer f2.Close()
+
+	_, err = f1.Generate()
+	assert.Nil(t, err)
+
+	// node 0 is taken over by others, the renew of f1 finds the lease lost
+	client.set("tsid:node:0", "others")
+	time.Sleep(25 * time.Millisecond)
+	_, err = f1.Generate()
+	assert.ErrorIs(t, err, tsid.ErrNodeLeaseLost)
+
+	// the renew of f2 keeps failing, the lease expires after TTL
+	client.setFail(true)
+	time.Sleep(40 * time.Millisecond)
+	_, err = f2.Generate()
+	assert.ErrorIs(t, err, tsid.ErrNodeLeaseLost)
+}
+
+type mockRedis struct {
+	mu     sync.Mutex
+	values map[string]string
+	renews int
+	fail   bool
+}
+
+func (m *mockRedis) set(key, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+}
+
+func (m *mockRedis) setFail(fail bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fail = fail
+}
+
+func (m *mockRedis) renewed() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.renews
+}
+
+func (m *mockRedis) SetNX(_ context.Context, key, value string, _ time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.values[key]; ok {
+		return false, nil
+	}
+	m.values[key] = value
+	return true, nil
+}
+
+func (m *mockRedis) Eval(_ context.Context, script string, keys []string, args ...any) (any, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail {
+		return nil, errors.New("connection refused")
+	}
+	if m.values[keys[0]] != args[0] {
+		return int64(0), nil
+	}
+	if strings.Contains(script, "pexpire") {
+		m.renews++
+	} else {
+		delete(m.values, keys[0])
+	}
+	return int64(1), nil
+}
@@ -0,0 +1,157 @@
+package tsid
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ULIDChars is the length of the ULID string
+const ULIDChars = 26
+
+// ErrULIDOverflow is returned when the random component overflows within the same millisecond
+var ErrULIDOverflow = errors.New("tsid: ulid random component overflow")
+
+// ULID is a 128-bit Universally Unique Lexicographically Sortable Identifier, see https://github.com/ulid/spec
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                      32_bit_uint_time_high                    |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|     16_bit_uint_time_low      |       16_bit_uint_random      |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                       32_bit_uint_random                      |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                       32_bit_uint_random                      |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+type ULID [16]byte
+
+// ULIDGenerator generates monotonic ULIDs, the random component is incremented
+// by one when generating within the same millisecond
+type ULIDGenerator struct {
+	mu       sync.Mutex
+	clock    Clock
+	random   Random
+	lastTime int64
+	last     ULID
+}
+
+// NewULIDGenerator creates a ULIDGenerator, nil clock means SystemClock,
+// nil random means crypto/rand.
+func NewULIDGenerator(clock Clock, random Random) *ULIDGenerator {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	if random == nil {
+		random = NewByteRandom(NewCryptoRandomSupplier())
+	}
+	return &ULIDGenerator{clock: clock, random: random}
+}
+
+// Generate returns a new ULID, which is greater than the previous one
+func (g *ULIDGenerator) Generate() (ULID, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	milli := g.clock.UnixMilli()
+	if milli <= g.lastTime { // same millisecond, or the clock moved backwards
+		u := g.last
+		for i := 15; i >= 6; i-- {
+			if u[i]++; u[i] != 0 {
+				g.last = u
+				return u, nil
+			}
+		}
+		return ULID{}, ErrULIDOverflow
+	}
+
+	entropy, err := g.random.NextBytes(10)
+	if err != nil {
+		return ULID{}, err
+	}
+
+	var u ULID
+	putMillis48(u[:], milli)
+	copy(u[6:], entropy)
+	g.lastTime, g.last = milli, u
+	return u, nil
+}
+
+// ParseULID parses the 26 characters Crockford's base32 ULID string, case-insensitive
+func ParseULID(s string) (ULID, error) {
+	var u ULID
+	if len(s) != ULIDChars {
+		return u, fmt.Errorf("invalid ulid length %d: %s", len(s), s)
+	}
+
+	var hi uint64 // 128 bits = 2 bits (first char must be <= 7) + 25*5 bits
+	var lo uint64
+	for i := 0; i < ULIDChars; i++ {
+		v := base32Value(s[i])
+		if v < 0 || i == 0 && v > 7 {
+			return u, fmt.Errorf("invalid ulid %s", s)
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+
+	for i := 0; i < 8; i++ {
+		u[i] = byte(hi >> (56 - 8*i))
+		u[8+i] = byte(lo >> (56 - 8*i))
+	}
+	return u, nil
+}
+
+// String returns the 26 characters Crockford's base32 string in upper case
+func (u ULID) String() string {
+	hi, lo := beUint64(u[:8]), beUint64(u[8:])
+	c := make([]rune, ULIDChars)
+	for i := ULIDChars - 1; i >= 0; i-- {
+		c[i] = AlphabetUpper[lo&0b11111]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(c)
+}
+
+// UnixMilli returns the time component in millis since 1970-01-01
+func (u ULID) UnixMilli() int64 { return getMillis48(u[:]) }
+
+// Time returns the time component
+func (u ULID) Time() time.Time { return time.UnixMilli(u.UnixMilli()) }
+
+// Entropy returns the 80-bit random component in hex
+func (u ULID) Entropy() string { return hex.EncodeToString(u[6:]) }
+
+// base32Value returns the Crockford's base32 value of c, or -1 when invalid
+func base32Value(c byte) int64 {
+	if c >= 128 {
+		return -1
+	}
+	return AlphabetValues[c]
+}
+
+func putMillis48(b []byte, milli int64) {
+	for i := 0; i < 6; i++ {
+		b[i] = byte(milli >> (40 - 8*i))
+	}
+}
+
+func getMillis48(b []byte) int64 {
+	var milli int64
+	for i := 0; i < 6; i++ {
+		milli = milli<<8 | int64(b[i])
+	}
+	return milli
+}
+
+func beUint64(b []byte) uint64 {
+	var n uint64
+	for i := 0; i < 8; i++ {
+		n = n<<8 | uint64(b[i])
+	}
+	return n
+}
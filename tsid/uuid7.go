@@ -0,0 +1,120 @@
+package tsid
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UUID is a 128-bit RFC 9562 UUID
+type UUID [16]byte
+
+// UUIDv7Generator generates monotonic version 7 UUIDs, see RFC 9562 section 5.7.
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                           unix_ts_ms                          |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|          unix_ts_ms           |  ver  |       counter         |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|var|                        rand_b                             |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                            rand_b                             |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//
+// The 12-bit rand_a is used as a counter (method 1 of section 6.2), which starts randomly
+// in each millisecond and is incremented within the same millisecond. When it overflows,
+// the time is borrowed from the next millisecond.
+type UUIDv7Generator struct {
+	mu       sync.Mutex
+	clock    Clock
+	random   Random
+	lastTime int64
+	counter  uint16
+}
+
+const uuidCounterMask = 0x0fff
+
+// NewUUIDv7Generator creates a UUIDv7Generator, nil clock means SystemClock,
+// nil random means crypto/rand.
+func NewUUIDv7Generator(clock Clock, random Random) *UUIDv7Generator {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	if random == nil {
+		random = NewByteRandom(NewCryptoRandomSupplier())
+	}
+	return &UUIDv7Generator{clock: clock, random: random}
+}
+
+// Generate returns a new version 7 UUID, which is greater than the previous one
+func (g *UUIDv7Generator) Generate() (UUID, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	bytes, err := g.random.NextBytes(10)
+	if err != nil {
+		return UUID{}, err
+	}
+
+	milli := g.clock.UnixMilli()
+	if milli <= g.lastTime { // same millisecond, or the clock moved backwards
+		milli = g.lastTime
+		if g.counter++; g.counter > uuidCounterMask {
+			milli++
+			g.counter = 0
+		}
+	} else {
+		// leave the highest bit zero, so that there are at least 2048 increments in each millisecond
+		g.counter = (uint16(bytes[0])<<8 | uint16(bytes[1])) & (uuidCounterMask >> 1)
+	}
+	g.lastTime = milli
+
+	var u UUID
+	putMillis48(u[:], milli)
+	u[6] = 0x70 | byte(g.counter>>8)
+	u[7] = byte(g.counter)
+	copy(u[8:], bytes[2:])
+	u[8] = 0x80 | u[8]&0x3f // variant 10
+	return u, nil
+}
+
+// ParseUUID parses the canonical 8-4-4-4-12 UUID string, or 32 hex digits without hyphens
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	h := s
+	if len(s) == 36 {
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return u, fmt.Errorf("invalid uuid %s", s)
+		}
+		h = strings.ReplaceAll(s, "-", "")
+	}
+	if len(h) != 32 {
+		return u, fmt.Errorf("invalid uuid length %d: %s", len(s), s)
+	}
+	if _, err := hex.Decode(u[:], []byte(h)); err != nil {
+		return u, fmt.Errorf("invalid uuid %s: %w", s, err)
+	}
+	return u, nil
+}
+
+// String returns the canonical 8-4-4-4-12 string in lower case
+func (u UUID) String() string {
+	h := hex.EncodeToString(u[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// Version returns the version of the UUID
+func (u UUID) Version() int { return int(u[6] >> 4) }
+
+// UnixMilli returns the time component in millis since 1970-01-01, only for version 7
+func (u UUID) UnixMilli() int64 { return getMillis48(u[:]) }
+
+// Time returns the time component, only for version 7
+func (u UUID) Time() time.Time { return time.UnixMilli(u.UnixMilli()) }
+
+// Counter returns the 12-bit counter (rand_a) of version 7
+func (u UUID) Counter() int64 { return int64(u[6]&0x0f)<<8 | int64(u[7]) }
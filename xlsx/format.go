@@ -0,0 +1,538 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// Format is the spreadsheet file format.
+type Format int
+
+const (
+	// FormatXlsx is the Office Open XML spreadsheet (.xlsx).
+	FormatXlsx Format = iota
+	// FormatCSV is the comma-separated values (.csv).
+	FormatCSV
+	// FormatTSV is the tab-separated values (.tsv).
+	FormatTSV
+	// FormatODS is the OpenDocument spreadsheet (.ods).
+	FormatODS
+)
+
+const odsMimeType = "application/vnd.oasis.opendocument.spreadsheet"
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatTSV:
+		return "text/tab-separated-values"
+	case FormatODS:
+		return odsMimeType
+	default:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+}
+
+// FormatOf returns the format by the file name extension, default FormatXlsx.
+func FormatOf(filename string) Format {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".tsv", ".tab":
+		return FormatTSV
+	case ".ods":
+		return FormatODS
+	default:
+		return FormatXlsx
+	}
+}
+
+// FormatOfContentType returns the format by the MIME type, false when unknown.
+func FormatOfContentType(contentType string) (Format, bool) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv", "application/csv", "text/comma-separated-values":
+		return FormatCSV, true
+	case "text/tab-separated-values":
+		return FormatTSV, true
+	case odsMimeType:
+		return FormatODS, true
+	case FormatXlsx.ContentType():
+		return FormatXlsx, true
+	default:
+		return FormatXlsx, false
+	}
+}
+
+// detectFormat detects the format by the content.
+// The binary content other than the zip (xlsx or ods), like the legacy xls, is not supported.
+func detectFormat(bs []byte) (Format, error) {
+	if bytes.HasPrefix(bs, []byte("PK\x03\x04")) {
+		if zr, err := zip.NewReader(bytes.NewReader(bs), int64(len(bs))); err == nil {
+			for _, f := range zr.File {
+				if f.Name == "mimetype" {
+					return FormatODS, nil
+				}
+			}
+		}
+
+		return FormatXlsx, nil
+	}
+
+	// the text (CSV/TSV in UTF-8 or GBK) never contains NUL bytes
+	if bytes.IndexByte(bs[:min(len(bs), 8192)], 0) >= 0 {
+		return FormatXlsx, fmt.Errorf("binary content is not xlsx, ods, csv or tsv: %w", ErrUnknownExcelError)
+	}
+
+	if line, _, _ := bufio.NewReader(bytes.NewReader(bs)).ReadLine(); bytes.Count(line, []byte("\t")) > bytes.Count(line, []byte(",")) {
+		return FormatTSV, nil
+	}
+
+	return FormatCSV, nil
+}
+
+// parseFormat parses the content in the format into a workbook.
+func parseFormat(bs []byte, format Format) (*spreadsheet.Workbook, error) {
+	switch format {
+	case FormatCSV, FormatTSV:
+		rows, err := readDelimited(bs, format)
+		if err != nil {
+			return nil, err
+		}
+
+		return buildWorkbook([]sheetData{{rows: rows}}), nil
+	case FormatODS:
+		sheets, err := readODS(bs)
+		if err != nil {
+			return nil, err
+		}
+
+		return buildWorkbook(sheets), nil
+	default:
+		return spreadsheet.Read(bytes.NewReader(bs), int64(len(bs)))
+	}
+}
+
+// sheetData is the cell values of a sheet, the value can be string, float64, bool or nil.
+type sheetData struct {
+	name string
+	rows [][]interface{}
+}
+
+func buildWorkbook(sheets []sheetData) *spreadsheet.Workbook {
+	wb := spreadsheet.New()
+
+	for _, sd := range sheets {
+		sheet := wb.AddSheet()
+		if sd.name != "" {
+			sheet.SetName(sd.name)
+		}
+
+		for _, r := range sd.rows {
+			row := sheet.AddRow()
+			for _, v := range r {
+				cell := row.AddCell()
+				if s, ok := v.(string); ok && s != "" {
+					cell.SetString(s)
+				}
+			}
+		}
+	}
+
+	return wb
+}
+
+// decodeText decodes the text to UTF-8, which is UTF-8 (with or without BOM) or GBK (GB18030).
+func decodeText(bs []byte) ([]byte, error) {
+	bs = bytes.TrimPrefix(bs, []byte("\xEF\xBB\xBF"))
+	if utf8.Valid(bs) {
+		return bs, nil
+	}
+
+	return simplifiedchinese.GB18030.NewDecoder().Bytes(bs)
+}
+
+func readDelimited(bs []byte, format Format) ([][]interface{}, error) {
+	bs, err := decodeText(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	r := csv.NewReader(bytes.NewReader(bs))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	if format == FormatTSV {
+		r.Comma = '\t'
+	}
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]interface{}, len(records))
+	for i, record := range records {
+		rows[i] = make([]interface{}, len(record))
+		for j, v := range record {
+			rows[i][j] = strings.TrimSpace(v)
+		}
+	}
+
+	return rows, nil
+}
+
+// the max columns and rows of a sheet, and the max characters of a cell,
+// which limit the repeated cells, rows and spaces in ods.
+const (
+	maxODSColumns   = 16384
+	maxODSRows      = 1048576
+	maxODSCellChars = 32767
+)
+
+// readODS reads the tables in the content.xml of the OpenDocument spreadsheet.
+// The repeated cells and rows are expanded, except the trailing empty ones.
+func readODS(bs []byte) ([]sheetData, error) {
+	zr, err := zip.NewReader(bytes.NewReader(bs), int64(len(bs)))
+	if err != nil {
+		return nil, err
+	}
+
+	var content io.ReadCloser
+	for _, f := range zr.File {
+		if f.Name == "content.xml" {
+			if content, err = f.Open(); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	if content == nil {
+		return nil, fmt.Errorf("content.xml not found in ods: %w", ErrUnknownExcelError)
+	}
+	defer content.Close()
+
+	var (
+		sheets             []sheetData
+		row                []interface{}
+		rowRepeated        int
+		cellRepeated       int
+		emptyRows          int
+		emptyCells         int
+		cellValue          string
+		cellText           strings.Builder
+		inText, hasTextPar bool
+	)
+
+	d := xml.NewDecoder(content)
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return sheets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch e := t.(type) {
+		case xml.StartElement:
+			switch e.Name.Local {
+			case "table":
+				sheets = append(sheets, sheetData{name: attr(e, "name")})
+			case "table-row":
+				row, emptyCells = nil, 0
+				rowRepeated = repeated(e, "number-rows-repeated")
+			case "table-cell", "covered-table-cell":
+				cellRepeated = repeated(e, "number-columns-repeated")
+				cellValue = attr(e, "value")
+				if v := attr(e, "date-value"); v != "" {
+					cellValue = v
+				} else if v := attr(e, "boolean-value"); v != "" {
+					cellValue = v
+				}
+				cellText.Reset()
+				hasTextPar = false
+			case "p":
+				if hasTextPar {
+					cellText.WriteString("\n")
+				}
+				inText, hasTextPar = true, true
+			case "s": // spaces
+				cellText.WriteString(strings.Repeat(" ", min(repeated(e, "c"), maxODSCellChars)))
+			}
+		case xml.EndElement:
+			switch e.Name.Local {
+			case "p":
+				inText = false
+			case "table-cell", "covered-table-cell":
+				v := cellValue
+				if v == "" {
+					v = strings.TrimSpace(cellText.String())
+				}
+				if v == "" {
+					// the empty cells are kept only when followed by a non-empty cell,
+					// since the trailing ones are usually repeated to the max columns
+					emptyCells += cellRepeated
+					break
+				}
+				for i := 0; i < emptyCells && len(row) < maxODSColumns; i++ {
+					row = append(row, "")
+				}
+				emptyCells = 0
+				for i := 0; i < cellRepeated && len(row) < maxODSColumns; i++ {
+					row = append(row, v)
+				}
+			case "table-row":
+				if len(sheets) == 0 {
+					continue
+				}
+
+				if len(row) == 0 {
+					// the same as empty cells, the trailing empty rows are usually repeated to the max rows
+					emptyRows += rowRepeated
+					break
+				}
+
+				sd := &sheets[len(sheets)-1]
+				for i := 0; i < emptyRows && len(sd.rows) < maxODSRows; i++ {
+					sd.rows = append(sd.rows, nil)
+				}
+				emptyRows = 0
+				for i := 0; i < rowRepeated && len(sd.rows) < maxODSRows; i++ {
+					sd.rows = append(sd.rows, row)
+				}
+			case "table":
+				emptyRows = 0
+			}
+		case xml.CharData:
+			if inText {
+				cellText.Write(e)
+			}
+		}
+	}
+}
+
+func repeated(e xml.StartElement, name string) int {
+	if n, err := strconv.Atoi(attr(e, name)); err == nil && n > 0 {
+		return n
+	}
+
+	return 1
+}
+
+// SaveAs writes the workbook out to a writer in the format.
+// CSV and TSV contain only the current written (or the first) sheet, encoded in UTF-8 with BOM,
+// ODS contains all the sheets, and both contain only cell values, without styles, merged cells or data validations.
+func (x *Xlsx) SaveAs(w io.Writer, format Format) error {
+	switch format {
+	case FormatCSV, FormatTSV:
+		sheet := x.currentSheet
+		if !sheet.IsValid() {
+			sheets := x.workbook.Sheets()
+			if len(sheets) == 0 {
+				return writeDelimited(w, nil, format)
+			}
+			sheet = sheets[0]
+		}
+
+		return writeDelimited(w, sheetValues(sheet).rows, format)
+	case FormatODS:
+		var sheets []sheetData
+		for _, sheet := range x.workbook.Sheets() {
+			sheets = append(sheets, sheetValues(sheet))
+		}
+
+		return writeODS(w, sheets)
+	default:
+		return x.Save(w)
+	}
+}
+
+// sheetValues reads the cell values of the sheet, the empty rows between rows are kept.
+func sheetValues(sheet spreadsheet.Sheet) sheetData {
+	sd := sheetData{name: sheet.Name()}
+
+	for _, row := range sheet.Rows() {
+		for uint32(len(sd.rows)+1) < row.RowNumber() {
+			sd.rows = append(sd.rows, nil)
+		}
+
+		var values []interface{}
+		for _, cell := range RowCells(row) {
+			col, err := cell.Column()
+			if err != nil {
+				continue
+			}
+
+			idx := int(reference.ColumnToIndex(col))
+			for len(values) <= idx {
+				values = append(values, nil)
+			}
+			values[idx] = cellValue(cell)
+		}
+
+		sd.rows = append(sd.rows, values)
+	}
+
+	return sd
+}
+
+// cellValue returns the typed value of the cell, which is string, float64 or bool.
+func cellValue(c spreadsheet.Cell) interface{} {
+	x := c.X()
+	switch x.TAttr {
+	case sml.ST_CellTypeB:
+		return x.V != nil && *x.V == "1"
+	case sml.ST_CellTypeUnset, sml.ST_CellTypeN:
+		if x.V != nil {
+			if f, err := strconv.ParseFloat(*x.V, 64); err == nil {
+				return f
+			}
+		}
+	}
+
+	return GetCellString(c)
+}
+
+func writeDelimited(w io.Writer, rows [][]interface{}, format Format) error {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if format == FormatTSV {
+		cw.Comma = '\t'
+	}
+
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			switch fv := v.(type) {
+			case nil:
+			case float64:
+				record[i] = strconv.FormatFloat(fv, 'f', -1, 64)
+			case bool:
+				record[i] = strings.ToUpper(strconv.FormatBool(fv))
+			default:
+				record[i] = fmt.Sprintf("%v", fv)
+			}
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeODS(w io.Writer, sheets []sheetData) error {
+	zw := zip.NewWriter(w)
+
+	// the mimetype should be the first entry and not compressed
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mw, odsMimeType); err != nil {
+		return err
+	}
+
+	mw, err = zw.Create("META-INF/manifest.xml")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mw, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">`+
+		`<manifest:file-entry manifest:full-path="/" manifest:version="1.2" manifest:media-type="`+odsMimeType+`"/>`+
+		`<manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>`+
+		`</manifest:manifest>`); err != nil {
+		return err
+	}
+
+	cw, err := zw.Create("content.xml")
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(cw)
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"` +
+		` xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"` +
+		` xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" office:version="1.2">` +
+		`<office:body><office:spreadsheet>`)
+
+	for i, sd := range sheets {
+		name := sd.name
+		if name == "" {
+			name = "Sheet" + strconv.Itoa(i+1)
+		}
+
+		bw.WriteString(`<table:table table:name="`)
+		_ = xml.EscapeText(bw, []byte(name))
+		bw.WriteString(`">`)
+
+		for _, row := range sd.rows {
+			bw.WriteString(`<table:table-row>`)
+			for _, v := range row {
+				switch fv := v.(type) {
+				case nil:
+					bw.WriteString(`<table:table-cell/>`)
+				case float64:
+					s := strconv.FormatFloat(fv, 'f', -1, 64)
+					bw.WriteString(`<table:table-cell office:value-type="float" office:value="` + s + `"><text:p>` + s + `</text:p></table:table-cell>`)
+				case bool:
+					s := strconv.FormatBool(fv)
+					bw.WriteString(`<table:table-cell office:value-type="boolean" office:boolean-value="` + s + `"><text:p>` + strings.ToUpper(s) + `</text:p></table:table-cell>`)
+				default:
+					bw.WriteString(`<table:table-cell office:value-type="string"><text:p>`)
+					_ = xml.EscapeText(bw, []byte(fmt.Sprintf("%v", fv)))
+					bw.WriteString(`</text:p></table:table-cell>`)
+				}
+			}
+			bw.WriteString(`</table:table-row>`)
+		}
+
+		bw.WriteString(`</table:table>`)
+	}
+
+	bw.WriteString(`</office:spreadsheet></office:body></office:document-content>`)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// saveToFileAs writes the workbook out to a file in the format by the file name extension.
+func (x *Xlsx) saveToFileAs(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	if err := x.SaveAs(f, FormatOf(file)); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,120 @@
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bingoohuang/ngg/xlsx"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+type formatRow struct {
+	Name  string  `title:"姓名"`
+	Age   int     `title:"年龄"`
+	Score float64 `title:"得分"`
+}
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, xlsx.FormatCSV, xlsx.FormatOf("a.CSV"))
+	assert.Equal(t, xlsx.FormatTSV, xlsx.FormatOf("a.tsv"))
+	assert.Equal(t, xlsx.FormatODS, xlsx.FormatOf("/tmp/a.ods"))
+	assert.Equal(t, xlsx.FormatXlsx, xlsx.FormatOf("a.xlsx"))
+
+	f, ok := xlsx.FormatOfContentType("text/csv; charset=utf-8")
+	assert.True(t, ok)
+	assert.Equal(t, xlsx.FormatCSV, f)
+	_, ok = xlsx.FormatOfContentType("application/octet-stream")
+	assert.False(t, ok)
+}
+
+func TestReadGBKCSV(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("姓名,年龄,得分\n张三,18,90.5\n李四,20,60\n"))
+	assert.Nil(t, err)
+
+	x, err := xlsx.New(xlsx.WithExcel(gbk))
+	assert.Nil(t, err)
+
+	var rows []formatRow
+	assert.Nil(t, x.Read(&rows))
+	assert.Equal(t, []formatRow{{"张三", 18, 90.5}, {"李四", 20, 60}}, rows)
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	rows := []formatRow{{"张三", 18, 90.5}, {"a,\"b\"", 20, 60}}
+
+	for _, format := range []xlsx.Format{xlsx.FormatCSV, xlsx.FormatTSV, xlsx.FormatODS} {
+		x, err := xlsx.New()
+		assert.Nil(t, err)
+		assert.Nil(t, x.Write(rows))
+
+		var buf bytes.Buffer
+		assert.Nil(t, x.SaveAs(&buf, format))
+
+		x2, err := xlsx.New(xlsx.WithExcel(buf.Bytes()))
+		assert.Nil(t, err)
+
+		var got []formatRow
+		assert.Nil(t, x2.Read(&got))
+		assert.Equal(t, rows, got, "format %d", format)
+	}
+}
+
+func TestDownloadCSV(t *testing.T) {
+	x, err := xlsx.New()
+	assert.Nil(t, err)
+	assert.Nil(t, x.Write([]formatRow{{"张三", 18, 90.5}}))
+
+	w := httptest.NewRecorder()
+	assert.Nil(t, x.Download(w, "file.csv"))
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, "\xEF\xBB\xBF姓名,年龄,得分\n张三,18,90.5\n", w.Body.String())
+}
+
+type odsRow struct {
+	A string `title:"A" ignoreEmptyRows:"false"`
+	B string `title:"B"`
+	C string `title:"C"`
+	D string `title:"D"`
+}
+
+func TestReadODSRepeated(t *testing.T) {
+	cell := func(v string, repeated int) string {
+		if v == "" {
+			return fmt.Sprintf(`<table:table-cell table:number-columns-repeated="%d"/>`, repeated)
+		}
+		return fmt.Sprintf(`<table:table-cell table:number-columns-repeated="%d"><text:p>%s</text:p></table:table-cell>`, repeated, v)
+	}
+	row := func(repeated int, cells ...string) string {
+		return fmt.Sprintf(`<table:table-row table:number-rows-repeated="%d">%s</table:table-row>`, repeated, strings.Join(cells, ""))
+	}
+
+	content := `<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"` +
+		` xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">` +
+		`<office:body><office:spreadsheet><table:table table:name="Sheet1">` +
+		row(1, cell("A", 1), cell("B", 1), cell("C", 1), cell("D", 1), cell("", 16380)) +
+		row(1, cell("1", 1), cell("", 2), cell("4", 1), cell("", 16380)) +
+		row(2, cell("", 16384)) +
+		row(1, cell("", 3), cell("x", 1)) +
+		row(1048570, cell("", 16384)) +
+		`</table:table></office:spreadsheet></office:body></office:document-content>`
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("mimetype")
+	_, _ = w.Write([]byte("application/vnd.oasis.opendocument.spreadsheet"))
+	w, _ = zw.Create("content.xml")
+	_, _ = w.Write([]byte(content))
+	assert.Nil(t, zw.Close())
+
+	x, err := xlsx.New(xlsx.WithExcel(buf.Bytes()))
+	assert.Nil(t, err)
+
+	var rows []odsRow
+	assert.Nil(t, x.Read(&rows))
+	assert.Equal(t, []odsRow{{A: "1", D: "4"}, {}, {}, {D: "x"}}, rows)
+}
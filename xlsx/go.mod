@@ -7,6 +7,7 @@ require (
 	github.com/bingoohuang/ngg/ss v0.0.0-20240910090138-165f286848e1
 	github.com/stretchr/testify v1.9.0
 	github.com/unidoc/unioffice v1.35.0
+	golang.org/x/text v0.18.0
 )
 
 require (
@@ -38,7 +39,6 @@ require (
 	golang.org/x/exp v0.0.0-20240909161429-701f63a606c0 // indirect
 	golang.org/x/sys v0.25.0 // indirect
 	golang.org/x/term v0.24.0 // indirect
-	golang.org/x/text v0.18.0 // indirect
 	golang.org/x/xerrors v0.0.0-20240903120638-7835f813f4da // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
@@ -1,6 +1,7 @@
 package xlsx
 
 import (
+	"io"
 	"log"
 	"mime"
 	"net/http"
@@ -29,20 +30,42 @@ func parseUploadFile(r *http.Request, filenameKey string) (*spreadsheet.Workbook
 
 	defer file.Close()
 
-	return spreadsheet.Read(file, header.Size)
+	format := FormatOf(header.Filename)
+	if format == FormatXlsx {
+		if f, ok := FormatOfContentType(header.Header.Get("Content-Type")); ok {
+			format = f
+		}
+	}
+
+	if format == FormatXlsx {
+		return spreadsheet.Read(file, header.Size)
+	}
+
+	bs, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFormat(bs, format)
 }
 
 // Download downloads the excels file in the http response.
+// The format is chosen by the filename extension, .csv, .tsv, .ods or xlsx by default.
 func (x *Xlsx) Download(w http.ResponseWriter, filename string) error {
 	h := w.Header().Set
 
 	h("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
 	h("Content-Description", "File Transfer")
-	h("Content-Type", "application/octet-stream")
+	format := FormatOf(filename)
+	if format == FormatXlsx {
+		h("Content-Type", "application/octet-stream")
+	} else {
+		h("Content-Type", format.ContentType())
+	}
 	h("Content-Transfer-Encoding", "binary")
 	h("Expires", "0")
 	h("Cache-Control", "must-revalidate")
 	h("Pragma", "public")
 
-	return x.Save(w)
+	return x.SaveAs(w, format)
 }
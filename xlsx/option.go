@@ -1,10 +1,10 @@
 package xlsx
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"log"
+	"os"
 
 	"github.com/unidoc/unioffice/spreadsheet"
 )
@@ -69,18 +69,31 @@ func parseExcel(f interface{}) (wb *spreadsheet.Workbook, err error) {
 
 	switch ft := f.(type) {
 	case string:
+		if format := FormatOf(ft); format != FormatXlsx {
+			if bs, err = os.ReadFile(ft); err != nil {
+				return nil, err
+			}
+
+			return parseFormat(bs, format)
+		}
+
 		return spreadsheet.Open(ft)
 	case []byte:
-		return spreadsheet.Read(bytes.NewReader(ft), int64(len(ft)))
+		bs = ft
 	case io.Reader:
 		if bs, err = io.ReadAll(ft); err != nil {
 			return nil, err
 		}
-
-		return spreadsheet.Read(bytes.NewReader(bs), int64(len(bs)))
 	default:
 		return nil, ErrUnknownExcelError
 	}
+
+	format, err := detectFormat(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFormat(bs, format)
 }
 
 // WithValidations defines the validations for the cells.
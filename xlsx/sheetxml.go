@@ -0,0 +1,439 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// sheetReader reads the SpreadsheetML (xlsx) rows by xml tokens without loading the whole workbook into memory.
+// Only the shared strings table is kept in memory.
+type sheetReader struct {
+	zr            *zip.Reader
+	sheetNames    []string
+	sheetPaths    []string
+	sharedStrings []string
+}
+
+func newSheetReader(r io.ReaderAt, size int64) (*sheetReader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &sheetReader{zr: zr}
+	if err := s.readWorkbook(); err != nil {
+		return nil, err
+	}
+	if err := s.readSharedStrings(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *sheetReader) open(name string) (io.ReadCloser, error) {
+	for _, f := range s.zr.File {
+		if strings.EqualFold(f.Name, name) {
+			return f.Open()
+		}
+	}
+
+	return nil, fmt.Errorf("%s not found in xlsx: %w", name, ErrUnknownExcelError)
+}
+
+func (s *sheetReader) readWorkbook() error {
+	var rels struct {
+		Relationship []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		}
+	}
+	if err := s.decodeXML("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return err
+	}
+
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := s.decodeXML("xl/workbook.xml", &wb); err != nil {
+		return err
+	}
+
+	for _, sheet := range wb.Sheets {
+		for _, rel := range rels.Relationship {
+			if rel.ID != sheet.RID {
+				continue
+			}
+
+			target := rel.Target
+			if strings.HasPrefix(target, "/") {
+				target = strings.TrimPrefix(target, "/")
+			} else {
+				target = path.Join("xl", target)
+			}
+
+			s.sheetNames = append(s.sheetNames, sheet.Name)
+			s.sheetPaths = append(s.sheetPaths, target)
+		}
+	}
+
+	return nil
+}
+
+func (s *sheetReader) decodeXML(name string, v interface{}) error {
+	f, err := s.open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return xml.NewDecoder(f).Decode(v)
+}
+
+func (s *sheetReader) readSharedStrings() error {
+	f, err := s.open("xl/sharedStrings.xml")
+	if err != nil {
+		return nil // no shared strings
+	}
+	defer f.Close()
+
+	d := xml.NewDecoder(f)
+	var sb strings.Builder
+	inT, inPhonetic := false, false
+
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch e := t.(type) {
+		case xml.StartElement:
+			switch e.Name.Local {
+			case "si":
+				sb.Reset()
+			case "t":
+				inT = true
+			case "rPh":
+				inPhonetic = true
+			}
+		case xml.EndElement:
+			switch e.Name.Local {
+			case "si":
+				s.sharedStrings = append(s.sharedStrings, strings.TrimSpace(sb.String()))
+			case "t":
+				inT = false
+			case "rPh":
+				inPhonetic = false
+			}
+		case xml.CharData:
+			if inT && !inPhonetic {
+				sb.Write(e)
+			}
+		}
+	}
+}
+
+// findSheet returns the index of the first sheet whose name contains sheetName, or the first sheet.
+func (s *sheetReader) findSheet(sheetName string) int {
+	for i, name := range s.sheetNames {
+		if strings.Contains(name, sheetName) {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// eachRow calls fn with the row number (1-based) and the cell strings indexed by column (0-based) of the sheet.
+func (s *sheetReader) eachRow(sheetIndex int, fn func(rowNum int, cells []string) error) error {
+	if sheetIndex >= len(s.sheetPaths) {
+		return fmt.Errorf("no sheet found: %w", ErrUnknownExcelError)
+	}
+
+	f, err := s.open(s.sheetPaths[sheetIndex])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	d := xml.NewDecoder(bufio.NewReader(f))
+	var (
+		rowNum   int
+		cells    []string
+		col      int
+		cellType string
+		value    strings.Builder
+		inValue  bool
+	)
+
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch e := t.(type) {
+		case xml.StartElement:
+			switch e.Name.Local {
+			case "row":
+				rowNum++
+				if r := attr(e, "r"); r != "" {
+					rowNum, _ = strconv.Atoi(r)
+				}
+				cells, col = cells[:0], -1
+			case "c":
+				col++
+				if r := attr(e, "r"); r != "" {
+					col = columnIndex(r)
+				}
+				cellType = attr(e, "t")
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch e.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				for len(cells) <= col {
+					cells = append(cells, "")
+				}
+				cells[col] = s.cellString(cellType, strings.TrimSpace(value.String()))
+			case "row":
+				if err := fn(rowNum, cells); err != nil {
+					return err
+				}
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(e)
+			}
+		}
+	}
+}
+
+func (s *sheetReader) cellString(cellType, v string) string {
+	switch cellType {
+	case "s":
+		if id, err := strconv.Atoi(v); err == nil && id >= 0 && id < len(s.sharedStrings) {
+			return s.sharedStrings[id]
+		}
+		return ""
+	case "b":
+		if v == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return v
+	}
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+
+	return ""
+}
+
+// columnIndex returns the 0-based column index of the cell reference like AB12.
+func columnIndex(ref string) int {
+	idx := 0
+	for i := 0; i < len(ref); i++ {
+		c := ref[i]
+		if 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		idx = idx*26 + int(c-'A'+1)
+	}
+
+	return idx - 1
+}
+
+// columnName returns the column name like AB of the 0-based column index.
+func columnName(idx int) string {
+	var b []byte
+	for idx++; idx > 0; idx = (idx - 1) / 26 {
+		b = append([]byte{byte('A' + (idx-1)%26)}, b...)
+	}
+
+	return string(b)
+}
+
+// sheetWriter writes the SpreadsheetML (xlsx) rows directly into the zip stream,
+// the rows are not kept in memory. Strings are written inline, without the shared strings table.
+type sheetWriter struct {
+	zw         *zip.Writer
+	bw         *bufio.Writer
+	sheetNames []string
+	rowNum     int
+}
+
+func newSheetWriter(w io.Writer) *sheetWriter {
+	return &sheetWriter{zw: zip.NewWriter(w)}
+}
+
+const (
+	xmlHeader    = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+	sheetMainNS  = `http://schemas.openxmlformats.org/spreadsheetml/2006/main`
+	relationNS   = `http://schemas.openxmlformats.org/officeDocument/2006/relationships`
+	worksheetRel = relationNS + `/worksheet`
+)
+
+// addSheet finishes the current sheet, and starts a new sheet.
+func (s *sheetWriter) addSheet(name string) error {
+	if err := s.endSheet(); err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = "Sheet" + strconv.Itoa(len(s.sheetNames)+1)
+	}
+	s.sheetNames = append(s.sheetNames, name)
+	s.rowNum = 0
+
+	w, err := s.zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(s.sheetNames)))
+	if err != nil {
+		return err
+	}
+
+	s.bw = bufio.NewWriter(w)
+	_, err = s.bw.WriteString(xmlHeader + `<worksheet xmlns="` + sheetMainNS + `"><sheetData>`)
+	return err
+}
+
+func (s *sheetWriter) endSheet() error {
+	if s.bw == nil {
+		return nil
+	}
+
+	if _, err := s.bw.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+
+	err := s.bw.Flush()
+	s.bw = nil
+	return err
+}
+
+// writeRow writes the row at the row number (1-based), cells are indexed by column (0-based),
+// the value can be string, float64, bool or nil.
+func (s *sheetWriter) writeRow(rowNum int, cells []interface{}) error {
+	if s.bw == nil {
+		if err := s.addSheet(""); err != nil {
+			return err
+		}
+	}
+	if rowNum <= s.rowNum {
+		return fmt.Errorf("row %d should be greater than the last row %d", rowNum, s.rowNum)
+	}
+	s.rowNum = rowNum
+
+	w := s.bw
+	w.WriteString(`<row r="` + strconv.Itoa(rowNum) + `">`)
+	for col, cell := range cells {
+		ref := columnName(col) + strconv.Itoa(rowNum)
+		switch v := cell.(type) {
+		case nil:
+			continue
+		case string:
+			if v == "" {
+				continue
+			}
+			w.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(w, []byte(v)); err != nil {
+				return err
+			}
+			w.WriteString(`</t></is></c>`)
+		case float64:
+			w.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatFloat(v, 'f', -1, 64) + `</v></c>`)
+		case bool:
+			w.WriteString(`<c r="` + ref + `" t="b"><v>` + map[bool]string{true: "1", false: "0"}[v] + `</v></c>`)
+		default:
+			return fmt.Errorf("unsupported cell value type %T", cell)
+		}
+	}
+	_, err := w.WriteString(`</row>`)
+	return err
+}
+
+// close finishes the current sheet and writes the workbook parts.
+func (s *sheetWriter) close() error {
+	if len(s.sheetNames) == 0 {
+		if err := s.addSheet(""); err != nil {
+			return err
+		}
+	}
+	if err := s.endSheet(); err != nil {
+		return err
+	}
+
+	var sheets, rels, types strings.Builder
+	for i, name := range s.sheetNames {
+		n := strconv.Itoa(i + 1)
+		sheets.WriteString(`<sheet name="`)
+		_ = xml.EscapeText(&sheets, []byte(name))
+		sheets.WriteString(`" sheetId="` + n + `" r:id="rId` + n + `"/>`)
+		rels.WriteString(`<Relationship Id="rId` + n + `" Type="` + worksheetRel + `" Target="worksheets/sheet` + n + `.xml"/>`)
+		types.WriteString(`<Override PartName="/xl/worksheets/sheet` + n +
+			`.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`)
+	}
+	stylesRel := strconv.Itoa(len(s.sheetNames) + 1)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			types.String() + `</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="` + relationNS + `/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="` + sheetMainNS + `" xmlns:r="` + relationNS + `"><sheets>` +
+			sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels.String() + `<Relationship Id="rId` + stylesRel + `" Type="` + relationNS + `/styles" Target="styles.xml"/></Relationships>`},
+		{"xl/styles.xml", `<styleSheet xmlns="` + sheetMainNS + `">` +
+			`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+			`<cellXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/></cellXfs>` +
+			`</styleSheet>`},
+	}
+
+	for _, p := range parts {
+		w, err := s.zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, xmlHeader+p.content); err != nil {
+			return err
+		}
+	}
+
+	return s.zw.Close()
+}
@@ -0,0 +1,357 @@
+package xlsx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+)
+
+// ErrNotStruct is returned by the streaming API when the row type is not a struct.
+var ErrNotStruct = errors.New("the row type should be a struct")
+
+// ErrStopEach can be returned by the fn of ReadEach to stop reading the rest rows, then ReadEach returns nil.
+var ErrStopEach = errors.New("stop reading each row")
+
+// ReadEach reads the excel rows one at a time into the struct T, and calls fn for each row.
+// Unlike Read, the workbook is not loaded into memory, which is suitable for very large sheets.
+// The excel can be type of any of followings:
+// 1. a string for direct excel file name
+// 2. a []byte for the content of excel which loaded in advance.
+// 3. a io.Reader, which will be read into memory (still compressed) because xlsx needs random access.
+//
+// The title row is located by the `title` tags in the first 6 rows, and the sheet is chosen by the `sheet` tag,
+// just like Read. The cells are validated by the `validate` tag (the enums of WithValidations in optionFns),
+// but unlike Read, it stops at the first row with invalid cells and returns its CellErrors.
+// It also stops and returns the error when fn returns an error other than ErrStopEach.
+func ReadEach[T any](excel interface{}, fn func(row T) error, optionFns ...OptionFn) error {
+	r := makeRun(&[]T{}, nil)
+	if r.beanType.Kind() != reflect.Struct {
+		return ErrNotStruct
+	}
+
+	titles, customizedTitles := collectTitles(r.fields)
+	x := &Xlsx{option: createOption(optionFns)}
+	validators := make([]*validator, len(titles))
+	for i, tf := range titles {
+		v, err := x.parseValidator(tf.StructField)
+		if err != nil {
+			return err
+		}
+		validators[i] = v
+	}
+
+	sr, closer, err := openSheetReader(excel)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	ignoreEmptyRows := r.ignoreEmptyRows()
+	sheetIndex := sr.findSheet(r.FindTtag("sheet"))
+	var columns []int
+
+	err = sr.eachRow(sheetIndex, func(rowNum int, cells []string) error {
+		if columns == nil {
+			if rowNum > 6 {
+				// 前6行都找不到的话，结束
+				return ErrFailToLocationTitleRow
+			}
+
+			var err error
+			columns, err = locateTitleColumns(titles, customizedTitles, cells)
+			return err
+		}
+
+		values := make([]string, len(titles))
+		emptyCells := 0
+		for i := range titles {
+			if col := columns[i]; col >= 0 && col < len(cells) {
+				values[i] = cells[col]
+			}
+			if values[i] == "" {
+				emptyCells++
+			}
+		}
+
+		if ignoreEmptyRows && emptyCells == len(titles) {
+			return nil
+		}
+
+		rowBean := reflect.New(r.beanType).Elem()
+		var errs CellErrors
+		for i, tf := range titles {
+			cellErr := func(rule string, err error) *CellError {
+				e := &CellError{
+					Sheet: sr.sheetNames[sheetIndex], Row: uint32(rowNum),
+					Title: tf.Title.Text, Value: values[i], Rule: rule, Err: err,
+				}
+				// the column is left empty when the title is missing in the sheet
+				if columns[i] >= 0 {
+					e.Column = columnName(columns[i])
+				}
+				return e
+			}
+
+			if v := validators[i]; v != nil {
+				if rule, err := v.validate(values[i], uint32(rowNum)); err != nil {
+					errs = append(errs, cellErr(rule, err))
+					continue
+				}
+			}
+
+			if err := setFieldValue(rowBean, tf.StructField, values[i]); err != nil {
+				errs = append(errs, cellErr("type", err))
+			}
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+
+		return fn(rowBean.Interface().(T))
+	})
+	if errors.Is(err, ErrStopEach) {
+		return nil
+	}
+
+	return err
+}
+
+// locateTitleColumns returns the column indexes of titles in the row,
+// nil when the row is not the title row.
+func locateTitleColumns(titles []TitleField, customizedTitles bool, cells []string) ([]int, error) {
+	columns := make([]int, len(titles))
+	for i := range columns {
+		columns[i] = -1
+	}
+
+	found := 0
+	for col, s := range cells {
+		if s == "" {
+			continue
+		}
+
+		for i, title := range titles {
+			if !title.Title.Matches(s) {
+				continue
+			}
+
+			if columns[i] >= 0 {
+				return nil, fmt.Errorf("duplicate columns contains title %s: %w", title.Title.Text, ErrFailToLocationTitleRow)
+			}
+
+			columns[i] = col
+			found++
+
+			break
+		}
+	}
+
+	if found == 0 || customizedTitles && found < len(titles) {
+		return nil, nil
+	}
+
+	return columns, nil
+}
+
+func openSheetReader(excel interface{}) (*sheetReader, func(), error) {
+	switch ft := excel.(type) {
+	case string:
+		f, err := os.Open(ft)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stat, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+
+		sr, err := newSheetReader(f, stat.Size())
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+
+		return sr, func() { f.Close() }, nil
+	case []byte:
+		sr, err := newSheetReader(bytes.NewReader(ft), int64(len(ft)))
+		return sr, func() {}, err
+	case io.Reader:
+		bs, err := io.ReadAll(ft)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		sr, err := newSheetReader(bytes.NewReader(bs), int64(len(bs)))
+		return sr, func() {}, err
+	default:
+		return nil, nil, ErrUnknownExcelError
+	}
+}
+
+// StreamWriter writes the struct T row by row into the xlsx stream, the rows are not kept in memory.
+// Merging columns, cell styles and data validations are not supported in streaming.
+type StreamWriter[T any] struct {
+	sw      *sheetWriter
+	titles  []TitleField
+	columns []int
+	rowNum  int
+}
+
+// NewStreamWriter creates a StreamWriter which writes to w, e.g. a file.
+// With WithTemplate option, the template rows until the title row are copied (values only, without styles),
+// and the data rows are written under the title columns; otherwise the title row is written from the `title` tags,
+// unless `notitle` tag is declared. The sheet name is from the `sheet` tag.
+func NewStreamWriter[T any](w io.Writer, optionFns ...OptionFn) (*StreamWriter[T], error) {
+	r := makeRun(&[]T{}, nil)
+	if r.beanType.Kind() != reflect.Struct {
+		return nil, ErrNotStruct
+	}
+
+	titles, customizedTitles := collectTitles(r.fields)
+	s := &StreamWriter[T]{sw: newSheetWriter(w), titles: titles}
+	sheetName := r.FindTtag("sheet")
+
+	option := createOption(optionFns)
+	if tmpl := option.TemplateWorkbook; tmpl != nil {
+		defer tmpl.Close()
+		if err := s.copyTemplate(tmpl, sheetName, titles, customizedTitles); err != nil {
+			return nil, err
+		}
+
+		return s, nil
+	}
+
+	if err := s.sw.addSheet(sheetName); err != nil {
+		return nil, err
+	}
+
+	s.columns = make([]int, len(titles))
+	titleRow := make([]interface{}, len(titles))
+	for i, t := range titles {
+		s.columns[i] = i
+		titleRow[i] = t.Title.Text
+	}
+
+	if _, noTitle := r.LookupTtag("notitle"); noTitle {
+		return s, nil
+	}
+
+	s.rowNum++
+	return s, s.sw.writeRow(s.rowNum, titleRow)
+}
+
+func (s *StreamWriter[T]) copyTemplate(tmpl *spreadsheet.Workbook, sheetName string,
+	titles []TitleField, customizedTitles bool,
+) error {
+	sheets := tmpl.Sheets()
+	if len(sheets) == 0 {
+		return fmt.Errorf("no sheet in template: %w", ErrFailToLocationTitleRow)
+	}
+
+	sheet := sheets[0]
+	for _, sh := range sheets {
+		if strings.Contains(sh.Name(), sheetName) {
+			sheet = sh
+			break
+		}
+	}
+
+	if sheetName == "" {
+		sheetName = sheet.Name()
+	}
+	if err := s.sw.addSheet(sheetName); err != nil {
+		return err
+	}
+
+	for i, row := range sheet.Rows() {
+		if i > 5 {
+			break
+		}
+
+		var cells []interface{}
+		var strs []string
+		for _, cell := range RowCells(row) {
+			col, err := cell.Column()
+			if err != nil {
+				continue
+			}
+
+			idx := int(reference.ColumnToIndex(col))
+			for len(cells) <= idx {
+				cells = append(cells, nil)
+				strs = append(strs, "")
+			}
+
+			strs[idx] = GetCellString(cell)
+			cells[idx] = strs[idx]
+		}
+
+		s.rowNum = int(row.RowNumber())
+		if err := s.sw.writeRow(s.rowNum, cells); err != nil {
+			return err
+		}
+
+		columns, err := locateTitleColumns(titles, customizedTitles, strs)
+		if err != nil {
+			return err
+		}
+		if columns != nil {
+			s.columns = columns
+			return nil
+		}
+	}
+
+	return ErrFailToLocationTitleRow
+}
+
+// Write writes a row.
+func (s *StreamWriter[T]) Write(row T) error {
+	v := reflect.ValueOf(row)
+	var cells []interface{}
+
+	for i, tf := range s.titles {
+		col := s.columns[i]
+		if col < 0 {
+			continue
+		}
+
+		for len(cells) <= col {
+			cells = append(cells, nil)
+		}
+		cells[col] = streamCellValue(tf.StructField, v)
+	}
+
+	s.rowNum++
+	return s.sw.writeRow(s.rowNum, cells)
+}
+
+// Close finishes the xlsx stream, the underlying writer is not closed.
+func (s *StreamWriter[T]) Close() error { return s.sw.close() }
+
+// streamCellValue converts the field value to a cell value like setCellValue.
+func streamCellValue(field reflect.StructField, value reflect.Value) interface{} {
+	v := value.FieldByIndex(field.Index).Interface()
+
+	if fv, ok := ConvertNumberToFloat64(v); ok {
+		return fv
+	}
+
+	switch fv := v.(type) {
+	case time.Time:
+		return formatTime(field.Tag, fv)
+	case string, bool:
+		return fv
+	default:
+		return nil
+	}
+}
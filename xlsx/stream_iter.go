@@ -0,0 +1,28 @@
+//go:build go1.23
+
+package xlsx
+
+import "iter"
+
+// ReadIter reads the excel rows one at a time like ReadEach, in the form of Go 1.23 iterator.
+//
+//	for row, err := range xlsx.ReadIter[Member]("members.xlsx") {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func ReadIter[T any](excel interface{}, optionFns ...OptionFn) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		err := ReadEach(excel, func(row T) error {
+			if !yield(row, nil) {
+				return ErrStopEach
+			}
+			return nil
+		}, optionFns...)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+//go:build go1.23
+
+package xlsx_test
+
+import (
+	"testing"
+
+	"github.com/bingoohuang/ngg/xlsx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadIter(t *testing.T) {
+	var got []memberStat
+	for row, err := range xlsx.ReadIter[memberStat]("testdata/out_demo1.xlsx") {
+		assert.Nil(t, err)
+		got = append(got, row)
+		break
+	}
+	assert.Equal(t, []memberStat{{Total: 100, New: 50, Effective: 50}}, got)
+
+	for _, err := range xlsx.ReadIter[memberStat]("testdata/not-exists.xlsx") {
+		assert.NotNil(t, err)
+	}
+}
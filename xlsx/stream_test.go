@@ -0,0 +1,145 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/ngg/xlsx"
+	"github.com/stretchr/testify/assert"
+)
+
+type streamRow struct {
+	ID      int       `title:"编号" sheet:"明细"`
+	Name    string    `title:"名称"`
+	Score   float64   `title:"得分"`
+	Passed  bool      `title:"通过"`
+	Created time.Time `title:"创建时间" format:"yyyy-MM-dd"`
+	Ignored string    `title:"-"`
+}
+
+func TestStream(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := xlsx.NewStreamWriter[streamRow](&buf)
+	assert.Nil(t, err)
+
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)
+	var rows []streamRow
+	for i := 1; i <= 1000; i++ {
+		row := streamRow{ID: i, Name: fmt.Sprintf("<name&%d>", i), Score: float64(i) / 2, Passed: i%2 == 0, Created: day}
+		rows = append(rows, row)
+		assert.Nil(t, w.Write(row))
+	}
+	assert.Nil(t, w.Close())
+
+	var got []streamRow
+	err = xlsx.ReadEach(buf.Bytes(), func(row streamRow) error {
+		got = append(got, row)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, rows, got)
+
+	// stop by the callback error
+	count := 0
+	stop := fmt.Errorf("stop")
+	err = xlsx.ReadEach(bytes.NewReader(buf.Bytes()), func(row streamRow) error {
+		if count++; count == 10 {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 10, count)
+}
+
+func TestReadEachWorkbook(t *testing.T) {
+	// written by Xlsx.Write, with shared strings
+	var got []memberStat
+	err := xlsx.ReadEach("testdata/out_demo1.xlsx", func(row memberStat) error {
+		got = append(got, row)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []memberStat{
+		{Total: 100, New: 50, Effective: 50},
+		{Total: 200, New: 60, Effective: 140},
+	}, got)
+}
+
+func TestStreamWithTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := xlsx.NewStreamWriter[memberStat](&buf, xlsx.WithTemplate("testdata/template.xlsx"))
+	assert.Nil(t, err)
+
+	rows := []memberStat{
+		{Total: 100, New: 50, Effective: 50},
+		{Total: 200, New: 60, Effective: 140},
+	}
+	for _, row := range rows {
+		assert.Nil(t, w.Write(row))
+	}
+	assert.Nil(t, w.Close())
+
+	var got []memberStat
+	err = xlsx.ReadEach(buf.Bytes(), func(row memberStat) error {
+		got = append(got, row)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, rows, got)
+}
+
+type streamValidRow struct {
+	Name   string `title:"姓名" validate:"required"`
+	Gender string `title:"性别" validate:"enum=genders"`
+}
+
+func TestReadEachValidate(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := xlsx.NewStreamWriter[streamValidRow](&buf)
+	assert.Nil(t, err)
+	for _, row := range []streamValidRow{{"张三", "男"}, {"李四", "女"}, {"", "未知"}, {"王五", "男"}} {
+		assert.Nil(t, w.Write(row))
+	}
+	assert.Nil(t, w.Close())
+
+	var got []streamValidRow
+	err = xlsx.ReadEach(buf.Bytes(), func(row streamValidRow) error {
+		got = append(got, row)
+		return nil
+	}, xlsx.WithValidations(map[string][]string{"genders": {"男", "女"}}))
+	assert.Equal(t, []streamValidRow{{"张三", "男"}, {"李四", "女"}}, got)
+
+	var errs xlsx.CellErrors
+	assert.ErrorAs(t, err, &errs)
+	assert.Equal(t, 2, len(errs))
+	assert.Equal(t, []string{"A4", "required"}, []string{errs[0].Cell(), errs[0].Rule})
+	assert.Equal(t, []string{"B4", "enum"}, []string{errs[1].Cell(), errs[1].Rule})
+
+	// the missing title column is not reported as column A
+	type nameRow struct{ Name string }
+	var nameBuf bytes.Buffer
+	nw, err := xlsx.NewStreamWriter[nameRow](&nameBuf)
+	assert.Nil(t, err)
+	assert.Nil(t, nw.Write(nameRow{Name: "张三"}))
+	assert.Nil(t, nw.Close())
+
+	type memoRow struct {
+		Name string
+		Memo string `validate:"required"`
+	}
+	err = xlsx.ReadEach(nameBuf.Bytes(), func(memoRow) error { return nil })
+	assert.ErrorAs(t, err, &errs)
+	assert.Equal(t, []string{"", "Memo", "required"}, []string{errs[0].Column, errs[0].Title, errs[0].Rule})
+
+	// stop by ErrStopEach
+	got = nil
+	err = xlsx.ReadEach(buf.Bytes(), func(row streamValidRow) error {
+		got = append(got, row)
+		return xlsx.ErrStopEach
+	}, xlsx.WithValidations(map[string][]string{"genders": {"男", "女"}}))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(got))
+}
@@ -0,0 +1,230 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unidoc/unioffice/color"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+)
+
+// CellError is the error of a cell value when reading.
+type CellError struct {
+	Sheet  string
+	Row    uint32
+	Column string
+	// Title is the title text of the column.
+	Title string
+	Value string
+	// Rule is the failed rule, one of required, regex, range, enum, date, unique and type (converting error).
+	Rule string
+	Err  error
+}
+
+// Cell returns the cell reference, like B3.
+func (e *CellError) Cell() string { return e.Column + strconv.Itoa(int(e.Row)) }
+
+func (e *CellError) Error() string {
+	return fmt.Sprintf("cell %s %s: %v", e.Cell(), e.Title, e.Err)
+}
+
+func (e *CellError) Unwrap() error { return e.Err }
+
+// CellErrors is the errors of cells returned by Read, which can be annotated to the workbook by Annotate.
+type CellErrors []*CellError
+
+func (e CellErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, ce := range e {
+		msgs[i] = ce.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// validator validates the cell values of a column by the `validate` tag,
+// which contains rules separated by semicolon, like
+// `validate:"required;unique;regex=^1\\d{10}$;range=1..100;enum=男,女;date=yyyy-MM-dd"`.
+// The enum can also be a key of WithValidations.
+type validator struct {
+	required, unique bool
+	regex            *regexp.Regexp
+	min, max         *float64
+	enum             []string
+	date, dateLayout string
+	seen             map[string]uint32
+}
+
+func (x *Xlsx) parseValidator(sf reflect.StructField) (*validator, error) {
+	tag := sf.Tag.Get("validate")
+	if tag == "" {
+		return nil, nil
+	}
+
+	v := &validator{}
+	for _, rule := range strings.Split(tag, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "":
+		case "required":
+			v.required = true
+		case "unique":
+			v.unique = true
+			v.seen = make(map[string]uint32)
+		case "regex":
+			re, err := regexp.Compile(arg)
+			if err != nil {
+				return nil, fmt.Errorf("field %s bad regex %s: %w", sf.Name, arg, err)
+			}
+			v.regex = re
+		case "range":
+			lo, hi, ok := strings.Cut(arg, "..")
+			if !ok {
+				return nil, fmt.Errorf("field %s bad range %s, should be like 1..100", sf.Name, arg)
+			}
+			var err error
+			if v.min, err = parseBound(lo); err == nil {
+				v.max, err = parseBound(hi)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("field %s bad range %s: %w", sf.Name, arg, err)
+			}
+		case "enum":
+			if vm, ok := x.option.Validations[arg]; ok {
+				v.enum = vm
+			} else {
+				v.enum = strings.Split(arg, ",")
+			}
+		case "date":
+			v.date, v.dateLayout = arg, ParseJavaTimeFormat(arg)
+		default:
+			return nil, fmt.Errorf("field %s unknown validate rule %s", sf.Name, name)
+		}
+	}
+
+	return v, nil
+}
+
+func parseBound(s string) (*float64, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// validate validates the cell value, returns the failed rule and the error.
+func (v *validator) validate(s string, rowNum uint32) (string, error) {
+	if s == "" {
+		if v.required {
+			return "required", errors.New("value is required")
+		}
+
+		return "", nil
+	}
+
+	if v.regex != nil && !v.regex.MatchString(s) {
+		return "regex", fmt.Errorf("value %s does not match %s", s, v.regex)
+	}
+
+	if v.min != nil || v.max != nil {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return "range", fmt.Errorf("value %s is not a number", s)
+		}
+		if v.min != nil && f < *v.min || v.max != nil && f > *v.max {
+			return "range", fmt.Errorf("value %s is out of range [%s, %s]", s, formatBound(v.min), formatBound(v.max))
+		}
+	}
+
+	if len(v.enum) > 0 && !contains(v.enum, s) {
+		return "enum", fmt.Errorf("value %s is not one of %s", s, strings.Join(v.enum, ","))
+	}
+
+	if v.dateLayout != "" {
+		if _, err := time.Parse(v.dateLayout, s); err != nil {
+			return "date", fmt.Errorf("value %s is not a date in format %s", s, v.date)
+		}
+	}
+
+	if v.unique {
+		if first, ok := v.seen[s]; ok {
+			return "unique", fmt.Errorf("value %s is duplicate with row %d", s, first)
+		}
+		v.seen[s] = rowNum
+	}
+
+	return "", nil
+}
+
+func formatBound(f *float64) string {
+	if f == nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Annotate highlights the error cells with red fill and comments the error messages in the read workbook,
+// then the workbook can be saved or downloaded as xlsx for users to fix and upload again.
+// The original cell styles of the error cells are replaced.
+func (x *Xlsx) Annotate(errs CellErrors) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	style := x.workbook.StyleSheet.AddCellStyle()
+	fill := x.workbook.StyleSheet.Fills().AddFill()
+	pattern := fill.SetPatternFill()
+	pattern.SetPattern(sml.ST_PatternTypeSolid)
+	pattern.SetFgColor(color.RGB(0xFF, 0xC7, 0xCE))
+	style.SetFill(fill)
+
+	type cellKey struct{ sheet, cell string }
+	var keys []cellKey
+	messages := make(map[cellKey][]string)
+
+	for _, e := range errs {
+		k := cellKey{sheet: e.Sheet, cell: e.Cell()}
+		if _, ok := messages[k]; !ok {
+			keys = append(keys, k)
+		}
+		messages[k] = append(messages[k], e.Err.Error())
+	}
+
+	for _, k := range keys {
+		sheet := x.currentSheet
+		if sheet.Name() != k.sheet {
+			if sheet = x.findSheet(x.workbook, k.sheet); !sheet.IsValid() {
+				return fmt.Errorf("unable to find sheet with name %s", k.sheet)
+			}
+		}
+
+		sheet.Cell(k.cell).SetStyle(style)
+		if err := sheet.Comments().AddCommentWithStyle(k.cell, "xlsx", strings.Join(messages[k], "\n")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bingoohuang/ngg/xlsx"
+	"github.com/stretchr/testify/assert"
+)
+
+type validateRow struct {
+	Name   string `title:"姓名" validate:"required;unique"`
+	Mobile string `title:"手机" validate:"regex=^1\\d{10}$"`
+	Age    int    `title:"年龄" validate:"range=1..150"`
+	Gender string `title:"性别" validate:"enum=genders"`
+	Birth  string `title:"生日" validate:"date=yyyy-MM-dd"`
+	Score  int    `title:"得分"`
+}
+
+func TestValidate(t *testing.T) {
+	csv := "姓名,手机,年龄,性别,生日,得分\n" +
+		"张三,13800138000,18,男,2000-01-02,90\n" +
+		",1380013800,200,未知,2000/01/02,abc\n" +
+		"张三,,,女,,\n"
+
+	x, err := xlsx.New(xlsx.WithExcel([]byte(csv)), xlsx.WithValidations(map[string][]string{
+		"genders": {"男", "女"},
+	}))
+	assert.Nil(t, err)
+
+	var rows []validateRow
+	err = x.Read(&rows)
+
+	var errs xlsx.CellErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, rows, 3)
+	assert.Equal(t, validateRow{Name: "张三", Mobile: "13800138000", Age: 18, Gender: "男", Birth: "2000-01-02", Score: 90}, rows[0])
+
+	type cellRule struct{ Cell, Rule string }
+	var got []cellRule
+	for _, e := range errs {
+		got = append(got, cellRule{e.Cell(), e.Rule})
+	}
+	assert.Equal(t, []cellRule{
+		{"A3", "required"}, {"B3", "regex"}, {"C3", "range"}, {"D3", "enum"}, {"E3", "date"}, {"F3", "type"},
+		{"A4", "unique"},
+	}, got)
+	assert.Equal(t, "年龄", errs[2].Title)
+	assert.Equal(t, "200", errs[2].Value)
+
+	assert.Nil(t, x.Annotate(errs))
+	var buf bytes.Buffer
+	assert.Nil(t, x.Save(&buf))
+	assert.True(t, buf.Len() > 0)
+}
@@ -422,7 +422,8 @@ func (x *Xlsx) createColumnDataValidation(startRowNum uint32, sheet spreadsheet.
 }
 
 // Read reads the excel rows to slice.
-
+// The cell values are validated by the `validate` tag, and the cells failed to validate or convert
+// are returned as CellErrors, with the slice still filled, which can be annotated to the workbook by Annotate.
 func (x *Xlsx) Read(slicePtr interface{}) error {
 	r := makeRun(slicePtr, nil)
 
@@ -453,11 +454,11 @@ func (x *Xlsx) Read(slicePtr interface{}) error {
 	location := *loc
 	if location.isValid() {
 		slice, err := x.readRows(r.beanType, location, ignoreEmptyRows)
-		if err != nil {
-			return err
+		if slice.IsValid() {
+			r.rawValue.Elem().Set(slice)
 		}
 
-		r.rawValue.Elem().Set(slice)
+		return err
 	}
 
 	return nil
@@ -493,25 +494,38 @@ func (x *Xlsx) writePlaceholderToBean(r *run) error {
 }
 
 func (x *Xlsx) readRows(beanType reflect.Type, l templateLocation, ignoreEmptyRows bool) (reflect.Value, error) {
-	slice := reflect.MakeSlice(reflect.SliceOf(beanType), 0, len(l.templateRows))
-
-	for _, row := range l.templateRows {
-		rowBean, err := x.createRowBean(beanType, l, row, ignoreEmptyRows)
+	validators := make([]*validator, len(l.titleFields))
+	for i, tf := range l.titleFields {
+		v, err := x.parseValidator(tf.StructField)
 		if err != nil {
 			return reflect.Value{}, err
 		}
 
+		validators[i] = v
+	}
+
+	slice := reflect.MakeSlice(reflect.SliceOf(beanType), 0, len(l.templateRows))
+	var errs CellErrors
+
+	for _, row := range l.templateRows {
+		rowBean, rowErrs := x.createRowBean(beanType, l, row, ignoreEmptyRows, validators)
+		errs = append(errs, rowErrs...)
+
 		if rowBean.IsValid() {
 			slice = reflect.Append(slice, rowBean)
 		}
 	}
 
+	if len(errs) > 0 {
+		return slice, errs
+	}
+
 	return slice, nil
 }
 
 func (x *Xlsx) createRowBean(beanType reflect.Type, l templateLocation,
-	row spreadsheet.Row, ignoreEmptyRows bool,
-) (reflect.Value, error) {
+	row spreadsheet.Row, ignoreEmptyRows bool, validators []*validator,
+) (reflect.Value, CellErrors) {
 	type templateCellValue struct {
 		value string
 		TitleField
@@ -538,14 +552,29 @@ func (x *Xlsx) createRowBean(beanType reflect.Type, l templateLocation,
 	}
 
 	rowBean := reflect.New(beanType).Elem()
+	var errs CellErrors
+
+	for i, cell := range values {
+		cellErr := func(rule string, err error) *CellError {
+			return &CellError{
+				Sheet: x.currentSheet.Name(), Row: row.RowNumber(), Column: cell.Column,
+				Title: cell.Title.Text, Value: cell.value, Rule: rule, Err: err,
+			}
+		}
+
+		if v := validators[i]; v != nil {
+			if rule, err := v.validate(cell.value, row.RowNumber()); err != nil {
+				errs = append(errs, cellErr(rule, err))
+				continue
+			}
+		}
 
-	for _, cell := range values {
 		if err := setFieldValue(rowBean, cell.StructField, cell.value); err != nil {
-			return reflect.Value{}, err
+			errs = append(errs, cellErr("type", err))
 		}
 	}
 
-	return rowBean, nil
+	return rowBean, errs
 }
 
 func setFieldValue(rowBean reflect.Value, sf reflect.StructField, s string) error {
@@ -683,8 +712,15 @@ func collectTitles(fields []reflect.StructField) ([]TitleField, bool) {
 	return titles, false
 }
 
-// SaveToFile writes the workbook out to a file.
-func (x *Xlsx) SaveToFile(file string) error { return x.workbook.SaveToFile(file) }
+// SaveToFile writes the workbook out to a file, the format is chosen by the file extension,
+// .csv, .tsv, .ods or xlsx by default.
+func (x *Xlsx) SaveToFile(file string) error {
+	if FormatOf(file) != FormatXlsx {
+		return x.saveToFileAs(file)
+	}
+
+	return x.workbook.SaveToFile(file)
+}
 
 // Save writes the workbook out to a writer in the zipped xlsx format.
 func (x *Xlsx) Save(w io.Writer) error { return x.workbook.Save(w) }